@@ -0,0 +1,250 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"bytes"
+	"errors"
+	"hash/fnv"
+)
+
+// ArrayValidator validates a JSON array one item at a time, as it is built, see Schema.NewArrayValidator.
+// It is not safe for concurrent use
+type ArrayValidator struct {
+	schema *Schema
+	root   *subSchema
+	// The items appended so far
+	items []interface{}
+	// The indexes of the items by the hash of their canonical form and the canonical forms, for uniqueItems
+	hashedItems map[uint64][]int
+	canonical   [][]byte
+	// Whether an item matched "contains", otherwise the result of the item that came closest
+	containsMatched   bool
+	bestContainsMatch *Result
+}
+
+// NewArrayValidator returns an ArrayValidator for a document that is an array, which is validated against the schema
+// item by item with Append. Every item is only validated once, when it is appended, and "maxItems" and "uniqueItems"
+// are checked against the items appended before. Finish checks what can only be known once all items are there.
+//
+// The root schema may only use keywords that can be checked one item at a time: "type", "items", "additionalItems",
+// "minItems", "maxItems", "uniqueItems" and "contains". Other keywords that apply to arrays, like "allOf" or "enum",
+// result in an error from Append and Finish. Nested schemas aren't restricted
+func (v *Schema) NewArrayValidator() *ArrayValidator {
+	return &ArrayValidator{
+		schema:      v,
+		root:        resolveRefSchema(v.rootSchema),
+		hashedItems: make(map[uint64][]int),
+	}
+}
+
+// Append validates an item added to the end of the array. The result only holds the errors caused by this item.
+// Like with NewGoLoader the item may be any Go value that can be marshaled to JSON, i.e. a float64 from json.Unmarshal
+func (a *ArrayValidator) Append(item interface{}) (*Result, error) {
+	if err := a.checkKeywords(); err != nil {
+		return nil, err
+	}
+	item, err := NewGoLoader(item).LoadJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	i := len(a.items)
+	a.items = append(a.items, item)
+	result, context := a.newResult()
+	root := a.root
+
+	// A schema that doesn't allow arrays only fails for the first item
+	if a.rejectsArrays(result, context, i == 0) {
+		return a.finish(result), nil
+	}
+
+	// items & additionalItems:
+	var itemSchema *subSchema
+	switch {
+	case root.itemsChildrenIsSingleSchema:
+		itemSchema = root.itemsChildren[0]
+	case i < len(root.itemsChildren):
+		itemSchema = root.itemsChildren[i]
+	case len(root.itemsChildren) > 0:
+		switch additionalItems := root.additionalItems.(type) {
+		case bool:
+			// Like for the array as a whole, this is only reported once
+			if !additionalItems && i == len(root.itemsChildren) {
+				result.addInternalError(new(ArrayNoAdditionalItemsError), context, a.itemsSoFar(), ErrorDetails{})
+			}
+		case *subSchema:
+			itemSchema = additionalItems
+		}
+	}
+	if itemSchema != nil {
+		subContext := acquireJsonContextIndex(i, context)
+		result.mergeErrors(itemSchema.subValidateWithContext(item, subContext, result.state))
+		releaseJsonContext(subContext)
+	}
+
+	// maxItems:
+	// Every item beyond the maximum is reported, as the result only holds the errors of this item
+	if root.maxItems != nil && len(a.items) > *root.maxItems {
+		result.addInternalError(
+			new(ArrayMaxItemsError),
+			context,
+			a.itemsSoFar(),
+			ErrorDetails{"max": *root.maxItems, "from": i, "to": i},
+		)
+	}
+
+	// uniqueItems:
+	if root.uniqueItems {
+		a.checkUnique(i, result, context)
+	}
+
+	// contains:
+	if root.contains != nil && !a.containsMatched {
+		subContext := acquireJsonContextIndex(i, context)
+		containsResult := root.contains.subValidateWithContext(item, subContext, result.state)
+		releaseJsonContext(subContext)
+		if containsResult.Valid() {
+			a.containsMatched = true
+			a.bestContainsMatch = nil
+		} else if a.bestContainsMatch == nil || containsResult.score > a.bestContainsMatch.score {
+			a.bestContainsMatch = containsResult
+		}
+	}
+
+	return a.finish(result), nil
+}
+
+// Finish checks the keywords that depend on all items, "minItems" and "contains", once the last item was appended.
+// The result only holds the errors found by these checks
+func (a *ArrayValidator) Finish() (*Result, error) {
+	if err := a.checkKeywords(); err != nil {
+		return nil, err
+	}
+
+	result, context := a.newResult()
+	root := a.root
+	if a.rejectsArrays(result, context, len(a.items) == 0) {
+		return a.finish(result), nil
+	}
+
+	if root.minItems != nil && len(a.items) < *root.minItems {
+		result.addInternalError(
+			new(ArrayMinItemsError),
+			context,
+			a.itemsSoFar(),
+			ErrorDetails{"min": *root.minItems},
+		)
+	}
+	if root.contains != nil && !a.containsMatched {
+		result.addInternalError(new(ArrayContainsError), context, a.itemsSoFar(), ErrorDetails{})
+		if a.bestContainsMatch != nil {
+			result.mergeErrors(a.bestContainsMatch)
+		}
+	}
+
+	return a.finish(result), nil
+}
+
+// checkKeywords returns an error if the root schema has a keyword that needs the whole array
+func (a *ArrayValidator) checkKeywords() error {
+	keyword := wholeValueKeyword(a.root)
+	if keyword == "" && a.root.unevaluatedItems != nil {
+		keyword = KEY_UNEVALUATED_ITEMS
+	}
+	if keyword == "" {
+		return nil
+	}
+	return errors.New(formatErrorDescription(
+		Locale.ArrayValidatorUnsupportedKeyword(),
+		ErrorDetails{"keyword": keyword},
+	))
+}
+
+// rejectsArrays reports whether the root schema doesn't allow arrays at all, adding the error to the result if report is set
+func (a *ArrayValidator) rejectsArrays(result *Result, context *JsonContext, report bool) bool {
+	root := a.root
+	if root.pass != nil && !*root.pass {
+		if report {
+			result.addInternalError(new(FalseError), context, a.itemsSoFar(), ErrorDetails{})
+		}
+		return true
+	}
+	if root.types.IsTyped() && !root.types.Contains(TYPE_ARRAY) {
+		if report {
+			result.addInternalError(
+				new(InvalidTypeError),
+				context,
+				a.itemsSoFar(),
+				ErrorDetails{
+					"expected": root.types.String(),
+					"given":    TYPE_ARRAY,
+				},
+			)
+		}
+		return true
+	}
+	return false
+}
+
+// checkUnique reports the item at index j if it equals an item appended before
+func (a *ArrayValidator) checkUnique(j int, result *Result, context *JsonContext) {
+	var canonical bytes.Buffer
+	if err := writeComparable(&canonical, a.items[j]); err != nil {
+		result.addInternalError(new(InternalError), context, a.itemsSoFar(), ErrorDetails{"err": err})
+		a.canonical = append(a.canonical, nil)
+		return
+	}
+	a.canonical = append(a.canonical, canonical.Bytes())
+	h := fnv.New64a()
+	h.Write(canonical.Bytes())
+	hash := h.Sum64()
+
+	// Like for the array as a whole, a duplicate is reported against the last equal item before it
+	for n, i := range a.hashedItems[hash] {
+		if bytes.Equal(canonical.Bytes(), a.canonical[i]) {
+			result.addInternalError(
+				new(ItemsMustBeUniqueError),
+				context,
+				a.itemsSoFar(),
+				ErrorDetails{"type": TYPE_ARRAY, "i": i, "j": j},
+			)
+			a.hashedItems[hash][n] = j
+			return
+		}
+	}
+	a.hashedItems[hash] = append(a.hashedItems[hash], j)
+}
+
+// itemsSoFar returns the items appended so far as the value of an error, which isn't changed by later appends
+func (a *ArrayValidator) itemsSoFar() []interface{} {
+	return a.items[:len(a.items):len(a.items)]
+}
+
+func (a *ArrayValidator) newResult() (*Result, *JsonContext) {
+	return &Result{state: &validationState{schema: a.schema}}, NewJsonContext(STRING_CONTEXT_ROOT, nil)
+}
+
+func (a *ArrayValidator) finish(result *Result) *Result {
+	rootURI := a.schema.documentReference
+	if a.schema.rootSchema.id != nil {
+		rootURI = *a.schema.rootSchema.id
+	}
+	result.setSchemaURI(0, rootURI.String())
+	if a.schema.maxErrorsPerField > 0 {
+		result.limitErrorsPerField(a.schema.maxErrorsPerField)
+	}
+	return result
+}
@@ -0,0 +1,310 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/xeipuuv/gojsonreference"
+)
+
+// CBOR loader
+// decodes CBOR (RFC 8949) to the same document shape as the JSON loaders
+
+type cborLoader struct {
+	source []byte
+}
+
+// NewCBORLoader creates a new JSONLoader, taking CBOR encoded bytes as source.
+// The document is decoded to the same values JSON decodes to: numbers become json.Number,
+// byte strings are base64 encoded like encoding/json does and map keys that are numbers, booleans or
+// byte strings are converted to strings. Tags are ignored except for bignums, which are decoded to numbers
+func NewCBORLoader(source []byte) JSONLoader {
+	return &cborLoader{source: source}
+}
+
+func (l *cborLoader) JsonSource() interface{} {
+	return l.source
+}
+
+func (l *cborLoader) JsonReference() (gojsonreference.JsonReference, error) {
+	return gojsonreference.NewJsonReference("#")
+}
+
+func (l *cborLoader) LoaderFactory() JSONLoaderFactory {
+	return &DefaultJSONLoaderFactory{}
+}
+
+func (l *cborLoader) LoadJSON() (interface{}, error) {
+	d := &cborDecoder{data: l.source}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("cbor: unexpected data after top-level value")
+	}
+	return value, nil
+}
+
+const (
+	cborUnsigned = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+
+	cborIndefinite = 31
+	cborBreak      = 0xff
+	cborMaxDepth   = 1000
+)
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errors.New("cbor: unexpected end of data")
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// header reads the initial byte of a data item and its argument
+func (d *cborDecoder) header() (major byte, info byte, arg uint64, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b[0]>>5, b[0]&0x1f
+
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		var n []byte
+		if n, err = d.read(1 << (info - 24)); err != nil {
+			return 0, 0, 0, err
+		}
+		for _, c := range n {
+			arg = arg<<8 | uint64(c)
+		}
+	case info == cborIndefinite:
+		if major == cborUnsigned || major == cborNegative || major == cborTag {
+			return 0, 0, 0, errors.New("cbor: invalid indefinite length item")
+		}
+	default:
+		return 0, 0, 0, fmt.Errorf("cbor: invalid additional information %d", info)
+	}
+
+	return major, info, arg, nil
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, errors.New("cbor: maximum nesting depth exceeded")
+	}
+
+	major, info, arg, err := d.header()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		return json.Number(strconv.FormatUint(arg, 10)), nil
+
+	case cborNegative:
+		// The value is -1 - arg, which doesn't fit in an int64 for large arguments
+		n := new(big.Int).SetUint64(arg)
+		return json.Number(n.Neg(n).Sub(n, big.NewInt(1)).String()), nil
+
+	case cborBytes, cborText:
+		b, err := d.readString(major, info, arg)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborBytes {
+			return base64.StdEncoding.EncodeToString(b), nil
+		}
+		return string(b), nil
+
+	case cborArray:
+		array := []interface{}{}
+		for i := uint64(0); info == cborIndefinite && !d.atBreak() || info != cborIndefinite && i < arg; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		return array, nil
+
+	case cborMap:
+		m := map[string]interface{}{}
+		for i := uint64(0); info == cborIndefinite && !d.atBreak() || info != cborIndefinite && i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			k, err := cborKeyToString(key)
+			if err != nil {
+				return nil, err
+			}
+			if m[k], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+
+	case cborTag:
+		content, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		// Bignums hold the big-endian bytes of the number, which were base64 encoded above
+		if arg == 2 || arg == 3 {
+			s, ok := content.(string)
+			if !ok {
+				return nil, errors.New("cbor: bignum must be a byte string")
+			}
+			b, _ := base64.StdEncoding.DecodeString(s)
+			n := new(big.Int).SetBytes(b)
+			if arg == 3 {
+				n.Neg(n).Sub(n, big.NewInt(1))
+			}
+			return json.Number(n.String()), nil
+		}
+		return content, nil
+
+	default:
+		return d.decodeSimple(info, arg)
+	}
+}
+
+// atBreak consumes the break that ends an indefinite length item if it is next
+func (d *cborDecoder) atBreak() bool {
+	if d.pos < len(d.data) && d.data[d.pos] == cborBreak {
+		d.pos++
+		return true
+	}
+	return false
+}
+
+// readString reads the contents of a byte or text string, joining the chunks of an indefinite length string
+func (d *cborDecoder) readString(major byte, info byte, arg uint64) ([]byte, error) {
+	if info != cborIndefinite {
+		return d.read(arg)
+	}
+
+	var s []byte
+	for {
+		if d.atBreak() {
+			return s, nil
+		}
+		chunkMajor, chunkInfo, chunkArg, err := d.header()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || chunkInfo == cborIndefinite {
+			return nil, errors.New("cbor: invalid chunk in indefinite length string")
+		}
+		chunk, err := d.read(chunkArg)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, chunk...)
+	}
+}
+
+func (d *cborDecoder) decodeSimple(info byte, arg uint64) (interface{}, error) {
+	var f float64
+
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		// undefined has no JSON equivalent and is treated as null
+		return nil, nil
+	case 25:
+		f = halfToFloat64(uint16(arg))
+	case 26:
+		f = float64(math.Float32frombits(uint32(arg)))
+	case 27:
+		f = math.Float64frombits(arg)
+	case cborIndefinite:
+		return nil, errors.New("cbor: unexpected break")
+	default:
+		return nil, fmt.Errorf("cbor: unsupported simple value %d", arg)
+	}
+
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, errors.New("cbor: NaN and infinity are not supported")
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
+
+func halfToFloat64(h uint16) float64 {
+	exponent := int(h>>10) & 0x1f
+	mantissa := float64(h & 0x3ff)
+
+	var f float64
+	switch exponent {
+	case 0:
+		f = math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mantissa+1024, exponent-25)
+	}
+
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+// cborKeyToString converts a map key to the string it would be as a JSON object key
+func cborKeyToString(key interface{}) (string, error) {
+	switch k := key.(type) {
+	case string:
+		return k, nil
+	case json.Number:
+		return string(k), nil
+	case bool:
+		return strconv.FormatBool(k), nil
+	case nil:
+		return TYPE_NULL, nil
+	}
+	return "", errors.New("cbor: map keys must be strings, numbers or booleans")
+}
@@ -0,0 +1,208 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"bytes"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenerateGo generates Go type definitions in package pkg for the documents described by a schema, to (de)serialize
+// them with encoding/json. Objects with "properties" become structs with a field for every property, named after the
+// root schema's title or "Root" at the root, and after the parent type and property name below it. Properties that
+// are not "required" are pointers with "omitempty", so absent values can be told apart from zero values.
+// Types follow "type", "items" and "additionalProperties" and referenced schemas become a type of their own.
+// Other keywords, such as "allOf" or "oneOf", are not reflected in the types, values they describe are interface{}
+func GenerateGo(s *Schema, pkg string) ([]byte, error) {
+	g := &goGenerator{names: make(map[*subSchema]string), used: make(map[string]bool)}
+
+	name := "Root"
+	if root := resolveRefSchema(s.rootSchema); root.title != nil {
+		name = goIdentifier(*root.title)
+	}
+	if typ := g.goType(s.rootSchema, name); typ != name {
+		g.definitions = append([]string{"type " + g.uniqueName(name) + " " + typ + "\n"}, g.definitions...)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gojsonschema. DO NOT EDIT.\n\n")
+	buf.WriteString("package " + pkg + "\n")
+	for _, definition := range g.definitions {
+		buf.WriteString("\n" + definition)
+	}
+	return format.Source(buf.Bytes())
+}
+
+type goGenerator struct {
+	// The names of the struct types generated for subSchemas
+	names map[*subSchema]string
+	// The type names that are taken
+	used        map[string]bool
+	definitions []string
+}
+
+// goType returns the Go type for values described by s, generating the struct types it needs.
+// name is the name of the struct type if one is needed, unless s is a reference
+func (g *goGenerator) goType(s *subSchema, name string) string {
+	if s.refSchema != nil {
+		if fragment := s.ref.GetUrl().Fragment; fragment != "" {
+			name = goIdentifier(fragment[strings.LastIndex(fragment, "/")+1:])
+		}
+		if target := resolveRefSchema(s); target.title != nil {
+			name = goIdentifier(*target.title)
+		}
+		s = resolveRefSchema(s)
+	}
+	if s.pass != nil {
+		return "interface{}"
+	}
+
+	var types []string
+	for _, t := range s.types.types {
+		if t != TYPE_NULL {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 && len(s.propertiesChildren) > 0 {
+		types = []string{TYPE_OBJECT}
+	}
+	if len(types) != 1 {
+		return "interface{}"
+	}
+
+	switch types[0] {
+	case TYPE_STRING:
+		return "string"
+	case TYPE_INTEGER:
+		return "int64"
+	case TYPE_NUMBER:
+		return "float64"
+	case TYPE_BOOLEAN:
+		return "bool"
+	case TYPE_ARRAY:
+		if s.itemsChildrenIsSingleSchema {
+			return "[]" + g.goType(s.itemsChildren[0], name+"Item")
+		}
+		return "[]interface{}"
+	}
+
+	// Objects
+	if len(s.propertiesChildren) > 0 {
+		return g.structType(s, name)
+	}
+	if additional, ok := s.additionalProperties.(*subSchema); ok {
+		return "map[string]" + g.goType(additional, name+"Value")
+	}
+	return "map[string]interface{}"
+}
+
+// structType returns the name of the struct type for an object with properties, generating it the first time.
+// The name is taken before the fields are generated, so recursive schemas refer to the type being generated
+func (g *goGenerator) structType(s *subSchema, name string) string {
+	if existing, ok := g.names[s]; ok {
+		return existing
+	}
+	name = g.uniqueName(name)
+	g.names[s] = name
+	index := len(g.definitions)
+	g.definitions = append(g.definitions, "")
+
+	var buf bytes.Buffer
+	writeGoComment(&buf, s.description)
+	buf.WriteString("type " + name + " struct {\n")
+	fields := make(map[string]bool)
+	for _, child := range s.propertiesChildren {
+		fieldName := goIdentifier(child.property)
+		for i := 2; fields[fieldName]; i++ {
+			fieldName = goIdentifier(child.property) + strconv.Itoa(i)
+		}
+		fields[fieldName] = true
+
+		required := isStringInSlice(s.required, child.property)
+		typ := g.goType(child, name+fieldName)
+		// Absent and null values need a pointer to be told apart from zero values, slices, maps and interfaces can be nil
+		if (!required || resolveRefSchema(child).types.Contains(TYPE_NULL)) && !strings.HasPrefix(typ, "[]") &&
+			!strings.HasPrefix(typ, "map[") && typ != "interface{}" {
+			typ = "*" + typ
+		}
+		tag := child.property
+		if !required {
+			tag += ",omitempty"
+		}
+
+		description := child.description
+		if description == nil {
+			description = resolveRefSchema(child).description
+		}
+		writeGoComment(&buf, description)
+		buf.WriteString(fieldName + " " + typ + " " + goStructTag("json:"+strconv.Quote(tag)) + "\n")
+	}
+	buf.WriteString("}\n")
+
+	g.definitions[index] = buf.String()
+	return name
+}
+
+// uniqueName returns name, or name with a number if a type with that name exists already
+func (g *goGenerator) uniqueName(name string) string {
+	unique := name
+	for i := 2; g.used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.used[unique] = true
+	return unique
+}
+
+func writeGoComment(buf *bytes.Buffer, text *string) {
+	if text == nil {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSpace(*text), "\n") {
+		buf.WriteString("// " + line + "\n")
+	}
+}
+
+// goStructTag returns a struct tag as a raw string literal where possible
+func goStructTag(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// goIdentifier turns a name into an exported Go identifier, i.e. "first_name" into "FirstName"
+func goIdentifier(name string) string {
+	var buf strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		buf.WriteRune(r)
+	}
+	identifier := buf.String()
+	if first := []rune(identifier); len(first) == 0 || !unicode.IsUpper(first[0]) {
+		identifier = "X" + identifier
+	}
+	return identifier
+}
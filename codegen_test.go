@@ -0,0 +1,108 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// typeCheckGo compiles generated code, which doesn't import anything
+func typeCheckGo(t *testing.T, source []byte) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "generated.go", source, parser.ParseComments)
+	require.Nil(t, err)
+	_, err = new(types.Config).Check("example", fset, []*ast.File{file}, nil)
+	require.Nil(t, err)
+}
+
+func TestGenerateGo(t *testing.T) {
+	s, err := NewSchema(NewStringLoader(simpleSchema))
+	require.Nil(t, err)
+
+	source, err := GenerateGo(s, "example")
+	require.Nil(t, err)
+	assert.Equal(t, "// Code generated by gojsonschema. DO NOT EDIT.\n"+`
+package example
+
+type ExampleSchema struct {
+	FirstName string `+"`json:\"firstName\"`"+`
+	LastName  string `+"`json:\"lastName\"`"+`
+	// Age in years
+	Age *int64 `+"`json:\"age,omitempty\"`"+`
+}
+`, string(source))
+	typeCheckGo(t, source)
+
+	s, err = NewSchema(NewStringLoader(`{
+		"type" : "object",
+		"properties" : {
+			"address" : {"$ref" : "#/definitions/address"},
+			"tags" : {"type" : "array", "items" : {"type" : "string"}},
+			"orders" : {
+				"type" : "array",
+				"items" : {
+					"type" : "object",
+					"properties" : {"id" : {"type" : "integer"}, "total" : {"type" : ["number", "null"]}},
+					"required" : ["id", "total"]
+				}
+			},
+			"labels" : {"type" : "object", "additionalProperties" : {"type" : "boolean"}},
+			"category" : {"$ref" : "#/definitions/category"},
+			"extra" : {}
+		},
+		"required" : ["address"],
+		"definitions" : {
+			"address" : {"type" : "object", "properties" : {"zip-code" : {"type" : "string"}}},
+			"category" : {"properties" : {"children" : {"type" : "array", "items" : {"$ref" : "#/definitions/category"}}}}
+		}
+	}`))
+	require.Nil(t, err)
+
+	source, err = GenerateGo(s, "example")
+	require.Nil(t, err)
+	assert.Equal(t, "// Code generated by gojsonschema. DO NOT EDIT.\n"+`
+package example
+
+type Root struct {
+	Address  Address          `+"`json:\"address\"`"+`
+	Tags     []string         `+"`json:\"tags,omitempty\"`"+`
+	Orders   []RootOrdersItem `+"`json:\"orders,omitempty\"`"+`
+	Labels   map[string]bool  `+"`json:\"labels,omitempty\"`"+`
+	Category *Category        `+"`json:\"category,omitempty\"`"+`
+	Extra    interface{}      `+"`json:\"extra,omitempty\"`"+`
+}
+
+type Address struct {
+	ZipCode *string `+"`json:\"zip-code,omitempty\"`"+`
+}
+
+type RootOrdersItem struct {
+	Id    int64    `+"`json:\"id\"`"+`
+	Total *float64 `+"`json:\"total\"`"+`
+}
+
+type Category struct {
+	Children []Category `+"`json:\"children,omitempty\"`"+`
+}
+`, string(source))
+	typeCheckGo(t, source)
+}
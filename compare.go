@@ -0,0 +1,281 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Change describes a single difference between two versions of a schema
+type Change struct {
+	// Path is the instance location the change applies to, i.e. (root).address.zip
+	// Array items are denoted by *
+	Path string
+	// Keyword is the keyword that changed
+	Keyword string
+	// Breaking is true if documents valid against the old schema may be invalid against the new one
+	Breaking bool
+	// Old holds the old value of the keyword, or nil if it was not set
+	Old interface{}
+	// New holds the new value of the keyword, or nil if it is not set anymore
+	New interface{}
+}
+
+// String returns a string representation of the change
+func (c Change) String() string {
+	kind := "non-breaking"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	return fmt.Sprintf("%s: %s changed from %v to %v (%s)", c.Path, c.Keyword, c.Old, c.New, kind)
+}
+
+// CompareSchemas compiles both schemas and lists the changes between them.
+// Only the common keywords are compared: type, required, enum, the numeric
+// bounds, additionalProperties and the properties and items subschemas
+func CompareSchemas(old JSONLoader, new JSONLoader) ([]Change, error) {
+	oldSchema, err := NewSchema(old)
+	if err != nil {
+		return nil, err
+	}
+	newSchema, err := NewSchema(new)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &schemaComparison{visited: make(map[[2]*subSchema]bool)}
+	c.compare(oldSchema.rootSchema, newSchema.rootSchema, NewJsonContext(STRING_CONTEXT_ROOT, nil))
+	return c.changes, nil
+}
+
+type schemaComparison struct {
+	changes []Change
+	// Guards against endless recursion on circular references
+	visited map[[2]*subSchema]bool
+}
+
+func (c *schemaComparison) add(context *JsonContext, keyword string, breaking bool, old interface{}, new interface{}) {
+	c.changes = append(c.changes, Change{
+		Path:     context.String(),
+		Keyword:  keyword,
+		Breaking: breaking,
+		Old:      old,
+		New:      new,
+	})
+}
+
+func (c *schemaComparison) compare(old *subSchema, new *subSchema, context *JsonContext) {
+	for old != nil && old.refSchema != nil {
+		old = old.refSchema
+	}
+	for new != nil && new.refSchema != nil {
+		new = new.refSchema
+	}
+	if old == nil || new == nil {
+		return
+	}
+
+	key := [2]*subSchema{old, new}
+	if c.visited[key] {
+		return
+	}
+	c.visited[key] = true
+
+	c.compareTypes(old, new, context)
+	c.compareRequired(old, new, context)
+	c.compareEnum(old, new, context)
+
+	c.compareLowerBound(KEY_MINIMUM, old.minimum, new.minimum, context)
+	c.compareLowerBound(KEY_EXCLUSIVE_MINIMUM, old.exclusiveMinimum, new.exclusiveMinimum, context)
+	c.compareUpperBound(KEY_MAXIMUM, old.maximum, new.maximum, context)
+	c.compareUpperBound(KEY_EXCLUSIVE_MAXIMUM, old.exclusiveMaximum, new.exclusiveMaximum, context)
+	c.compareLowerBound(KEY_MIN_LENGTH, intToRat(old.minLength), intToRat(new.minLength), context)
+	c.compareUpperBound(KEY_MAX_LENGTH, intToRat(old.maxLength), intToRat(new.maxLength), context)
+	c.compareLowerBound(KEY_MIN_ITEMS, intToRat(old.minItems), intToRat(new.minItems), context)
+	c.compareUpperBound(KEY_MAX_ITEMS, intToRat(old.maxItems), intToRat(new.maxItems), context)
+	c.compareLowerBound(KEY_MIN_PROPERTIES, intToRat(old.minProperties), intToRat(new.minProperties), context)
+	c.compareUpperBound(KEY_MAX_PROPERTIES, intToRat(old.maxProperties), intToRat(new.maxProperties), context)
+
+	c.compareAdditionalProperties(old, new, context)
+	c.compareProperties(old, new, context)
+
+	if old.itemsChildrenIsSingleSchema && new.itemsChildrenIsSingleSchema {
+		c.compare(old.itemsChildren[0], new.itemsChildren[0], NewJsonContext("*", context))
+	}
+}
+
+func (c *schemaComparison) compareTypes(old *subSchema, new *subSchema, context *JsonContext) {
+	if !new.types.IsTyped() {
+		if old.types.IsTyped() {
+			c.add(context, KEY_TYPE, false, old.types.String(), nil)
+		}
+		return
+	}
+	if !old.types.IsTyped() {
+		c.add(context, KEY_TYPE, true, nil, new.types.String())
+		return
+	}
+
+	allowsType := func(types jsonSchemaType, t string) bool {
+		return types.Contains(t) || (t == TYPE_INTEGER && types.Contains(TYPE_NUMBER))
+	}
+
+	narrowed, widened := false, false
+	for _, t := range old.types.types {
+		if !allowsType(new.types, t) {
+			narrowed = true
+		}
+	}
+	for _, t := range new.types.types {
+		if !allowsType(old.types, t) {
+			widened = true
+		}
+	}
+	if narrowed || widened {
+		c.add(context, KEY_TYPE, narrowed, old.types.String(), new.types.String())
+	}
+}
+
+func (c *schemaComparison) compareRequired(old *subSchema, new *subSchema, context *JsonContext) {
+	for _, property := range new.required {
+		if !isStringInSlice(old.required, property) {
+			c.add(NewJsonContext(property, context), KEY_REQUIRED, true, false, true)
+		}
+	}
+	for _, property := range old.required {
+		if !isStringInSlice(new.required, property) {
+			c.add(NewJsonContext(property, context), KEY_REQUIRED, false, true, false)
+		}
+	}
+}
+
+func (c *schemaComparison) compareEnum(old *subSchema, new *subSchema, context *JsonContext) {
+	if len(new.enum) == 0 {
+		if len(old.enum) > 0 {
+			c.add(context, KEY_ENUM, false, old.enum, nil)
+		}
+		return
+	}
+	if len(old.enum) == 0 {
+		c.add(context, KEY_ENUM, true, nil, new.enum)
+		return
+	}
+
+	narrowed, widened := false, false
+	for _, v := range old.enum {
+		if !isStringInSlice(new.enum, v) {
+			narrowed = true
+		}
+	}
+	for _, v := range new.enum {
+		if !isStringInSlice(old.enum, v) {
+			widened = true
+		}
+	}
+	if narrowed || widened {
+		c.add(context, KEY_ENUM, narrowed, old.enum, new.enum)
+	}
+}
+
+// compareLowerBound handles keywords where raising the value makes the schema stricter
+func (c *schemaComparison) compareLowerBound(keyword string, old *big.Rat, new *big.Rat, context *JsonContext) {
+	switch {
+	case old == nil && new == nil:
+	case old == nil:
+		c.add(context, keyword, true, nil, new.RatString())
+	case new == nil:
+		c.add(context, keyword, false, old.RatString(), nil)
+	case old.Cmp(new) != 0:
+		c.add(context, keyword, new.Cmp(old) > 0, old.RatString(), new.RatString())
+	}
+}
+
+// compareUpperBound handles keywords where lowering the value makes the schema stricter
+func (c *schemaComparison) compareUpperBound(keyword string, old *big.Rat, new *big.Rat, context *JsonContext) {
+	switch {
+	case old == nil && new == nil:
+	case old == nil:
+		c.add(context, keyword, true, nil, new.RatString())
+	case new == nil:
+		c.add(context, keyword, false, old.RatString(), nil)
+	case old.Cmp(new) != 0:
+		c.add(context, keyword, new.Cmp(old) < 0, old.RatString(), new.RatString())
+	}
+}
+
+func (c *schemaComparison) compareAdditionalProperties(old *subSchema, new *subSchema, context *JsonContext) {
+	oldAllowed, oldIsBool := old.additionalProperties.(bool)
+	newAllowed, newIsBool := new.additionalProperties.(bool)
+	if old.additionalProperties == nil {
+		oldAllowed, oldIsBool = true, true
+	}
+	if new.additionalProperties == nil {
+		newAllowed, newIsBool = true, true
+	}
+
+	switch {
+	case oldIsBool && newIsBool:
+		if oldAllowed != newAllowed {
+			c.add(context, KEY_ADDITIONAL_PROPERTIES, !newAllowed, oldAllowed, newAllowed)
+		}
+	case oldIsBool:
+		// From a boolean to a schema only loosens the constraint if nothing was allowed before
+		c.add(context, KEY_ADDITIONAL_PROPERTIES, oldAllowed, oldAllowed, STRING_SCHEMA)
+	case newIsBool:
+		c.add(context, KEY_ADDITIONAL_PROPERTIES, !newAllowed, STRING_SCHEMA, newAllowed)
+	default:
+		c.compare(old.additionalProperties.(*subSchema), new.additionalProperties.(*subSchema), context)
+	}
+}
+
+func (c *schemaComparison) compareProperties(old *subSchema, new *subSchema, context *JsonContext) {
+	additionalAllowed := true
+	if b, ok := new.additionalProperties.(bool); ok {
+		additionalAllowed = b
+	}
+
+	for _, oldProperty := range old.propertiesChildren {
+		newProperty := findProperty(new, oldProperty.property)
+		subContext := NewJsonContext(oldProperty.property, context)
+		if newProperty == nil {
+			c.add(subContext, KEY_PROPERTIES, !additionalAllowed, STRING_SCHEMA, nil)
+			continue
+		}
+		c.compare(oldProperty, newProperty, subContext)
+	}
+
+	for _, newProperty := range new.propertiesChildren {
+		if findProperty(old, newProperty.property) == nil {
+			c.add(NewJsonContext(newProperty.property, context), KEY_PROPERTIES, false, nil, STRING_SCHEMA)
+		}
+	}
+}
+
+func findProperty(s *subSchema, property string) *subSchema {
+	for _, child := range s.propertiesChildren {
+		if child.property == property {
+			return child
+		}
+	}
+	return nil
+}
+
+func intToRat(i *int) *big.Rat {
+	if i == nil {
+		return nil
+	}
+	return big.NewRat(int64(*i), 1)
+}
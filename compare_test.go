@@ -0,0 +1,91 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareSchemasAddedRequired(t *testing.T) {
+	changes, err := CompareSchemas(NewStringLoader(simpleSchema), NewStringLoader(`{
+		"type": "object",
+		"properties": {
+			"firstName": {"type": "string"},
+			"lastName": {"type": "string"},
+			"age": {"type": "integer", "minimum": 0}
+		},
+		"required": ["firstName", "lastName", "age"]
+	}`))
+	require.Nil(t, err)
+
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "(root).age", changes[0].Path)
+		assert.Equal(t, KEY_REQUIRED, changes[0].Keyword)
+		assert.True(t, changes[0].Breaking)
+	}
+}
+
+func TestCompareSchemasAddedOptional(t *testing.T) {
+	changes, err := CompareSchemas(NewStringLoader(simpleSchema), NewStringLoader(`{
+		"type": "object",
+		"properties": {
+			"firstName": {"type": "string"},
+			"lastName": {"type": "string"},
+			"age": {"type": "integer", "minimum": 0},
+			"nickname": {"type": "string"}
+		},
+		"required": ["firstName", "lastName"]
+	}`))
+	require.Nil(t, err)
+
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "(root).nickname", changes[0].Path)
+		assert.Equal(t, KEY_PROPERTIES, changes[0].Keyword)
+		assert.False(t, changes[0].Breaking)
+	}
+}
+
+func TestCompareSchemasKeywords(t *testing.T) {
+	changes, err := CompareSchemas(NewStringLoader(`{
+		"properties": {
+			"color": {"enum": ["red", "green", "blue"]},
+			"size": {"type": "integer", "maximum": 10},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		}
+	}`), NewStringLoader(`{
+		"properties": {
+			"color": {"enum": ["red", "green"]},
+			"size": {"type": "number", "maximum": 20},
+			"tags": {"type": "array", "items": {"type": "string", "maxLength": 5}}
+		},
+		"additionalProperties": false
+	}`))
+	require.Nil(t, err)
+
+	breaking := map[string]bool{}
+	for _, change := range changes {
+		breaking[change.Path+" "+change.Keyword] = change.Breaking
+	}
+	assert.Equal(t, map[string]bool{
+		"(root) additionalProperties": true,
+		"(root).color enum":           true,
+		"(root).size type":            false,
+		"(root).size maximum":         false,
+		"(root).tags.* maxLength":     true,
+	}, breaking)
+}
@@ -0,0 +1,324 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// CustomKeyword is the interface all keywords added to CustomKeywordChain must implement
+	CustomKeyword interface {
+		// Validate checks value against the keyword. keywordValue holds the value of the keyword in the schema,
+		// v can be used to validate (parts of) the value against subschemas
+		Validate(keywordValue interface{}, value interface{}, v Validator, context *JsonContext) []ResultError
+	}
+
+	// Validator gives custom keywords access to the validation in progress
+	Validator interface {
+		// ValidateSubschema validates instance against schema, which can be any JSON value that is a valid schema.
+		// References in the subschema are resolved the same way as in the schema the keyword appears in
+		ValidateSubschema(schema interface{}, instance interface{}, context *JsonContext) []ResultError
+		// Now returns the current time, which is the time of the clock set with SchemaLoader.SetClock if any.
+		// Keywords that depend on the time should use it, so their results can be reproduced
+		Now() time.Time
+	}
+
+	// CustomKeywordChain holds the custom keywords
+	CustomKeywordChain struct {
+		keywords map[string]CustomKeyword
+	}
+)
+
+var (
+	// CustomKeywords holds the custom keywords, and is a public variable
+	// so library users can add their own keywords
+	CustomKeywords = CustomKeywordChain{
+		keywords: map[string]CustomKeyword{},
+	}
+
+	keywordLock = new(sync.RWMutex)
+
+	// keywordAliases maps keywords to the keyword they stand for, see RegisterKeywordAlias
+	keywordAliases = map[string]string{}
+)
+
+// RegisterKeywordAlias makes schemas compiled afterwards treat the keyword alias exactly like the keyword canonical,
+// i.e. RegisterKeywordAlias("minLen", "minLength") for a legacy dialect. A schema that has both uses canonical.
+// Aliases are known keywords for SchemaLoader.RejectUnknownKeywords
+func RegisterKeywordAlias(alias string, canonical string) {
+	keywordLock.Lock()
+	keywordAliases[alias] = canonical
+	keywordLock.Unlock()
+}
+
+// RemoveKeywordAlias removes an alias registered with RegisterKeywordAlias (if it exists)
+func RemoveKeywordAlias(alias string) {
+	keywordLock.Lock()
+	delete(keywordAliases, alias)
+	keywordLock.Unlock()
+}
+
+// isKeywordAlias reports whether keyword is an alias registered with RegisterKeywordAlias
+func isKeywordAlias(keyword string) bool {
+	keywordLock.RLock()
+	_, ok := keywordAliases[keyword]
+	keywordLock.RUnlock()
+
+	return ok
+}
+
+// applyKeywordAliases returns the keywords of a schema with the aliases replaced by the keyword they stand for.
+// m itself is never modified, as it is the document the schema was compiled from
+func applyKeywordAliases(m map[string]interface{}) map[string]interface{} {
+	keywordLock.RLock()
+	defer keywordLock.RUnlock()
+
+	var renamed map[string]interface{}
+	for alias, canonical := range keywordAliases {
+		value, ok := m[alias]
+		if !ok || existsMapKey(m, canonical) {
+			continue
+		}
+		if renamed == nil {
+			renamed = make(map[string]interface{}, len(m))
+			for k, v := range m {
+				renamed[k] = v
+			}
+		}
+		delete(renamed, alias)
+		renamed[canonical] = value
+	}
+	if renamed == nil {
+		return m
+	}
+	return renamed
+}
+
+// Add adds a CustomKeyword to the CustomKeywordChain
+// Schemas compiled afterwards validate the keyword with the given name using k
+func (c *CustomKeywordChain) Add(name string, k CustomKeyword) *CustomKeywordChain {
+	keywordLock.Lock()
+	c.keywords[name] = k
+	keywordLock.Unlock()
+
+	return c
+}
+
+// Remove deletes a CustomKeyword from the CustomKeywordChain (if it exists)
+func (c *CustomKeywordChain) Remove(name string) *CustomKeywordChain {
+	keywordLock.Lock()
+	delete(c.keywords, name)
+	keywordLock.Unlock()
+
+	return c
+}
+
+// Has checks to see if the CustomKeywordChain holds a CustomKeyword with the given name
+func (c *CustomKeywordChain) Has(name string) bool {
+	keywordLock.RLock()
+	_, ok := c.keywords[name]
+	keywordLock.RUnlock()
+
+	return ok
+}
+
+// customKeywordUse is a custom keyword as it appears in a subSchema
+type customKeywordUse struct {
+	name    string
+	keyword CustomKeyword
+	value   interface{}
+	// The subschemas the keyword validated against, compiled on first use
+	subschemas *subschemaCache
+}
+
+// maxCachedSubschemas is the number of subschemas a subschemaCache holds at most
+const maxCachedSubschemas = 64
+
+// subschemaCache holds the subschemas a custom keyword passed to Validator.ValidateSubschema by the identity of their
+// document node, which usually is a part of the value of the keyword. So every subschema is only compiled once and
+// validating is read-only afterwards. Subschemas beyond maxCachedSubschemas, such as those a keyword builds anew for
+// every value, are compiled every time they are validated against instead
+type subschemaCache struct {
+	lock    sync.RWMutex
+	schemas map[interface{}]compiledSubschema
+}
+
+type compiledSubschema struct {
+	// The document node the subschema was compiled from, which is referenced so its identity can't be reused
+	documentNode interface{}
+	schema       *subSchema
+	err          error
+}
+
+// subschemaKey returns the identity of a document node, false for nodes that can't be schemas
+func subschemaKey(documentNode interface{}) (interface{}, bool) {
+	switch node := documentNode.(type) {
+	case map[string]interface{}:
+		return reflect.ValueOf(node).Pointer(), true
+	case bool:
+		return node, true
+	}
+	return nil, false
+}
+
+// get returns documentNode compiled as a subschema of parent, compiling it if it wasn't before
+func (c *subschemaCache) get(d *Schema, documentNode interface{}, property string, parent *subSchema) (*subSchema, error) {
+	key, ok := subschemaKey(documentNode)
+	if !ok {
+		return d.parseDetachedSchema(documentNode, property, parent)
+	}
+
+	c.lock.RLock()
+	compiled, ok := c.schemas[key]
+	c.lock.RUnlock()
+	if ok {
+		return compiled.schema, compiled.err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if compiled, ok := c.schemas[key]; ok {
+		return compiled.schema, compiled.err
+	}
+	compiled = compiledSubschema{documentNode: documentNode}
+	compiled.schema, compiled.err = d.parseDetachedSchema(documentNode, property, parent)
+	if c.schemas == nil {
+		c.schemas = map[interface{}]compiledSubschema{}
+	}
+	if len(c.schemas) < maxCachedSubschemas {
+		c.schemas[key] = compiled
+	}
+	return compiled.schema, compiled.err
+}
+
+// find returns the custom keywords used in a schema, sorted by name so they are always validated in the same order
+func (c *CustomKeywordChain) find(m map[string]interface{}) []customKeywordUse {
+	keywordLock.RLock()
+	defer keywordLock.RUnlock()
+
+	var uses []customKeywordUse
+	for name, keyword := range c.keywords {
+		if value, ok := m[name]; ok {
+			uses = append(uses, customKeywordUse{name: name, keyword: keyword, value: value, subschemas: &subschemaCache{}})
+		}
+	}
+	sort.Slice(uses, func(i, j int) bool {
+		return uses[i].name < uses[j].name
+	})
+
+	return uses
+}
+
+// keywordValidator implements Validator for a single custom keyword in a subSchema
+type keywordValidator struct {
+	use       *customKeywordUse
+	subSchema *subSchema
+	state     *validationState
+}
+
+func (kv *keywordValidator) ValidateSubschema(schema interface{}, instance interface{}, context *JsonContext) []ResultError {
+	newSchema, err := kv.use.subschemas.get(kv.state.schema, schema, kv.use.name, kv.subSchema)
+	return kv.validateCompiled(newSchema, err, instance, context)
+}
+
+// validateCompiled validates instance against a compiled subschema, or reports the error compiling it failed with
+func (kv *keywordValidator) validateCompiled(newSchema *subSchema, err error, instance interface{}, context *JsonContext) []ResultError {
+	if err != nil {
+		internalError := new(InternalError)
+		newError(internalError, context, instance, kv.state.locale(), ErrorDetails{"error": err})
+		return []ResultError{internalError}
+	}
+
+	return newSchema.subValidateWithContext(instance, context, kv.state).Errors()
+}
+
+func (kv *keywordValidator) Now() time.Time {
+	return kv.state.now()
+}
+
+func (v *subSchema) validateCustomKeywords(currentSubSchema *subSchema, value interface{}, result *Result, context *JsonContext) {
+
+	if internalLogEnabled {
+		internalLog("validateCustomKeywords %s", context.String())
+		internalLog(" %v", value)
+	}
+
+	// The keyword may hold on to the context
+	if len(currentSubSchema.customKeywords) > 0 {
+		context.retain()
+	}
+
+	for i := range currentSubSchema.customKeywords {
+		v.validateCustomKeyword(&currentSubSchema.customKeywords[i], currentSubSchema, value, result, context)
+	}
+}
+
+func (v *subSchema) validateCustomKeyword(use *customKeywordUse, currentSubSchema *subSchema, value interface{}, result *Result, context *JsonContext) {
+	defer recoverExtension(result, context, value, "keyword "+use.name)
+	validator := &keywordValidator{use: use, subSchema: currentSubSchema, state: result.state}
+	for _, err := range use.keyword.Validate(use.value, value, validator, context) {
+		result.addError(err)
+	}
+}
+
+// ParseJSONKeyword is a CustomKeyword for strings that hold a JSON document themselves. The string is parsed and the
+// document it holds is validated against the subschema that is the value of the keyword. Strings that don't hold valid
+// JSON result in an InvalidJSONStringError, values that aren't strings are ignored. It is not added by default,
+// so its name can be chosen:
+//
+//	gojsonschema.CustomKeywords.Add("parseJSON", gojsonschema.ParseJSONKeyword{})
+type ParseJSONKeyword struct{}
+
+// Validate implements CustomKeyword
+func (ParseJSONKeyword) Validate(keywordValue interface{}, value interface{}, v Validator, context *JsonContext) []ResultError {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var document interface{}
+	err := decoder.Decode(&document)
+	if err == nil {
+		// Anything after the document makes the string invalid as well
+		if _, trailing := decoder.Token(); trailing != io.EOF {
+			err = errors.New("unexpected data after the JSON document")
+		}
+	}
+	if err != nil {
+		invalid := new(InvalidJSONStringError)
+		newError(invalid, context, value, validatorLocale(v), ErrorDetails{"error": err})
+		return []ResultError{invalid}
+	}
+
+	return v.ValidateSubschema(keywordValue, document, context)
+}
+
+// validatorLocale returns the locale of the validation a keyword is called from
+func validatorLocale(v Validator) locale {
+	if kv, ok := v.(*keywordValidator); ok {
+		return kv.state.locale()
+	}
+	return Locale
+}
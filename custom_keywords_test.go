@@ -0,0 +1,375 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// myAllOfKeyword behaves like allOf by delegating to subschema validation
+type myAllOfKeyword struct{}
+
+func (myAllOfKeyword) Validate(keywordValue interface{}, value interface{}, v Validator, context *JsonContext) []ResultError {
+	var errs []ResultError
+	for _, schema := range keywordValue.([]interface{}) {
+		errs = append(errs, v.ValidateSubschema(schema, value, context)...)
+	}
+	return errs
+}
+
+func TestCustomKeywordSubschemaValidation(t *testing.T) {
+	CustomKeywords.Add("myAllOf", myAllOfKeyword{})
+	defer CustomKeywords.Remove("myAllOf")
+
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"name" : {
+				"myAllOf" : [
+					{"type" : "string"},
+					{"$ref" : "#/definitions/short"}
+				]
+			}
+		},
+		"definitions" : {
+			"short" : {"maxLength" : 3}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"name" : "abc"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`{"name" : "abcd"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeStringLTE, result.Errors()[0].Type())
+		assert.Equal(t, "name", result.Errors()[0].Field())
+	}
+
+	result, err = schema.Validate(NewStringLoader(`{"name" : 1234}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeInvalidType, result.Errors()[0].Type())
+	}
+}
+
+// notInFutureKeyword rejects date-times after the current time of the validation
+type notInFutureKeyword struct{}
+
+func (notInFutureKeyword) Validate(keywordValue interface{}, value interface{}, v Validator, context *JsonContext) []ResultError {
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	date, err := time.Parse(time.RFC3339, s)
+	if err != nil || !date.After(v.Now()) {
+		return nil
+	}
+	resultErr := &ResultErrorFields{}
+	resultErr.SetType("not_in_future")
+	resultErr.SetContext(context)
+	resultErr.SetValue(value)
+	resultErr.SetDescription("Date is in the future")
+	return []ResultError{resultErr}
+}
+
+func TestCustomKeywordSubschemaCompiledOnce(t *testing.T) {
+	CustomKeywords.Add("myAllOf", myAllOfKeyword{})
+	defer CustomKeywords.Remove("myAllOf")
+
+	// "id" is deprecated in draft-07, so compiling the subschema adds a warning
+	schema, err := NewSchema(NewStringLoader(`{
+		"$schema" : "http://json-schema.org/draft-07/schema#",
+		"myAllOf" : [{"id" : "http://example.com/short.json", "maxLength" : 3}]
+	}`))
+	require.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				result, err := schema.Validate(NewStringLoader(`"abcd"`))
+				assert.Nil(t, err)
+				assert.Len(t, result.Errors(), 1)
+				schema.Warnings()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, schema.Warnings(), 1, "the subschema is only compiled once")
+}
+
+// myMaxLenKeyword builds a new subschema for every value it validates
+type myMaxLenKeyword struct{}
+
+func (myMaxLenKeyword) Validate(keywordValue interface{}, value interface{}, v Validator, context *JsonContext) []ResultError {
+	return v.ValidateSubschema(map[string]interface{}{"maxLength": keywordValue}, value, context)
+}
+
+func TestCustomKeywordBuiltSubschemas(t *testing.T) {
+	CustomKeywords.Add("myMaxLen", myMaxLenKeyword{})
+	defer CustomKeywords.Remove("myMaxLen")
+
+	schema, err := NewSchema(NewStringLoader(`{"items" : {"myMaxLen" : 3}}`))
+	require.Nil(t, err)
+
+	items := make([]interface{}, 2*maxCachedSubschemas)
+	for i := range items {
+		items[i] = strings.Repeat("a", i%5)
+	}
+	result, err := schema.Validate(NewGoLoader(items))
+	require.Nil(t, err)
+	assert.Len(t, result.Errors(), 2*maxCachedSubschemas/5)
+}
+
+// notInFutureFormat is the format equivalent of notInFutureKeyword
+type notInFutureFormat struct{}
+
+func (notInFutureFormat) IsFormat(input interface{}) bool {
+	return notInFutureFormat{}.IsFormatAt(input, time.Now())
+}
+
+func (notInFutureFormat) IsFormatAt(input interface{}, now time.Time) bool {
+	s, ok := input.(string)
+	if !ok {
+		return true
+	}
+	date, err := time.Parse(time.RFC3339, s)
+	return err == nil && !date.After(now)
+}
+
+func TestSchemaLoaderSetClock(t *testing.T) {
+	CustomKeywords.Add("notInFuture", notInFutureKeyword{})
+	defer CustomKeywords.Remove("notInFuture")
+	FormatCheckers.Add("past-date-time", notInFutureFormat{})
+	defer FormatCheckers.Remove("past-date-time")
+
+	schemaJSON := NewStringLoader(`{
+		"properties" : {
+			"created" : {"format" : "date-time", "notInFuture" : true},
+			"updated" : {"format" : "past-date-time"}
+		}
+	}`)
+	document := NewStringLoader(`{"created" : "2020-06-01T12:00:00Z", "updated" : "2020-06-01T12:00:00Z"}`)
+
+	sl := NewSchemaLoader()
+	sl.SetClock(func() time.Time {
+		return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	})
+	schema, err := sl.Compile(schemaJSON)
+	require.Nil(t, err)
+
+	result, err := schema.Validate(document)
+	require.Nil(t, err)
+	errorTypes := map[string]string{}
+	for _, resultErr := range result.Errors() {
+		errorTypes[resultErr.Field()] = resultErr.Type()
+	}
+	assert.Equal(t, map[string]string{"created": "not_in_future", "updated": ErrorTypeFormat}, errorTypes)
+
+	sl = NewSchemaLoader()
+	sl.SetClock(func() time.Time {
+		return time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	})
+	schema, err = sl.Compile(schemaJSON)
+	require.Nil(t, err)
+
+	result, err = schema.Validate(document)
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+}
+
+// panickingFormat and panickingKeyword are extensions with a bug
+type panickingFormat struct{}
+
+func (panickingFormat) IsFormat(input interface{}) bool {
+	var m map[string]bool
+	m["boom"] = true
+	return true
+}
+
+type panickingKeyword struct{}
+
+func (panickingKeyword) Validate(keywordValue interface{}, value interface{}, v Validator, context *JsonContext) []ResultError {
+	panic("boom")
+}
+
+func TestExtensionPanicRecovery(t *testing.T) {
+	FormatCheckers.Add("panicking", panickingFormat{})
+	defer FormatCheckers.Remove("panicking")
+	CustomKeywords.Add("panicking", panickingKeyword{})
+	defer CustomKeywords.Remove("panicking")
+
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"code" : {"type" : "string", "format" : "panicking"},
+			"name" : {"panicking" : true},
+			"age" : {"minimum" : 0}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"code" : "abc", "name" : "John", "age" : -1}`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+	errs := map[string]string{}
+	for _, e := range result.Errors() {
+		errs[e.Field()] = e.Type() + ": " + e.Description()
+	}
+	assert.Equal(t, map[string]string{
+		"code": "extension_panic: format panicking panicked: assignment to entry in nil map",
+		"name": "extension_panic: keyword panicking panicked: boom",
+		"age":  "number_gte: Must be greater than or equal to 0",
+	}, errs)
+}
+
+func TestRegisterKeywordAlias(t *testing.T) {
+	RegisterKeywordAlias("minLen", "minLength")
+	defer RemoveKeywordAlias("minLen")
+
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"name" : {"type" : "string", "minLen" : 3},
+			"code" : {"minLen" : 1, "minLength" : 4}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"name" : "Jo"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeStringGTE, result.Errors()[0].Type())
+		assert.Equal(t, "name", result.Errors()[0].Field())
+	}
+
+	result, err = schema.Validate(NewStringLoader(`{"name" : "Joe", "code" : "abc"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "code", result.Errors()[0].Field())
+	}
+
+	// The alias is validated like the keyword it stands for when compiling
+	_, err = NewSchema(NewStringLoader(`{"minLen" : "three"}`))
+	assert.NotNil(t, err)
+
+	RemoveKeywordAlias("minLen")
+	schema, err = NewSchema(NewStringLoader(`{"minLen" : 3}`))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`"Jo"`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+}
+
+func TestRejectUnknownKeywords(t *testing.T) {
+	compile := func(schema string) error {
+		sl := NewSchemaLoader()
+		sl.RejectUnknownKeywords = true
+		_, err := sl.Compile(NewStringLoader(schema))
+		return err
+	}
+
+	assert.Nil(t, compile(`{"title" : "a", "properties" : {"a" : {"minLength" : 3}}, "definitions" : {"b" : {"const" : 1}}}`))
+	assert.EqualError(t, compile(`{"properties" : {"a" : {"minLen" : 3}}}`), "Unknown keyword minLen")
+
+	// The keywords are those of the draft of the schema
+	assert.Nil(t, compile(`{"$schema" : "http://json-schema.org/draft-04/schema#", "id" : "a.json", "$ref" : "#/definitions/a", "definitions" : {"a" : {}}}`))
+	assert.Nil(t, compile(`{"$schema" : "http://json-schema.org/draft-07/schema#", "contentMediaType" : "text/plain", "contentEncoding" : "base64", "writeOnly" : true}`))
+	assert.EqualError(t, compile(`{"$schema" : "http://json-schema.org/draft-07/schema#", "$anchor" : "a"}`), "Unknown keyword $anchor")
+	assert.Nil(t, compile(`{"$schema" : "https://json-schema.org/draft/2019-09/schema", "$anchor" : "a", "deprecated" : true, "$defs" : {"a" : {"dependentRequired" : {}}}}`))
+
+	// Aliases and custom keywords are known
+	RegisterKeywordAlias("minLen", "minLength")
+	defer RemoveKeywordAlias("minLen")
+	assert.Nil(t, compile(`{"properties" : {"a" : {"minLen" : 3}}}`))
+	assert.Nil(t, compile(`{"minLen" : 3, "minLength" : 4}`))
+	CustomKeywords.Add("myAllOf", myAllOfKeyword{})
+	defer CustomKeywords.Remove("myAllOf")
+	assert.Nil(t, compile(`{"myAllOf" : [{"minLength" : 1}]}`))
+
+	schema, err := NewSchema(NewStringLoader(`{"maxLen" : 3}`))
+	require.Nil(t, err, "unknown keywords are ignored by default")
+	result, err := schema.Validate(NewStringLoader(`"long"`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+}
+
+func TestParseJSONKeyword(t *testing.T) {
+	CustomKeywords.Add("parseJSON", ParseJSONKeyword{})
+	defer CustomKeywords.Remove("parseJSON")
+
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"payload" : {
+				"type" : "string",
+				"parseJSON" : {"required" : ["id"], "properties" : {"id" : {"type" : "integer"}}}
+			}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"payload" : "{\"id\" : 42}"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`{"payload" : "{\"id\" : \"42\"}"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeInvalidType, result.Errors()[0].Type())
+	}
+
+	for _, payload := range []string{`"{\"id\" : "`, `"{} {}"`, `"not json"`} {
+		result, err = schema.Validate(NewStringLoader(`{"payload" : ` + payload + `}`))
+		require.Nil(t, err)
+		if assert.Len(t, result.Errors(), 1, payload) {
+			assert.Equal(t, ErrorTypeInvalidJSONString, result.Errors()[0].Type())
+			assert.Equal(t, "payload", result.Errors()[0].Field())
+		}
+	}
+}
+
+func TestParseJSONKeywordConcurrent(t *testing.T) {
+	CustomKeywords.Add("parseJSON", ParseJSONKeyword{})
+	defer CustomKeywords.Remove("parseJSON")
+
+	schema, err := NewSchema(NewStringLoader(`{"parseJSON" : {"properties" : {"id" : {"type" : "integer"}}}}`))
+	require.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				result, err := schema.Validate(NewStringLoader(`"{\"id\" : \"42\"}"`))
+				assert.Nil(t, err)
+				assert.Len(t, result.Errors(), 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The value of the keyword is compiled once
+	assert.Len(t, schema.rootSchema.customKeywords[0].subschemas.schemas, 1)
+}
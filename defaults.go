@@ -0,0 +1,108 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+// AccessMode is the direction a document is used in, which decides the defaults ApplyDefaults fills in
+type AccessMode int
+
+const (
+	// AccessAny applies the defaults of all properties
+	AccessAny AccessMode = iota
+	// AccessRead is for documents that are read, such as responses. Defaults of writeOnly properties are skipped
+	AccessRead
+	// AccessWrite is for documents that are written, such as requests. Defaults of readOnly properties are skipped
+	AccessWrite
+)
+
+// ApplyDefaults loads a JSON document and fills in the "default" of every property that is missing from an object
+// described by the schema, following "properties", "items", "allOf" and "$ref". The document is not validated.
+// Properties that are readOnly or writeOnly are skipped depending on mode
+func (d *Schema) ApplyDefaults(l JSONLoader, mode AccessMode) (interface{}, error) {
+	document, err := l.LoadJSON()
+	if err != nil {
+		return nil, err
+	}
+	d.rootSchema.applyDefaults(document, mode)
+	return document, nil
+}
+
+// applyDefaults fills in the defaults of the missing properties of node and its children.
+// Defaults that were filled in are not descended into, so recursive schemas don't fill in defaults endlessly
+func (s *subSchema) applyDefaults(node interface{}, mode AccessMode) {
+	if s.refSchema != nil {
+		s.refSchema.applyDefaults(node, mode)
+		return
+	}
+	for _, allOfSchema := range s.allOf {
+		allOfSchema.applyDefaults(node, mode)
+	}
+
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for _, property := range s.propertiesChildren {
+			if child, ok := value[property.property]; ok {
+				property.applyDefaults(child, mode)
+			} else if defaultValue, ok := property.defaultFor(mode); ok {
+				value[property.property] = defaultValue
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			if s.itemsChildrenIsSingleSchema {
+				s.itemsChildren[0].applyDefaults(item, mode)
+			} else if i < len(s.itemsChildren) {
+				s.itemsChildren[i].applyDefaults(item, mode)
+			}
+		}
+	}
+}
+
+// defaultFor returns a copy of the default of the subSchema, unless it has none or is excluded by mode
+func (s *subSchema) defaultFor(mode AccessMode) (interface{}, bool) {
+	if s.refSchema != nil {
+		return s.refSchema.defaultFor(mode)
+	}
+	m, ok := s.documentNode.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if mode == AccessWrite && m[KEY_READ_ONLY] == true || mode == AccessRead && m[KEY_WRITE_ONLY] == true {
+		return nil, false
+	}
+	defaultValue, ok := m[KEY_DEFAULT]
+	if !ok {
+		return nil, false
+	}
+	return copyJSON(defaultValue), true
+}
+
+// copyJSON returns a deep copy of a decoded JSON value, so the document never shares objects with the schema
+func copyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			c[key] = copyJSON(item)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = copyJSON(item)
+		}
+		return c
+	}
+	return value
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDefaultsAccessMode(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"id" : {"type" : "integer", "readOnly" : true, "default" : 0},
+			"password" : {"type" : "string", "writeOnly" : true, "default" : "changeme"},
+			"role" : {"$ref" : "#/definitions/role"},
+			"tags" : {"items" : {"properties" : {"color" : {"default" : "red"}}}}
+		},
+		"definitions" : {
+			"role" : {"enum" : ["user", "admin"], "default" : "user"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	document := `{"tags" : [{}, {"color" : "blue"}]}`
+
+	applied, err := schema.ApplyDefaults(NewStringLoader(document), AccessWrite)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"password": "changeme",
+		"role":     "user",
+		"tags":     []interface{}{map[string]interface{}{"color": "red"}, map[string]interface{}{"color": "blue"}},
+	}, applied)
+
+	applied, err = schema.ApplyDefaults(NewStringLoader(document), AccessRead)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":   json.Number("0"),
+		"role": "user",
+		"tags": []interface{}{map[string]interface{}{"color": "red"}, map[string]interface{}{"color": "blue"}},
+	}, applied)
+
+	applied, err = schema.ApplyDefaults(NewStringLoader(`{"id" : 5}`), AccessAny)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":       json.Number("5"),
+		"password": "changeme",
+		"role":     "user",
+	}, applied)
+}
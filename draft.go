@@ -15,9 +15,11 @@
 package gojsonschema
 
 import (
+	"encoding/json"
 	"errors"
 	"math"
 	"reflect"
+	"sync"
 
 	"github.com/xeipuuv/gojsonreference"
 )
@@ -62,6 +64,53 @@ func init() {
 	}
 }
 
+var (
+	keywordsOfDrafts     map[Draft]map[string]bool
+	keywordsOfDraftsOnce sync.Once
+
+	// keywords2019 holds the keywords added by draft 2019-09 and 2020-12, which are known in hybrid mode.
+	// No meta-schema of these drafts is embedded to take them from
+	keywords2019 = []string{
+		KEY_DEFS, KEY_VOCABULARY, "$anchor", "$dynamicAnchor", "$dynamicRef", "$recursiveAnchor", "$recursiveRef",
+		"dependentRequired", "dependentSchemas", "prefixItems", KEY_UNEVALUATED_ITEMS, "unevaluatedProperties",
+		"minContains", "maxContains", "contentSchema", "deprecated", KEY_WRITE_ONLY,
+	}
+
+	// extensionKeywords holds the keywords this package supports beyond those of the drafts
+	extensionKeywords = []string{KEY_PROPERTY_ORDER, KEY_DISCRIMINATOR}
+)
+
+// draftKeywords returns the keywords of a draft, which are the properties of its meta-schema.
+// Hybrid mode knows the keywords of all drafts
+func draftKeywords(draft Draft) map[string]bool {
+	keywordsOfDraftsOnce.Do(func() {
+		keywordsOfDrafts = map[Draft]map[string]bool{Hybrid: {}}
+		add := func(draft Draft, keywords ...string) {
+			for _, keyword := range keywords {
+				keywordsOfDrafts[draft][keyword] = true
+				keywordsOfDrafts[Hybrid][keyword] = true
+			}
+		}
+
+		for _, config := range drafts {
+			var metaSchema struct {
+				Properties map[string]interface{} `json:"properties"`
+			}
+			if err := json.Unmarshal([]byte(config.MetaSchema), &metaSchema); err != nil {
+				panic(err)
+			}
+			keywordsOfDrafts[config.Version] = map[string]bool{}
+			add(config.Version, sortedKeys(metaSchema.Properties)...)
+			add(config.Version, extensionKeywords...)
+		}
+		// The meta-schema of draft 4 doesn't list $ref and the embedded one of draft 7 predates writeOnly
+		add(Draft4, KEY_REF)
+		add(Draft7, KEY_WRITE_ONLY)
+		add(Hybrid, keywords2019...)
+	})
+	return keywordsOfDrafts[draft]
+}
+
 func (dc draftConfigs) GetMetaSchema(url string) string {
 	for _, config := range dc {
 		if config.MetaSchemaURL == url {
@@ -123,3 +172,21 @@ func parseSchemaURL(documentNode interface{}) (string, *Draft, error) {
 
 	return "", nil, nil
 }
+
+// ValidateAgainstMetaSchema validates a schema against the meta-schema of the given draft, regardless of the
+// "$schema" it declares. The meta-schemas of the supported drafts are embedded, so nothing is loaded remotely
+func ValidateAgainstMetaSchema(l JSONLoader, draft Draft) (*Result, error) {
+	metaSchemaURL := drafts.GetSchemaURL(draft)
+	if metaSchemaURL == "" {
+		return nil, errors.New(formatErrorDescription(
+			Locale.NoMetaSchema(),
+			ErrorDetails{"draft": int(draft)},
+		))
+	}
+
+	metaSchema, err := NewSchema(NewReferenceLoader(metaSchemaURL))
+	if err != nil {
+		return nil, err
+	}
+	return metaSchema.Validate(l)
+}
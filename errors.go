@@ -2,6 +2,8 @@ package gojsonschema
 
 import (
 	"bytes"
+	"encoding/json"
+	"math/big"
 	"sync"
 	"text/template"
 )
@@ -15,6 +17,46 @@ type errorTemplate struct {
 	sync.RWMutex
 }
 
+// Error types as returned by ResultError.Type()
+const (
+	ErrorTypeFalse                        = "false"
+	ErrorTypeRequired                     = "required"
+	ErrorTypeInvalidType                  = "invalid_type"
+	ErrorTypeNumberAnyOf                  = "number_any_of"
+	ErrorTypeNumberOneOf                  = "number_one_of"
+	ErrorTypeNumberAllOf                  = "number_all_of"
+	ErrorTypeNumberNot                    = "number_not"
+	ErrorTypeMissingDependency            = "missing_dependency"
+	ErrorTypeInternal                     = "internal"
+	ErrorTypeConst                        = "const"
+	ErrorTypeEnum                         = "enum"
+	ErrorTypeArrayNoAdditionalItems       = "array_no_additional_items"
+	ErrorTypeArrayMinItems                = "array_min_items"
+	ErrorTypeArrayMaxItems                = "array_max_items"
+	ErrorTypeUnique                       = "unique"
+	ErrorTypeContains                     = "contains"
+	ErrorTypeArrayMinProperties           = "array_min_properties"
+	ErrorTypeArrayMaxProperties           = "array_max_properties"
+	ErrorTypeAdditionalPropertyNotAllowed = "additional_property_not_allowed"
+	ErrorTypeInvalidPropertyPattern       = "invalid_property_pattern"
+	ErrorTypeInvalidPropertyName          = "invalid_property_name"
+	ErrorTypeStringGTE                    = "string_gte"
+	ErrorTypeStringLTE                    = "string_lte"
+	ErrorTypePattern                      = "pattern"
+	ErrorTypeFormat                       = "format"
+	ErrorTypeMultipleOf                   = "multiple_of"
+	ErrorTypeNumberGTE                    = "number_gte"
+	ErrorTypeNumberGT                     = "number_gt"
+	ErrorTypeNumberLTE                    = "number_lte"
+	ErrorTypeNumberLT                     = "number_lt"
+	ErrorTypeConditionThen                = "condition_then"
+	ErrorTypeConditionElse                = "condition_else"
+	ErrorTypePropertyOrder                = "property_order"
+	ErrorTypeDiscriminator                = "discriminator"
+	ErrorTypeExtensionPanic               = "extension_panic"
+	ErrorTypeInvalidJSONString            = "invalid_json_string"
+)
+
 type (
 
 	// FalseError. ErrorDetails: -
@@ -47,13 +89,13 @@ type (
 	}
 
 	// NumberAllOfError is produced in case of a failing "allOf" validation
-	// ErrorDetails: -
+	// ErrorDetails: index (of the first subschema that failed)
 	NumberAllOfError struct {
 		ResultErrorFields
 	}
 
 	// NumberNotError is produced if a "not" validation failed
-	// ErrorDetails: -
+	// ErrorDetails: schema
 	NumberNotError struct {
 		ResultErrorFields
 	}
@@ -77,7 +119,7 @@ type (
 	}
 
 	// EnumError indicates an enum error
-	// ErrorDetails: allowed
+	// ErrorDetails: allowed, values, suggestion (for strings close to an allowed value)
 	EnumError struct {
 		ResultErrorFields
 	}
@@ -95,7 +137,7 @@ type (
 	}
 
 	// ArrayMaxItemsError is produced if an array contains more items than the allowed maximum
-	// ErrorDetails: max
+	// ErrorDetails: max, from and to (the first and the last index of the items beyond the maximum)
 	ArrayMaxItemsError struct {
 		ResultErrorFields
 	}
@@ -113,13 +155,13 @@ type (
 	}
 
 	// ArrayMinPropertiesError is produced if an object contains less properties than the allowed minimum
-	// ErrorDetails: min
+	// ErrorDetails: min, count
 	ArrayMinPropertiesError struct {
 		ResultErrorFields
 	}
 
 	// ArrayMaxPropertiesError is produced if an object contains more properties than the allowed maximum
-	// ErrorDetails: max
+	// ErrorDetails: max, count
 	ArrayMaxPropertiesError struct {
 		ResultErrorFields
 	}
@@ -207,6 +249,30 @@ type (
 	ConditionElseError struct {
 		ResultErrorFields
 	}
+
+	// PropertyOrderError is produced if properties don't appear in the order given by "propertyOrder"
+	// ErrorDetails: property, before
+	PropertyOrderError struct {
+		ResultErrorFields
+	}
+
+	// DiscriminatorError is produced if the value of an OpenAPI discriminator property doesn't select any schema
+	// ErrorDetails: property, value, allowed
+	DiscriminatorError struct {
+		ResultErrorFields
+	}
+
+	// ExtensionPanicError is produced if a custom format checker or keyword panics during validation
+	// ErrorDetails: extension, panic
+	ExtensionPanicError struct {
+		ResultErrorFields
+	}
+
+	// InvalidJSONStringError is produced by ParseJSONKeyword if a string does not hold valid JSON
+	// ErrorDetails: error
+	InvalidJSONStringError struct {
+		ResultErrorFields
+	}
 )
 
 // newError takes a ResultError type and sets the type, context, description, details, value, and field
@@ -215,101 +281,113 @@ func newError(err ResultError, context *JsonContext, value interface{}, locale l
 	var d string
 	switch err.(type) {
 	case *FalseError:
-		t = "false"
+		t = ErrorTypeFalse
 		d = locale.False()
 	case *RequiredError:
-		t = "required"
+		t = ErrorTypeRequired
 		d = locale.Required()
 	case *InvalidTypeError:
-		t = "invalid_type"
+		t = ErrorTypeInvalidType
 		d = locale.InvalidType()
 	case *NumberAnyOfError:
-		t = "number_any_of"
+		t = ErrorTypeNumberAnyOf
 		d = locale.NumberAnyOf()
 	case *NumberOneOfError:
-		t = "number_one_of"
+		t = ErrorTypeNumberOneOf
 		d = locale.NumberOneOf()
 	case *NumberAllOfError:
-		t = "number_all_of"
+		t = ErrorTypeNumberAllOf
 		d = locale.NumberAllOf()
 	case *NumberNotError:
-		t = "number_not"
+		t = ErrorTypeNumberNot
 		d = locale.NumberNot()
 	case *MissingDependencyError:
-		t = "missing_dependency"
+		t = ErrorTypeMissingDependency
 		d = locale.MissingDependency()
 	case *InternalError:
-		t = "internal"
+		t = ErrorTypeInternal
 		d = locale.Internal()
 	case *ConstError:
-		t = "const"
+		t = ErrorTypeConst
 		d = locale.Const()
 	case *EnumError:
-		t = "enum"
+		t = ErrorTypeEnum
 		d = locale.Enum()
 	case *ArrayNoAdditionalItemsError:
-		t = "array_no_additional_items"
+		t = ErrorTypeArrayNoAdditionalItems
 		d = locale.ArrayNoAdditionalItems()
 	case *ArrayMinItemsError:
-		t = "array_min_items"
+		t = ErrorTypeArrayMinItems
 		d = locale.ArrayMinItems()
 	case *ArrayMaxItemsError:
-		t = "array_max_items"
+		t = ErrorTypeArrayMaxItems
 		d = locale.ArrayMaxItems()
 	case *ItemsMustBeUniqueError:
-		t = "unique"
+		t = ErrorTypeUnique
 		d = locale.Unique()
 	case *ArrayContainsError:
-		t = "contains"
+		t = ErrorTypeContains
 		d = locale.ArrayContains()
 	case *ArrayMinPropertiesError:
-		t = "array_min_properties"
+		t = ErrorTypeArrayMinProperties
 		d = locale.ArrayMinProperties()
 	case *ArrayMaxPropertiesError:
-		t = "array_max_properties"
+		t = ErrorTypeArrayMaxProperties
 		d = locale.ArrayMaxProperties()
 	case *AdditionalPropertyNotAllowedError:
-		t = "additional_property_not_allowed"
+		t = ErrorTypeAdditionalPropertyNotAllowed
 		d = locale.AdditionalPropertyNotAllowed()
 	case *InvalidPropertyPatternError:
-		t = "invalid_property_pattern"
+		t = ErrorTypeInvalidPropertyPattern
 		d = locale.InvalidPropertyPattern()
 	case *InvalidPropertyNameError:
-		t = "invalid_property_name"
+		t = ErrorTypeInvalidPropertyName
 		d = locale.InvalidPropertyName()
 	case *StringLengthGTEError:
-		t = "string_gte"
+		t = ErrorTypeStringGTE
 		d = locale.StringGTE()
 	case *StringLengthLTEError:
-		t = "string_lte"
+		t = ErrorTypeStringLTE
 		d = locale.StringLTE()
 	case *DoesNotMatchPatternError:
-		t = "pattern"
+		t = ErrorTypePattern
 		d = locale.DoesNotMatchPattern()
 	case *DoesNotMatchFormatError:
-		t = "format"
+		t = ErrorTypeFormat
 		d = locale.DoesNotMatchFormat()
 	case *MultipleOfError:
-		t = "multiple_of"
+		t = ErrorTypeMultipleOf
 		d = locale.MultipleOf()
 	case *NumberGTEError:
-		t = "number_gte"
+		t = ErrorTypeNumberGTE
 		d = locale.NumberGTE()
 	case *NumberGTError:
-		t = "number_gt"
+		t = ErrorTypeNumberGT
 		d = locale.NumberGT()
 	case *NumberLTEError:
-		t = "number_lte"
+		t = ErrorTypeNumberLTE
 		d = locale.NumberLTE()
 	case *NumberLTError:
-		t = "number_lt"
+		t = ErrorTypeNumberLT
 		d = locale.NumberLT()
 	case *ConditionThenError:
-		t = "condition_then"
+		t = ErrorTypeConditionThen
 		d = locale.ConditionThen()
 	case *ConditionElseError:
-		t = "condition_else"
+		t = ErrorTypeConditionElse
 		d = locale.ConditionElse()
+	case *PropertyOrderError:
+		t = ErrorTypePropertyOrder
+		d = locale.PropertyOrder()
+	case *DiscriminatorError:
+		t = ErrorTypeDiscriminator
+		d = locale.Discriminator()
+	case *ExtensionPanicError:
+		t = ErrorTypeExtensionPanic
+		d = locale.ExtensionPanic()
+	case *InvalidJSONStringError:
+		t = ErrorTypeInvalidJSONString
+		d = locale.InvalidJSONString()
 	}
 
 	err.SetType(t)
@@ -323,7 +401,36 @@ func newError(err ResultError, context *JsonContext, value interface{}, locale l
 		details["context"] = context.String()
 	}
 
-	err.SetDescription(formatErrorDescription(err.DescriptionFormat(), details))
+	err.SetDescription(formatErrorDescription(err.DescriptionFormat(), localizeNumbers(locale, details)))
+}
+
+// localizeNumbers returns a copy of the details with the numbers formatted by the locale, if it is a NumberFormatter
+func localizeNumbers(l locale, details ErrorDetails) ErrorDetails {
+	formatter, ok := l.(NumberFormatter)
+	if !ok {
+		return details
+	}
+
+	localized := make(ErrorDetails, len(details))
+	for key, value := range details {
+		var number *big.Float
+		switch value := value.(type) {
+		case int:
+			number = new(big.Float).SetInt64(int64(value))
+		case float64:
+			number = big.NewFloat(value)
+		case *big.Float:
+			number = value
+		case json.Number:
+			number, _ = new(big.Float).SetString(string(value))
+		}
+		if number != nil {
+			localized[key] = formatter.FormatNumber(number)
+		} else {
+			localized[key] = value
+		}
+	}
+	return localized
 }
 
 // formatErrorDescription takes a string in the default text/template
@@ -0,0 +1,146 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// evaluatingKeywords are the keywords that are evaluated against a value when present, apart from custom keywords.
+// "then" and "else" are left out, only the keywords of the one that is applied are evaluated
+var evaluatingKeywords = map[string]bool{
+	KEY_REF: true, KEY_TYPE: true, KEY_CONST: true, KEY_ENUM: true, KEY_FORMAT: true,
+	KEY_MULTIPLE_OF: true, KEY_MINIMUM: true, KEY_MAXIMUM: true, KEY_EXCLUSIVE_MINIMUM: true, KEY_EXCLUSIVE_MAXIMUM: true,
+	KEY_MIN_LENGTH: true, KEY_MAX_LENGTH: true, KEY_PATTERN: true,
+	KEY_ITEMS: true, KEY_ADDITIONAL_ITEMS: true, KEY_MIN_ITEMS: true, KEY_MAX_ITEMS: true, KEY_UNIQUE_ITEMS: true,
+	KEY_CONTAINS: true, KEY_UNEVALUATED_ITEMS: true,
+	KEY_PROPERTIES: true, KEY_PATTERN_PROPERTIES: true, KEY_ADDITIONAL_PROPERTIES: true, KEY_PROPERTY_NAMES: true,
+	KEY_REQUIRED: true, KEY_MIN_PROPERTIES: true, KEY_MAX_PROPERTIES: true, KEY_DEPENDENCIES: true,
+	KEY_ALL_OF: true, KEY_ANY_OF: true, KEY_ONE_OF: true, KEY_NOT: true, KEY_IF: true,
+}
+
+// traceKeywords records the keywords of the subSchema that are evaluated against the value at context,
+// see SchemaLoader.TraceEvaluatedKeywords
+func (s *validationState) traceKeywords(currentSubSchema *subSchema, value interface{}, context *JsonContext) {
+	m, ok := currentSubSchema.documentNode.(map[string]interface{})
+	if !ok {
+		return
+	}
+	location := currentSubSchema.keywordLocation()
+	instanceType := s.instanceType(value)
+	// A value of the wrong type is only checked against "type"
+	typeOnly := currentSubSchema.types.IsTyped() && !currentSubSchema.types.Contains(instanceType) &&
+		!(instanceType == TYPE_INTEGER && currentSubSchema.types.Contains(TYPE_NUMBER))
+
+	pointer := context.Pointer()
+	for _, keyword := range sortedKeys(m) {
+		var applies bool
+		switch {
+		case currentSubSchema.refSchema != nil || currentSubSchema.unresolvedRef:
+			// The other keywords next to "$ref" are ignored
+			applies = keyword == KEY_REF
+		case typeOnly:
+			applies = keyword == KEY_TYPE
+		default:
+			applies = s.keywordApplies(currentSubSchema, canonicalKeyword(keyword), instanceType)
+		}
+		keywordLocation := location + "/" + jsonPointerEscaper.Replace(keyword)
+		if applies && !isStringInSlice(s.evaluatedKeywords[pointer], keywordLocation) {
+			s.evaluatedKeywords[pointer] = append(s.evaluatedKeywords[pointer], keywordLocation)
+		}
+	}
+}
+
+// keywordApplies reports whether keyword is evaluated against values of the given type
+func (s *validationState) keywordApplies(currentSubSchema *subSchema, keyword string, instanceType string) bool {
+	switch keyword {
+	case KEY_FORMAT:
+		return instanceType == TYPE_STRING || instanceType == TYPE_NUMBER || instanceType == TYPE_INTEGER
+	case KEY_PROPERTY_ORDER:
+		return instanceType == TYPE_OBJECT && s.schema.enforcePropertyOrder
+	case KEY_DISCRIMINATOR:
+		return instanceType == TYPE_OBJECT && currentSubSchema.discriminator != nil
+	}
+	for _, use := range currentSubSchema.customKeywords {
+		if use.name == keyword {
+			return true
+		}
+	}
+	if !evaluatingKeywords[keyword] {
+		return false
+	}
+	for _, tk := range typeKeywords {
+		if tk.keyword == keyword {
+			return tk.typ == instanceType || (tk.typ == TYPE_NUMBER && instanceType == TYPE_INTEGER)
+		}
+	}
+	return true
+}
+
+// instanceType returns the JSON type of a value, where numbers without a fraction are integers
+func (s *validationState) instanceType(value interface{}) string {
+	if value == nil {
+		return TYPE_NULL
+	}
+	if number, ok := value.(json.Number); ok {
+		if checkJSONInteger(number) && (!s.schema.strictIntegers || isIntegerLiteral(number)) {
+			return TYPE_INTEGER
+		}
+		return TYPE_NUMBER
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice:
+		return TYPE_ARRAY
+	case reflect.Map:
+		return TYPE_OBJECT
+	case reflect.String:
+		return TYPE_STRING
+	case reflect.Bool:
+		return TYPE_BOOLEAN
+	}
+	return TYPE_NUMBER
+}
+
+// canonicalKeyword returns the keyword an alias registered with RegisterKeywordAlias stands for
+func canonicalKeyword(keyword string) string {
+	keywordLock.RLock()
+	defer keywordLock.RUnlock()
+	if canonical, ok := keywordAliases[keyword]; ok {
+		return canonical
+	}
+	return keyword
+}
+
+// keywordLocation returns the path to the subSchema from the root schema as a JSON Pointer, i.e. "/properties/age".
+// Like the keywordLocation of the standard output format, it runs through the "$ref" keywords that led to the subSchema
+func (s *subSchema) keywordLocation() string {
+	var segments []string
+	for current := s; current.parent != nil; current = current.parent {
+		location := current.relativeLocation()
+		for i := len(location) - 1; i >= 0; i-- {
+			segments = append(segments, jsonPointerEscaper.Replace(fmt.Sprint(location[i])))
+		}
+	}
+	for i, j := 0, len(segments)-1; i < j; i, j = i+1, j-1 {
+		segments[i], segments[j] = segments[j], segments[i]
+	}
+	if len(segments) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Fingerprint returns a hash of the schema that only depends on its contents, for use as a cache key or to detect
+// changes. The order of keys, insignificant whitespace and the notation of numbers don't affect it. Documents that the
+// schema refers to with "$ref" are part of the fingerprint, as well as the draft the schema was compiled as
+func (d *Schema) Fingerprint() string {
+	h := sha256.New()
+	h.Write([]byte(strconv.Itoa(int(*d.rootSchema.draft))))
+	h.Write([]byte{0})
+	// The documents were parsed from JSON, so they can always be marshaled again
+	root, _ := marshalCanonical(d.rootSchema.documentNode)
+	h.Write(root)
+
+	// References within the schema document itself are covered by the document
+	rootDocuments := []string{registryKey(d.documentReference)}
+	if d.rootSchema.id != nil {
+		rootDocuments = append(rootDocuments, registryKey(*d.rootSchema.id))
+	}
+	referenced := make(map[string]interface{})
+	collectReferencedSchemas(d.rootSchema, rootDocuments, map[*subSchema]bool{}, referenced)
+	for _, ref := range sortedKeys(referenced) {
+		document, _ := marshalCanonical(referenced[ref])
+		h.Write([]byte{0})
+		h.Write([]byte(ref))
+		h.Write([]byte{0})
+		h.Write(document)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// collectReferencedSchemas collects the schemas that "$ref" refers to in other documents than rootDocuments,
+// keyed by the reference
+func collectReferencedSchemas(s *subSchema, rootDocuments []string, visited map[*subSchema]bool, referenced map[string]interface{}) {
+	if s == nil || visited[s] {
+		return
+	}
+	visited[s] = true
+
+	if s.refSchema != nil {
+		if !isStringInSlice(rootDocuments, registryKey(*s.ref)) {
+			referenced[s.ref.String()] = s.refSchema.documentNode
+		}
+		collectReferencedSchemas(s.refSchema, rootDocuments, visited, referenced)
+	}
+
+	children := append([]*subSchema{s.propertyNames, s.contains, s.unevaluatedItems, s.not, s._if, s._then, s._else},
+		s.propertiesChildren...)
+	children = append(children, s.itemsChildren...)
+	for _, group := range [][]*subSchema{s.allOf, s.anyOf, s.oneOf} {
+		children = append(children, group...)
+	}
+	for _, c := range s.patternProperties {
+		children = append(children, c)
+	}
+	for _, c := range s.dependencies {
+		if c, ok := c.(*subSchema); ok {
+			children = append(children, c)
+		}
+	}
+	for _, c := range []interface{}{s.additionalProperties, s.additionalItems} {
+		if c, ok := c.(*subSchema); ok {
+			children = append(children, c)
+		}
+	}
+	for _, c := range children {
+		collectReferencedSchemas(c, rootDocuments, visited, referenced)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 )
 
 type (
@@ -17,6 +18,24 @@ type (
 		IsFormat(input interface{}) bool
 	}
 
+	// ClockFormatChecker can be implemented by formatters that depend on the current time, such as a
+	// check that a date is not in the future. It is used instead of IsFormat during validation, with the
+	// time of the clock set with SchemaLoader.SetClock
+	ClockFormatChecker interface {
+		FormatChecker
+		// IsFormatAt checks if input has the correct format and type at the given time
+		IsFormatAt(input interface{}, now time.Time) bool
+	}
+
+	// NormalizingFormatChecker can be implemented by formatters of formats that have a canonical form, such as
+	// date-times in UTC. When SchemaLoader.NormalizeFormats is set, the canonical form of every value that has
+	// the format is added to the result as an annotation, see NormalizedValue
+	NormalizingFormatChecker interface {
+		FormatChecker
+		// Normalize returns the canonical form of input, which has the correct format, or false if it has none
+		Normalize(input interface{}) (interface{}, bool)
+	}
+
 	// FormatCheckerChain holds the formatters
 	FormatCheckerChain struct {
 		formatters map[string]FormatChecker
@@ -25,6 +44,9 @@ type (
 	// EmailFormatChecker verifies email address formats
 	EmailFormatChecker struct{}
 
+	// IDNEmailFormatChecker verifies internationalized email address formats per RFC6531
+	IDNEmailFormatChecker struct{}
+
 	// IPV4FormatChecker verifies IP addresses in the IPv4 format
 	IPV4FormatChecker struct{}
 
@@ -91,6 +113,9 @@ type (
 	// HostnameFormatChecker validates a hostname is in the correct format
 	HostnameFormatChecker struct{}
 
+	// IDNHostnameFormatChecker validates an internationalized hostname per RFC5890
+	IDNHostnameFormatChecker struct{}
+
 	// UUIDFormatChecker validates a UUID is in the correct format
 	UUIDFormatChecker struct{}
 
@@ -113,8 +138,9 @@ var (
 			"time":                  TimeFormatChecker{},
 			"date-time":             DateTimeFormatChecker{},
 			"hostname":              HostnameFormatChecker{},
+			"idn-hostname":          IDNHostnameFormatChecker{},
 			"email":                 EmailFormatChecker{},
-			"idn-email":             EmailFormatChecker{},
+			"idn-email":             IDNEmailFormatChecker{},
 			"ipv4":                  IPV4FormatChecker{},
 			"ipv6":                  IPV6FormatChecker{},
 			"uri":                   URIFormatChecker{},
@@ -135,6 +161,9 @@ var (
 	// Use a regex to make sure curly brackets are balanced properly after validating it as a AURI
 	rxURITemplate = regexp.MustCompile("^([^{]*({[^}]*})?)*$")
 
+	// Besides the full stop, IDNA recognizes the ideographic, fullwidth and halfwidth ideographic full stops as label separators
+	idnLabelSeparators = strings.NewReplacer("\u3002", ".", "\uff0e", ".", "\uff61", ".")
+
 	rxUUID = regexp.MustCompile("^[a-f0-9]{8}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{4}-[a-f0-9]{12}$")
 
 	rxJSONPointer = regexp.MustCompile("^(?:/(?:[^~/]|~0|~1)*)*$")
@@ -187,6 +216,33 @@ func (c *FormatCheckerChain) IsFormat(name string, input interface{}) bool {
 	return f.IsFormat(input)
 }
 
+// isFormatAt is IsFormat for a validation, formatters that implement ClockFormatChecker get the current time of now
+func (c *FormatCheckerChain) isFormatAt(name string, input interface{}, now func() time.Time) bool {
+	lock.RLock()
+	f, ok := c.formatters[name]
+	lock.RUnlock()
+
+	if !ok {
+		return true
+	}
+	if clockChecker, ok := f.(ClockFormatChecker); ok {
+		return clockChecker.IsFormatAt(input, now())
+	}
+	return f.IsFormat(input)
+}
+
+// normalize returns the canonical form of input if the formatter with the given name implements NormalizingFormatChecker
+func (c *FormatCheckerChain) normalize(name string, input interface{}) (interface{}, bool) {
+	lock.RLock()
+	f := c.formatters[name]
+	lock.RUnlock()
+
+	if normalizer, ok := f.(NormalizingFormatChecker); ok {
+		return normalizer.Normalize(input)
+	}
+	return nil, false
+}
+
 // IsFormat checks if input is a correctly formatted e-mail address
 func (f EmailFormatChecker) IsFormat(input interface{}) bool {
 	asString, ok := input.(string)
@@ -198,6 +254,28 @@ func (f EmailFormatChecker) IsFormat(input interface{}) bool {
 	return err == nil
 }
 
+// IsFormat checks if input is a correctly formatted internationalized e-mail address
+func (f IDNEmailFormatChecker) IsFormat(input interface{}) bool {
+	asString, ok := input.(string)
+	if !ok {
+		return false
+	}
+
+	address, err := mail.ParseAddress(asString)
+	if err != nil {
+		return false
+	}
+
+	domain := address.Address[strings.LastIndex(address.Address, "@")+1:]
+
+	// Domain literals like [127.0.0.1] are already checked by the mail parser
+	if strings.HasPrefix(domain, "[") {
+		return true
+	}
+
+	return IDNHostnameFormatChecker{}.IsFormat(domain)
+}
+
 // IsFormat checks if input is a correctly formatted IPv4-address
 func (f IPV4FormatChecker) IsFormat(input interface{}) bool {
 	asString, ok := input.(string)
@@ -222,6 +300,20 @@ func (f IPV6FormatChecker) IsFormat(input interface{}) bool {
 	return ip != nil && strings.Contains(asString, ":")
 }
 
+// Normalize returns a date-time with a date and a time in UTC, i.e. 2020-01-02T03:04:05.6Z.
+// Values with only a date or only a time are not normalized
+func (f DateTimeFormatChecker) Normalize(input interface{}) (interface{}, bool) {
+	asString, ok := input.(string)
+	if !ok {
+		return nil, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, asString)
+	if err != nil {
+		return nil, false
+	}
+	return t.UTC().Format(time.RFC3339Nano), true
+}
+
 // IsFormat checks if input is a correctly formatted  date/time per RFC3339 5.6
 func (f DateTimeFormatChecker) IsFormat(input interface{}) bool {
 	asString, ok := input.(string)
@@ -229,6 +321,12 @@ func (f DateTimeFormatChecker) IsFormat(input interface{}) bool {
 		return false
 	}
 
+	// RFC3339 only allows a period as the fraction separator, while
+	// time.Parse also accepts a comma
+	if strings.Contains(asString, ",") {
+		return false
+	}
+
 	formats := []string{
 		"15:04:05",
 		"15:04:05Z07:00",
@@ -263,6 +361,12 @@ func (f TimeFormatChecker) IsFormat(input interface{}) bool {
 		return false
 	}
 
+	// RFC3339 only allows a period as the fraction separator, while
+	// time.Parse also accepts a comma
+	if strings.Contains(asString, ",") {
+		return false
+	}
+
 	if _, err := time.Parse("15:04:05Z07:00", asString); err == nil {
 		return true
 	}
@@ -323,6 +427,74 @@ func (f HostnameFormatChecker) IsFormat(input interface{}) bool {
 	return rxHostname.MatchString(asString) && len(asString) < 256
 }
 
+// IsFormat checks if input is a correctly formatted internationalized hostname
+func (f IDNHostnameFormatChecker) IsFormat(input interface{}) bool {
+	asString, ok := input.(string)
+	if !ok {
+		return false
+	}
+
+	// The length limits apply to the hostname converted to ASCII
+	asciiLength := -1
+	for _, label := range strings.Split(idnLabelSeparators.Replace(asString), ".") {
+		asciiLabel, ok := idnLabelToASCII(label)
+		if !ok {
+			return false
+		}
+		asciiLength += len(asciiLabel) + 1
+	}
+
+	return asciiLength < 256
+}
+
+// idnLabelToASCII converts a label to its ASCII form and reports whether it is valid.
+// Only the most common rules of RFC5891 are checked, the full IDNA2008 tables are not included
+func idnLabelToASCII(label string) (string, bool) {
+	if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+		return "", false
+	}
+
+	runes := []rune(label)
+	isASCII := true
+
+	for i, r := range runes {
+		switch {
+		case r < 0x80:
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return "", false
+			}
+			continue
+		case i == 0 && unicode.Is(unicode.M, r):
+			// A label can't start with a combining mark
+			return "", false
+		case r == '\u302e' || r == '\u302f':
+			// The Hangul tone marks are disallowed
+			return "", false
+		case r == '\u00b7':
+			// The middle dot is only allowed between two l's, as used in Catalan
+			if i == 0 || i == len(runes)-1 || runes[i-1] != 'l' || runes[i+1] != 'l' {
+				return "", false
+			}
+		case r == '\u30fb':
+			// The katakana middle dot is only allowed in labels with Japanese characters
+			if !strings.ContainsFunc(label, func(r rune) bool {
+				return unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han) && r != '\u30fb'
+			}) {
+				return "", false
+			}
+		case !unicode.In(r, unicode.L, unicode.M, unicode.Nd):
+			return "", false
+		}
+		isASCII = false
+	}
+
+	if !isASCII {
+		label = "xn--" + punycodeEncode(label)
+	}
+
+	return label, len(label) <= 63
+}
+
 // IsFormat checks if input is a correctly formatted UUID
 func (f UUIDFormatChecker) IsFormat(input interface{}) bool {
 	asString, ok := input.(string)
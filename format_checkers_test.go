@@ -2,6 +2,8 @@ package gojsonschema
 
 import (
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
 )
 
@@ -21,3 +23,78 @@ func TestURIReferenceFormatCheckerIsFormat(t *testing.T) {
 	assert.True(t, checker.IsFormat("relative"))
 	assert.True(t, checker.IsFormat("https://dummyhost.com/dummy-path?dummy-qp-name=dummy-qp-value"))
 }
+
+func TestPunycodeEncode(t *testing.T) {
+	assert.Equal(t, "bcher-kva", punycodeEncode("bücher"))
+	assert.Equal(t, "mnchen-3ya", punycodeEncode("münchen"))
+	assert.Equal(t, "9n2bp8q", punycodeEncode("실례"))
+	assert.Equal(t, "9t4b11yi5a", punycodeEncode("테스트"))
+}
+
+func TestIDNHostnameFormatCheckerIsFormat(t *testing.T) {
+	checker := IDNHostnameFormatChecker{}
+
+	assert.True(t, checker.IsFormat("example.com"))
+	assert.True(t, checker.IsFormat("bücher.example"))
+	assert.True(t, checker.IsFormat("실례.테스트"))
+	assert.True(t, checker.IsFormat("例え。テスト"))
+	assert.True(t, checker.IsFormat("l·l.example"))
+
+	assert.False(t, checker.IsFormat(""))
+	assert.False(t, checker.IsFormat("example..com"))
+	assert.False(t, checker.IsFormat("-bücher.example"))
+	assert.False(t, checker.IsFormat("bücher_.example"))
+	assert.False(t, checker.IsFormat("́bücher.example"))
+	assert.False(t, checker.IsFormat("실〮례.테스트"))
+	assert.False(t, checker.IsFormat("a·b.example"))
+	assert.False(t, checker.IsFormat("ab・cd.example"))
+	// 60 characters, but longer than 63 once encoded
+	assert.False(t, checker.IsFormat(strings.Repeat("ü", 60)+".example"))
+}
+
+func TestIDNEmailFormatCheckerIsFormat(t *testing.T) {
+	checker := IDNEmailFormatChecker{}
+
+	assert.True(t, checker.IsFormat("john@example.com"))
+	assert.True(t, checker.IsFormat("실례@실례.테스트"))
+	assert.True(t, checker.IsFormat("jürgen@bücher.example"))
+
+	assert.False(t, checker.IsFormat("2962"))
+	assert.False(t, checker.IsFormat("jürgen@"))
+	assert.False(t, checker.IsFormat("jürgen@bücher_.example"))
+}
+
+func TestDateTimeFormatCheckerNormalize(t *testing.T) {
+	checker := DateTimeFormatChecker{}
+
+	normalized, ok := checker.Normalize("2020-01-02T05:04:05.50+02:00")
+	assert.True(t, ok)
+	assert.Equal(t, "2020-01-02T03:04:05.5Z", normalized)
+
+	_, ok = checker.Normalize("2020-01-02")
+	assert.False(t, ok)
+
+	sl := NewSchemaLoader()
+	sl.NormalizeFormats = true
+	schema, err := sl.Compile(NewStringLoader(`{"properties" : {"created" : {"format" : "date-time"}, "day" : {"format" : "date-time"}}}`))
+	require.Nil(t, err)
+	result, err := schema.Validate(NewStringLoader(`{"created" : "2020-01-02T05:04:05.50+02:00", "day" : "2020-01-02"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+	if assert.Len(t, result.Annotations(), 1) {
+		assert.Equal(t, "(root).created", result.Annotations()[0].Context.String())
+		assert.Equal(t, KEY_FORMAT, result.Annotations()[0].Keyword)
+		assert.Equal(t, NormalizedValue{Format: "date-time", Value: "2020-01-02T03:04:05.5Z"}, result.Annotations()[0].Value)
+	}
+
+	result, err = schema.Validate(NewStringLoader(`{"created" : "yesterday"}`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+	assert.Empty(t, result.Annotations())
+
+	schema, err = NewSchema(NewStringLoader(`{"format" : "date-time"}`))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`"2020-01-02T05:04:05+02:00"`))
+	require.Nil(t, err)
+	assert.Empty(t, result.Annotations())
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/xeipuuv/gojsonreference"
+)
+
+// instanceRefResolver replaces the references within a document by the values they point to,
+// see SchemaLoader.ResolveInstanceRefs
+type instanceRefResolver struct {
+	root interface{}
+	// The source of the document, which the resolved copies of its objects take their order from
+	source *schemaSource
+	locale locale
+	// References that are being resolved, to detect cycles
+	resolving map[string]bool
+	// References that were resolved, so values referenced more than once are resolved only once
+	resolved map[string]interface{}
+}
+
+// resolveInstanceRefs returns a copy of the document in which every object holding a "$ref" to a JSON pointer
+// within the document is replaced by the value it points to. The document itself is not modified
+func resolveInstanceRefs(root interface{}, source *schemaSource, l locale) (interface{}, error) {
+	r := &instanceRefResolver{
+		root:      root,
+		source:    source,
+		locale:    l,
+		resolving: make(map[string]bool),
+		resolved:  make(map[string]interface{}),
+	}
+	return r.resolve(root)
+}
+
+func (r *instanceRefResolver) resolve(node interface{}) (interface{}, error) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := v[KEY_REF].(string); ok && strings.HasPrefix(ref, "#") {
+			return r.resolveRef(ref)
+		}
+		resolved := make(map[string]interface{}, len(v))
+		// Keys are resolved in order, so the same cycle is always reported
+		for _, key := range sortedKeys(v) {
+			var err error
+			if resolved[key], err = r.resolve(v[key]); err != nil {
+				return nil, err
+			}
+		}
+		r.source.copyOrder(v, resolved)
+		return resolved, nil
+
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, value := range v {
+			var err error
+			if resolved[i], err = r.resolve(value); err != nil {
+				return nil, err
+			}
+		}
+		return resolved, nil
+	}
+
+	return node, nil
+}
+
+func (r *instanceRefResolver) resolveRef(ref string) (interface{}, error) {
+	if value, ok := r.resolved[ref]; ok {
+		return value, nil
+	}
+	if r.resolving[ref] {
+		return nil, errors.New(formatErrorDescription(r.locale.CircularInstanceReference(), ErrorDetails{"reference": ref}))
+	}
+	r.resolving[ref] = true
+	defer delete(r.resolving, ref)
+
+	reference, err := gojsonreference.NewJsonReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	target, _, err := reference.GetPointer().Get(r.root)
+	if err != nil {
+		return nil, errors.New(formatErrorDescription(r.locale.UnresolvedReference(), ErrorDetails{"reference": ref}))
+	}
+
+	value, err := r.resolve(target)
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[ref] = value
+	return value, nil
+}
@@ -24,17 +24,90 @@
 
 package gojsonschema
 
-import "bytes"
+import (
+	"bytes"
+	"strconv"
+	"sync"
+)
 
 // JsonContext implements a persistent linked-list of strings
 type JsonContext struct {
-	head string
-	tail *JsonContext
+	head  string
+	tail  *JsonContext
+	depth int
+	// Whether head is the index of an array item rather than an object key
+	isIndex bool
+	// Whether the context is referenced beyond the validation of its node, i.e. by an error, see retain
+	retained bool
 }
 
 // NewJsonContext creates a new JsonContext
 func NewJsonContext(head string, tail *JsonContext) *JsonContext {
-	return &JsonContext{head, tail}
+	depth := 0
+	if tail != nil {
+		depth = tail.depth + 1
+	}
+	return &JsonContext{head: head, tail: tail, depth: depth}
+}
+
+// newJsonContextIndex creates a new JsonContext for an array item
+func newJsonContextIndex(index int, tail *JsonContext) *JsonContext {
+	c := NewJsonContext(strconv.Itoa(index), tail)
+	c.isIndex = true
+	return c
+}
+
+// jsonContextPool holds the contexts that were released during validation, so every node of a document
+// doesn't need a new allocation
+var jsonContextPool = sync.Pool{New: func() interface{} { return new(JsonContext) }}
+
+// acquireJsonContext is NewJsonContext with a context from the pool, it must be released with releaseJsonContext
+// once the node has been validated
+func acquireJsonContext(head string, tail *JsonContext) *JsonContext {
+	c := jsonContextPool.Get().(*JsonContext)
+	c.head = head
+	c.tail = tail
+	if tail != nil {
+		c.depth = tail.depth + 1
+	}
+	return c
+}
+
+// acquireJsonContextIndex is newJsonContextIndex with a context from the pool, see acquireJsonContext
+func acquireJsonContextIndex(index int, tail *JsonContext) *JsonContext {
+	c := acquireJsonContext(strconv.Itoa(index), tail)
+	c.isIndex = true
+	return c
+}
+
+// releaseJsonContext returns a context to the pool, unless it was retained
+func releaseJsonContext(c *JsonContext) {
+	if c.retained {
+		return
+	}
+	*c = JsonContext{}
+	jsonContextPool.Put(c)
+}
+
+// retain marks the context and the contexts it extends as referenced beyond the validation of their node,
+// so they are never released to the pool. This must be done whenever a context is stored, i.e. in an error
+func (c *JsonContext) retain() {
+	for ; c != nil && !c.retained; c = c.tail {
+		c.retained = true
+	}
+}
+
+// Path returns the context as segments relative to the root of the document,
+// a string for every object key and an int for every array index
+func (c *JsonContext) Path() []interface{} {
+	if c == nil || c.tail == nil {
+		return []interface{}{}
+	}
+	var segment interface{} = c.head
+	if c.isIndex {
+		segment, _ = strconv.Atoi(c.head)
+	}
+	return append(c.tail.Path(), segment)
 }
 
 // String displays the context in reverse.
@@ -48,6 +121,15 @@ func (c *JsonContext) String(del ...string) string {
 	return buf.String()
 }
 
+// Pointer returns the context as a JSON Pointer relative to the root of the document,
+// i.e. "/a~1b/0" for the first item of the property "a/b". The root itself is the empty string
+func (c *JsonContext) Pointer() string {
+	if c == nil || c.tail == nil {
+		return ""
+	}
+	return c.tail.Pointer() + "/" + jsonPointerEscaper.Replace(c.head)
+}
+
 func (c *JsonContext) stringLen() int {
 	length := 0
 	if c.tail != nil {
@@ -28,16 +28,22 @@ package gojsonschema
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/xeipuuv/gojsonreference"
 )
@@ -64,7 +70,9 @@ type DefaultJSONLoaderFactory struct {
 
 // FileSystemJSONLoaderFactory is a JSON loader factory that uses http.FileSystem
 type FileSystemJSONLoaderFactory struct {
-	fs http.FileSystem
+	fs       http.FileSystem
+	client   *http.Client
+	maxBytes int64
 }
 
 // New creates a new JSON loader for the given source
@@ -78,8 +86,10 @@ func (d DefaultJSONLoaderFactory) New(source string) JSONLoader {
 // New creates a new JSON loader for the given source
 func (f FileSystemJSONLoaderFactory) New(source string) JSONLoader {
 	return &jsonReferenceLoader{
-		fs:     f.fs,
-		source: source,
+		fs:       f.fs,
+		client:   f.client,
+		maxBytes: f.maxBytes,
+		source:   source,
 	}
 }
 
@@ -95,8 +105,10 @@ func (o osFileSystem) Open(name string) (http.File, error) {
 // references are used to load JSONs from files and HTTP
 
 type jsonReferenceLoader struct {
-	fs     http.FileSystem
-	source string
+	fs       http.FileSystem
+	client   *http.Client
+	maxBytes int64 // set by NewSizeLimitedLoader, 0 if there is no limit
+	source   string
 }
 
 func (l *jsonReferenceLoader) JsonSource() interface{} {
@@ -109,7 +121,9 @@ func (l *jsonReferenceLoader) JsonReference() (gojsonreference.JsonReference, er
 
 func (l *jsonReferenceLoader) LoaderFactory() JSONLoaderFactory {
 	return &FileSystemJSONLoaderFactory{
-		fs: l.fs,
+		fs:       l.fs,
+		client:   l.client,
+		maxBytes: l.maxBytes,
 	}
 }
 
@@ -129,7 +143,34 @@ func NewReferenceLoaderFileSystem(source string, fs http.FileSystem) JSONLoader
 	}
 }
 
+// NewReferenceLoaderFS returns a JSON reference loader using the given source and fs.FS, such as a *zip.Reader
+// of a bundle of schemas. Sources are file URLs of the paths in fsys, i.e. file:///schemas/root.json for the
+// member schemas/root.json, so relative "$ref" like "common/types.json" resolve to other members of fsys
+func NewReferenceLoaderFS(source string, fsys fs.FS) JSONLoader {
+	return NewReferenceLoaderFileSystem(source, http.FS(fsys))
+}
+
+// NewReferenceLoaderHTTPClient returns a JSON reference loader using the given source that fetches
+// HTTP documents with the given client. The client is also used for every "$ref" loaded through this loader,
+// so a client with a custom transport can add authentication or use client certificates for all requests.
+func NewReferenceLoaderHTTPClient(source string, client *http.Client) JSONLoader {
+	return &jsonReferenceLoader{
+		fs:     osFS,
+		client: client,
+		source: source,
+	}
+}
+
 func (l *jsonReferenceLoader) LoadJSON() (interface{}, error) {
+	text, err := l.readText(l.maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONUsingNumber(bytes.NewReader(text))
+}
+
+// readText reads the document from the file or HTTP address of the reference, stopping once more than maxBytes are read
+func (l *jsonReferenceLoader) readText(maxBytes int64) ([]byte, error) {
 
 	var err error
 
@@ -141,7 +182,7 @@ func (l *jsonReferenceLoader) LoadJSON() (interface{}, error) {
 	refToURL := reference
 	refToURL.GetUrl().Fragment = ""
 
-	var document interface{}
+	var text []byte
 
 	if reference.HasFileScheme {
 
@@ -159,63 +200,76 @@ func (l *jsonReferenceLoader) LoadJSON() (interface{}, error) {
 			filename = filepath.FromSlash(filename)
 		}
 
-		document, err = l.loadFromFile(filename)
+		text, err = l.loadFromFile(filename, maxBytes)
 		if err != nil {
 			return nil, err
 		}
 
 	} else {
 
-		document, err = l.loadFromHTTP(refToURL.String())
+		text, err = l.loadFromHTTP(refToURL.String(), maxBytes)
 		if err != nil {
 			return nil, err
 		}
 
 	}
 
-	return document, nil
+	return text, nil
 
 }
 
-func (l *jsonReferenceLoader) loadFromHTTP(address string) (interface{}, error) {
+func (l *jsonReferenceLoader) loadFromHTTP(address string, maxBytes int64) ([]byte, error) {
 
 	// returned cached versions for metaschemas for drafts 4, 6 and 7
 	// for performance and allow for easier offline use
 	if metaSchema := drafts.GetMetaSchema(address); metaSchema != "" {
-		return decodeJSONUsingNumber(strings.NewReader(metaSchema))
+		return readAllLimited(strings.NewReader(metaSchema), maxBytes)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	client := l.client
+	if client == nil {
+		client = http.DefaultClient
 	}
 
-	resp, err := http.Get(address)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	// must return HTTP Status 200 OK
 	if resp.StatusCode != http.StatusOK {
 		return nil, errors.New(formatErrorDescription(Locale.HttpBadStatus(), ErrorDetails{"status": resp.Status}))
 	}
 
-	bodyBuff, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	// As Accept-Encoding is set explicitly the response is not decompressed transparently
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		body = gzipReader
 	}
 
-	return decodeJSONUsingNumber(bytes.NewReader(bodyBuff))
+	return readAllLimited(body, maxBytes)
 }
 
-func (l *jsonReferenceLoader) loadFromFile(path string) (interface{}, error) {
+func (l *jsonReferenceLoader) loadFromFile(path string, maxBytes int64) ([]byte, error) {
 	f, err := l.fs.Open(path)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
 
-	bodyBuff, err := ioutil.ReadAll(f)
-	if err != nil {
-		return nil, err
-	}
-
-	return decodeJSONUsingNumber(bytes.NewReader(bodyBuff))
+	return readAllLimited(f, maxBytes)
 
 }
 
@@ -344,6 +398,62 @@ func (l *jsonIOLoader) LoaderFactory() JSONLoaderFactory {
 	return &DefaultJSONLoaderFactory{}
 }
 
+// JSON reader loader with a base URI
+// reads the document from an io.Reader, but resolves relative references like a document loaded from the base URI
+
+type jsonReaderLoader struct {
+	source   io.Reader
+	base     string
+	buf      []byte
+	read     bool
+	maxBytes int64 // set by NewSizeLimitedLoader, 0 if there is no limit
+}
+
+// NewReaderLoaderWithBase creates a new JSON loader that reads the document from the provided io.Reader,
+// i.e. a schema piped through os.Stdin. Relative references in the schema are resolved against baseURI
+// as if the schema was loaded from there
+func NewReaderLoaderWithBase(source io.Reader, baseURI string) JSONLoader {
+	return &jsonReaderLoader{source: source, base: baseURI}
+}
+
+func (l *jsonReaderLoader) JsonSource() interface{} {
+	return l.source
+}
+
+// LoadJSON reads the reader on the first call, later calls decode the same document again
+func (l *jsonReaderLoader) LoadJSON() (interface{}, error) {
+	buf, err := l.readAll(l.maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONUsingNumber(bytes.NewReader(buf))
+}
+
+// readAll reads the source once, so the document can be loaded more than once.
+// Reading stops once more than maxBytes are read
+func (l *jsonReaderLoader) readAll(maxBytes int64) ([]byte, error) {
+	maxBytes = minLimit(maxBytes, l.maxBytes)
+	if !l.read {
+		buf, err := readAllLimited(l.source, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		l.buf, l.read = buf, true
+	}
+	if maxBytes > 0 && int64(len(l.buf)) > maxBytes {
+		return nil, errDocumentTooLarge(maxBytes)
+	}
+	return l.buf, nil
+}
+
+func (l *jsonReaderLoader) JsonReference() (gojsonreference.JsonReference, error) {
+	return gojsonreference.NewJsonReference(l.base)
+}
+
+func (l *jsonReaderLoader) LoaderFactory() JSONLoaderFactory {
+	return &DefaultJSONLoaderFactory{}
+}
+
 // JSON raw loader
 // In case the JSON is already marshalled to interface{} use this loader
 // This is used for testing as otherwise there is no guarantee the JSON is marshalled
@@ -369,6 +479,488 @@ func (l *jsonRawLoader) LoaderFactory() JSONLoaderFactory {
 	return &DefaultJSONLoaderFactory{}
 }
 
+// JSON size limited loader
+// protects against oversized documents and decompression bombs by aborting the read once a document
+// exceeds the maximum size
+
+type jsonSizeLimitedLoader struct {
+	loader   JSONLoader
+	maxBytes int64
+}
+
+// NewSizeLimitedLoader creates a new JSONLoader that returns an error for documents larger than maxBytes.
+// Reference loaders stop reading files and HTTP responses as soon as the limit is exceeded, after decompression,
+// and apply the limit to every "$ref" loaded through them as well. Reader loaders stop reading the reader.
+// For other loaders of JSON text, also when they are wrapped by other loaders, the size of the text is checked,
+// for Go and raw loaders that of the value encoded as JSON and for CBOR loaders that of the CBOR data. Merged loaders
+// apply the limit to every document they merge. The documents of any other loader, such as custom ones, are loaded
+// as is
+func NewSizeLimitedLoader(loader JSONLoader, maxBytes int64) JSONLoader {
+	switch l := loader.(type) {
+	case *jsonReferenceLoader:
+		limited := *l
+		limited.maxBytes = maxBytes
+		return &limited
+	case *jsonReaderLoader:
+		limited := *l
+		limited.maxBytes = maxBytes
+		return &limited
+	}
+	return &jsonSizeLimitedLoader{loader: loader, maxBytes: maxBytes}
+}
+
+func (l *jsonSizeLimitedLoader) JsonSource() interface{} {
+	return l.loader.JsonSource()
+}
+
+func (l *jsonSizeLimitedLoader) JsonReference() (gojsonreference.JsonReference, error) {
+	return l.loader.JsonReference()
+}
+
+func (l *jsonSizeLimitedLoader) LoaderFactory() JSONLoaderFactory {
+	return l.loader.LoaderFactory()
+}
+
+func (l *jsonSizeLimitedLoader) LoadJSON() (interface{}, error) {
+	text, err := loaderText(l, 0)
+	if err != nil {
+		return nil, err
+	}
+	if text == nil {
+		return loadJSONLimited(l.loader, l.maxBytes)
+	}
+	return decodeJSONText(text)
+}
+
+// loadJSONLimited loads the document of a loader without JSON text, returning an error if it is larger than maxBytes
+func loadJSONLimited(loader JSONLoader, maxBytes int64) (interface{}, error) {
+	switch l := loader.(type) {
+	case *jsonStrictLoader:
+		// Loaders without text are used as is by the strict loaders
+		return loadJSONLimited(l.loader, maxBytes)
+	case *jsonStrictUTF8Loader:
+		return loadJSONLimited(l.loader, maxBytes)
+	case *jsonSizeLimitedLoader:
+		return loadJSONLimited(l.loader, minLimit(maxBytes, l.maxBytes))
+	case *jsonGoLoader, *jsonRawLoader:
+		text, err := json.Marshal(l.JsonSource())
+		if err != nil {
+			return nil, err
+		}
+		if maxBytes > 0 && int64(len(text)) > maxBytes {
+			return nil, errDocumentTooLarge(maxBytes)
+		}
+		return decodeJSONUsingNumber(bytes.NewReader(text))
+	case *cborLoader:
+		if maxBytes > 0 && int64(len(l.source)) > maxBytes {
+			return nil, errDocumentTooLarge(maxBytes)
+		}
+		return l.LoadJSON()
+	case *jsonMergedLoader:
+		limited := make([]JSONLoader, len(l.loaders))
+		for i, part := range l.loaders {
+			limited[i] = NewSizeLimitedLoader(part, maxBytes)
+		}
+		return NewMergedLoader(limited...).LoadJSON()
+	}
+	// The size of the documents of other loaders, such as custom ones, isn't known
+	return loader.LoadJSON()
+}
+
+// minLimit returns the smallest of two size limits, where 0 means there is no limit
+func minLimit(a int64, b int64) int64 {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// readAllLimited reads r like ioutil.ReadAll, but stops with an error once more than maxBytes are read.
+// A maxBytes of 0 means there is no limit
+func readAllLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	if maxBytes <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	buf, err := ioutil.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(buf)) > maxBytes {
+		return nil, errDocumentTooLarge(maxBytes)
+	}
+	return buf, nil
+}
+
+func errDocumentTooLarge(maxBytes int64) error {
+	return errors.New(formatErrorDescription(Locale.DocumentTooLarge(), ErrorDetails{"max": maxBytes}))
+}
+
+// JSON merged loader
+// deep-merges several documents into one, i.e. a base schema and the overlays patching it
+
+type jsonMergedLoader struct {
+	loaders []JSONLoader
+}
+
+// NewMergedLoader creates a new JSONLoader that loads every loader in order and deep-merges the documents.
+// Objects are merged key by key, any other value, including arrays, replaces the value of the documents before it,
+// so the last loader wins. Relative references in the merged document are not resolved against the locations
+// of the sources, as the merged document doesn't have a location of its own
+func NewMergedLoader(loaders ...JSONLoader) JSONLoader {
+	return &jsonMergedLoader{loaders: loaders}
+}
+
+func (l *jsonMergedLoader) JsonSource() interface{} {
+	sources := make([]interface{}, len(l.loaders))
+	for i, loader := range l.loaders {
+		sources[i] = loader.JsonSource()
+	}
+	return sources
+}
+
+func (l *jsonMergedLoader) JsonReference() (gojsonreference.JsonReference, error) {
+	return gojsonreference.NewJsonReference("#")
+}
+
+func (l *jsonMergedLoader) LoaderFactory() JSONLoaderFactory {
+	if len(l.loaders) > 0 {
+		return l.loaders[0].LoaderFactory()
+	}
+	return &DefaultJSONLoaderFactory{}
+}
+
+func (l *jsonMergedLoader) LoadJSON() (interface{}, error) {
+	var merged interface{}
+	for _, loader := range l.loaders {
+		document, err := loader.LoadJSON()
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeJSON(merged, copyJSON(document))
+	}
+	return merged, nil
+}
+
+// mergeJSON merges overlay into base, both objects are merged recursively, otherwise overlay replaces base
+func mergeJSON(base interface{}, overlay interface{}) interface{} {
+	baseObject, ok := base.(map[string]interface{})
+	overlayObject, overlayIsObject := overlay.(map[string]interface{})
+	if !ok || !overlayIsObject {
+		return overlay
+	}
+	for key, value := range overlayObject {
+		baseObject[key] = mergeJSON(baseObject[key], value)
+	}
+	return baseObject
+}
+
+// JSON strict loader
+// encoding/json silently keeps the last value if an object contains the same key more than once,
+// which can hide malformed documents. This loader decodes the document token by token instead
+// and returns an error for duplicate keys
+
+type jsonStrictLoader struct {
+	loader JSONLoader
+}
+
+// NewStrictLoader creates a new JSONLoader that returns an error if the document contains duplicate keys.
+// Duplicate keys are detected for the loaders of JSON text, see loaderText, other loaders are used as is
+func NewStrictLoader(loader JSONLoader) JSONLoader {
+	return &jsonStrictLoader{loader: loader}
+}
+
+func (l *jsonStrictLoader) JsonSource() interface{} {
+	return l.loader.JsonSource()
+}
+
+func (l *jsonStrictLoader) JsonReference() (gojsonreference.JsonReference, error) {
+	return l.loader.JsonReference()
+}
+
+func (l *jsonStrictLoader) LoaderFactory() JSONLoaderFactory {
+	return l.loader.LoaderFactory()
+}
+
+func (l *jsonStrictLoader) LoadJSON() (interface{}, error) {
+	return loadJSONText(l, l.loader)
+}
+
+// tokenDecoder decodes a document token by token the same way encoding/json does, so duplicate keys can be
+// detected and where in the text every key appears can be recorded
+type tokenDecoder struct {
+	decoder *json.Decoder
+	text    []byte
+	// strict makes duplicate keys an error, see NewStrictLoader
+	strict bool
+	// source records the order of the keys of every object, and their spans if it has any, if it isn't nil.
+	// See loadJSONWithSource
+	source *schemaSource
+}
+
+func newTokenDecoder(text []byte, strict bool, source *schemaSource) *tokenDecoder {
+	decoder := json.NewDecoder(bytes.NewReader(text))
+	decoder.UseNumber()
+	return &tokenDecoder{decoder: decoder, text: text, strict: strict, source: source}
+}
+
+// decode decodes the document, context is only used by the errors of duplicate keys
+func (d *tokenDecoder) decode() (interface{}, error) {
+	var context *JsonContext
+	if d.strict {
+		context = NewJsonContext(STRING_CONTEXT_ROOT, nil)
+	}
+	return d.decodeValue(context)
+}
+
+func (d *tokenDecoder) decodeValue(context *JsonContext) (interface{}, error) {
+	token, err := d.decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := make(map[string]interface{})
+		var keys []string
+		var spans map[string]sourceSpan
+		if d.source != nil && d.source.spans != nil {
+			spans = make(map[string]sourceSpan)
+		}
+		for d.decoder.More() {
+			// The offset is at the end of the previous token, the key starts after the separator
+			start := int(d.decoder.InputOffset())
+			for start < len(d.text) && bytes.IndexByte([]byte(" \t\r\n,"), d.text[start]) >= 0 {
+				start++
+			}
+			token, err := d.decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := token.(string)
+			var keyContext *JsonContext
+			if d.strict {
+				if _, exists := object[key]; exists {
+					return nil, errors.New(formatErrorDescription(
+						Locale.DuplicateKey(),
+						ErrorDetails{"key": key, "context": context.String()},
+					))
+				}
+				keyContext = NewJsonContext(key, context)
+			}
+			if _, exists := object[key]; !exists && d.source != nil {
+				keys = append(keys, key)
+			}
+			if object[key], err = d.decodeValue(keyContext); err != nil {
+				return nil, err
+			}
+			if spans != nil {
+				spans[key] = sourceSpan{start: start, end: int(d.decoder.InputOffset())}
+			}
+		}
+		// Consume the closing delimiter
+		if _, err := d.decoder.Token(); err != nil {
+			return nil, err
+		}
+		if d.source != nil {
+			d.source.order[reflect.ValueOf(object).Pointer()] = keys
+		}
+		if spans != nil {
+			d.source.spans[reflect.ValueOf(object).Pointer()] = spans
+		}
+		return object, nil
+
+	case json.Delim('['):
+		array := make([]interface{}, 0)
+		for i := 0; d.decoder.More(); i++ {
+			var indexContext *JsonContext
+			if d.strict {
+				indexContext = newJsonContextIndex(i, context)
+			}
+			value, err := d.decodeValue(indexContext)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		if _, err := d.decoder.Token(); err != nil {
+			return nil, err
+		}
+		return array, nil
+	}
+
+	return token, nil
+}
+
+// JSON strict UTF-8 loader
+// encoding/json silently replaces invalid UTF-8 and escaped surrogates that aren't part of a pair, i.e. "\ud800",
+// by U+FFFD, after which they can't be told apart from a legitimate replacement character. This loader checks
+// the strings in the text of the document before it is decoded
+
+type jsonStrictUTF8Loader struct {
+	loader JSONLoader
+}
+
+// NewStrictUTF8Loader creates a new JSONLoader that returns an error if a string in the document contains invalid
+// UTF-8 or an unpaired surrogate. The text of the loaders of JSON text is checked, see loaderText, other loaders
+// are used as is
+func NewStrictUTF8Loader(loader JSONLoader) JSONLoader {
+	return &jsonStrictUTF8Loader{loader: loader}
+}
+
+func (l *jsonStrictUTF8Loader) JsonSource() interface{} {
+	return l.loader.JsonSource()
+}
+
+func (l *jsonStrictUTF8Loader) JsonReference() (gojsonreference.JsonReference, error) {
+	return l.loader.JsonReference()
+}
+
+func (l *jsonStrictUTF8Loader) LoaderFactory() JSONLoaderFactory {
+	return l.loader.LoaderFactory()
+}
+
+func (l *jsonStrictUTF8Loader) LoadJSON() (interface{}, error) {
+	return loadJSONText(l, l.loader)
+}
+
+// jsonText is the JSON text of a document, see loaderText
+type jsonText struct {
+	text []byte
+	// strict is set for loaders wrapped by NewStrictLoader, their documents may not contain duplicate keys
+	strict bool
+}
+
+// loaderText returns the JSON text of the document of loader, or an error once it is larger than maxBytes if that
+// isn't 0. It handles the loaders that hold or read JSON text, the string, bytes, reader and reference loaders,
+// and the loaders wrapping them, NewStrictLoader, NewStrictUTF8Loader and NewSizeLimitedLoader, whose checks of
+// the text are applied. Other loaders, such as those of Go values, CBOR or merged documents, have no text and nil
+// is returned
+func loaderText(loader JSONLoader, maxBytes int64) (*jsonText, error) {
+	var text []byte
+	switch l := loader.(type) {
+	case *jsonStringLoader:
+		text = []byte(l.source)
+	case *jsonBytesLoader:
+		text = l.source
+	case *jsonIOLoader:
+		text = l.buf.Bytes()
+	case *jsonReaderLoader:
+		// The reader and reference loaders stop reading at the limit
+		return textOrError(l.readAll(maxBytes))
+	case *jsonReferenceLoader:
+		return textOrError(l.readText(minLimit(maxBytes, l.maxBytes)))
+	case *jsonStrictLoader:
+		text, err := loaderText(l.loader, maxBytes)
+		if text != nil {
+			text.strict = true
+		}
+		return text, err
+	case *jsonStrictUTF8Loader:
+		text, err := loaderText(l.loader, maxBytes)
+		if text != nil {
+			if err := checkStrictUTF8(text.text); err != nil {
+				return nil, err
+			}
+		}
+		return text, err
+	case *jsonSizeLimitedLoader:
+		return loaderText(l.loader, minLimit(maxBytes, l.maxBytes))
+	default:
+		return nil, nil
+	}
+
+	if maxBytes > 0 && int64(len(text)) > maxBytes {
+		return nil, errDocumentTooLarge(maxBytes)
+	}
+	return &jsonText{text: text}, nil
+}
+
+func textOrError(text []byte, err error) (*jsonText, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &jsonText{text: text}, nil
+}
+
+// loadJSONText loads the document of a wrapping loader from its text, see loaderText, or with LoadJSON of the loader
+// it wraps if it has none
+func loadJSONText(loader JSONLoader, inner JSONLoader) (interface{}, error) {
+	text, err := loaderText(loader, 0)
+	if err != nil {
+		return nil, err
+	}
+	if text == nil {
+		return inner.LoadJSON()
+	}
+	return decodeJSONText(text)
+}
+
+// decodeJSONText decodes the document of text, see loaderText
+func decodeJSONText(text *jsonText) (interface{}, error) {
+	if text.strict {
+		return newTokenDecoder(text.text, true, nil).decode()
+	}
+	return decodeJSONUsingNumber(bytes.NewReader(text.text))
+}
+
+// checkStrictUTF8 returns an error for the first string in a JSON text that contains invalid UTF-8
+// or an escaped surrogate that isn't part of a pair
+func checkStrictUTF8(text []byte) error {
+	inString := false
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case !inString:
+			inString = c == '"'
+			i++
+		case c == '"':
+			inString = false
+			i++
+		case c == '\\':
+			r, ok := escapedRune(text, i)
+			if !ok {
+				i += 2
+				continue
+			}
+			if !utf16.IsSurrogate(r) {
+				i += 6
+				continue
+			}
+			// A high surrogate must be followed by a low one
+			if low, ok := escapedRune(text, i+6); ok && r < 0xdc00 && low >= 0xdc00 && low < 0xe000 {
+				i += 12
+				continue
+			}
+			return invalidUTF8Error(i)
+		case c < utf8.RuneSelf:
+			i++
+		default:
+			r, size := utf8.DecodeRune(text[i:])
+			if r == utf8.RuneError && size == 1 {
+				return invalidUTF8Error(i)
+			}
+			i += size
+		}
+	}
+	return nil
+}
+
+// escapedRune returns the rune of the \uXXXX escape at offset i, if there is one
+func escapedRune(text []byte, i int) (rune, bool) {
+	if i+6 > len(text) || text[i] != '\\' || text[i+1] != 'u' {
+		return 0, false
+	}
+	r, err := strconv.ParseUint(string(text[i+2:i+6]), 16, 16)
+	return rune(r), err == nil
+}
+
+func invalidUTF8Error(offset int) error {
+	return errors.New(formatErrorDescription(
+		Locale.InvalidUTF8(),
+		ErrorDetails{"offset": offset},
+	))
+}
+
 func decodeJSONUsingNumber(r io.Reader) (interface{}, error) {
 
 	var document interface{}
@@ -0,0 +1,395 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPLoaderGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			w.Write([]byte(`{"type" : "string"}`))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"type" : "integer"}`))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	schema, err := NewSchema(NewReferenceLoader(server.URL + "/schema.json"))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`"hello"`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "invalid_type", result.Errors()[0].Type())
+	}
+}
+
+func TestHTTPLoaderPlain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type" : "integer"}`))
+	}))
+	defer server.Close()
+
+	schema, err := NewSchema(NewReferenceLoader(server.URL + "/schema.json"))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`5`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+}
+
+type bearerTransport struct {
+	token string
+}
+
+func (b bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestHTTPLoaderClient(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requested = append(requested, r.URL.Path)
+		switch r.URL.Path {
+		case "/root.json":
+			w.Write([]byte(`{"properties" : {"id" : {"$ref" : "id.json"}}}`))
+		case "/id.json":
+			w.Write([]byte(`{"type" : "integer"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	_, err := NewSchema(NewReferenceLoader(server.URL + "/root.json"))
+	assert.NotNil(t, err)
+
+	client := &http.Client{Transport: bearerTransport{token: "secret"}}
+	schema, err := NewSchema(NewReferenceLoaderHTTPClient(server.URL+"/root.json", client))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"/root.json", "/id.json"}, requested)
+
+	result, err := schema.Validate(NewStringLoader(`{"id" : "x"}`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+}
+
+func TestStrictLoaderDuplicateKeys(t *testing.T) {
+	schema := NewStringLoader(`{"properties" : {"user" : {"properties" : {"password" : {"minLength" : 8}}}}}`)
+	document := `{"user" : {"password" : "x", "password" : "hunter22"}}`
+
+	// The last value wins by default
+	result, err := Validate(schema, NewStringLoader(document))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	_, err = Validate(schema, NewStrictLoader(NewStringLoader(document)))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `Duplicate key "password" in (root).user`, err.Error())
+	}
+
+	_, err = Validate(schema, NewStrictLoader(NewBytesLoader([]byte(`[{"a" : 1, "b" : {"a" : 2}, "a" : 3}]`))))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `Duplicate key "a" in (root).0`, err.Error())
+	}
+
+	result, err = Validate(schema, NewStrictLoader(NewStringLoader(`{"user" : {"password" : "x"}, "other" : [1.5, null, true]}`)))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+
+	_, err = Validate(schema, NewStrictLoader(NewReaderLoaderWithBase(strings.NewReader(document), "")))
+	assert.EqualError(t, err, `Duplicate key "password" in (root).user`)
+	reader, r := NewReaderLoader(strings.NewReader(document))
+	_, err = io.Copy(io.Discard, r)
+	require.Nil(t, err)
+	_, err = Validate(schema, NewStrictLoader(reader))
+	assert.EqualError(t, err, `Duplicate key "password" in (root).user`)
+}
+
+func TestStrictUTF8Loader(t *testing.T) {
+	schema := NewStringLoader(`{"properties" : {"name" : {"type" : "string"}}}`)
+
+	// encoding/json replaces invalid UTF-8 and unpaired surrogates by U+FFFD
+	for _, document := range []string{"{\"name\" : \"J\xffhn\"}", `{"name" : "J\ud800hn"}`, `{"name" : "J\udc00\ud800"}`} {
+		result, err := Validate(schema, NewStringLoader(document))
+		require.Nil(t, err)
+		assert.True(t, result.Valid())
+	}
+
+	_, err := Validate(schema, NewStrictUTF8Loader(NewStringLoader("{\"name\" : \"J\xffhn\"}")))
+	assert.EqualError(t, err, "String at offset 12 contains invalid UTF-8 or an unpaired surrogate")
+	_, err = Validate(schema, NewStrictUTF8Loader(NewBytesLoader([]byte(`{"name" : "J\ud800hn"}`))))
+	assert.EqualError(t, err, "String at offset 12 contains invalid UTF-8 or an unpaired surrogate")
+	_, err = Validate(schema, NewStrictUTF8Loader(NewStringLoader(`{"name" : "J\udc00\ud800"}`)))
+	assert.NotNil(t, err)
+	_, err = Validate(schema, NewStrictUTF8Loader(NewStrictLoader(NewStringLoader(`{"na\ud800me" : "John"}`))))
+	assert.NotNil(t, err)
+
+	result, err := Validate(schema, NewStrictUTF8Loader(NewStringLoader(`{"name" : "J\u00f6hn \ud83d\ude00 \\ud800 😀"}`)))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	// Strict loaders can be combined
+	_, err = Validate(schema, NewStrictUTF8Loader(NewStrictLoader(NewStringLoader(`{"name" : "a", "name" : "b"}`))))
+	assert.EqualError(t, err, `Duplicate key "name" in (root)`)
+}
+
+func TestCBORLoader(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"name" : {"type" : "string"},
+			"age" : {"type" : "integer", "maximum" : 40},
+			"tags" : {"items" : {"type" : "string"}},
+			"score" : {"type" : "number"},
+			"1" : {"type" : "string", "contentEncoding" : "base64"},
+			"balance" : {"type" : "integer"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	document := []byte{
+		0xa6,                                               // map of 6 pairs
+		0x64, 'n', 'a', 'm', 'e', 0x64, 'J', 'o', 'h', 'n', // "name" : "John"
+		0x63, 'a', 'g', 'e', 0x18, 0x2a, // "age" : 42
+		0x64, 't', 'a', 'g', 's', 0x9f, 0x61, 'a', 0x61, 'b', 0xff, // "tags" : indefinite ["a", "b"]
+		0x65, 's', 'c', 'o', 'r', 'e', 0xf9, 0x3e, 0x00, // "score" : half precision 1.5
+		0x01, 0x42, 0x01, 0x02, // 1 : h'0102'
+		0x67, 'b', 'a', 'l', 'a', 'n', 'c', 'e', 0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // "balance" : -2^64
+	}
+
+	loaded, err := NewCBORLoader(document).LoadJSON()
+	require.Nil(t, err)
+	expected, err := NewStringLoader(`{"name" : "John", "age" : 42, "tags" : ["a", "b"], "score" : 1.5, "1" : "AQI=", "balance" : -18446744073709551616}`).LoadJSON()
+	require.Nil(t, err)
+	assert.Equal(t, expected, loaded)
+
+	cborResult, err := schema.Validate(NewCBORLoader(document))
+	require.Nil(t, err)
+	jsonResult, err := schema.Validate(NewGoLoader(expected))
+	require.Nil(t, err)
+
+	assert.False(t, cborResult.Valid())
+	assert.Equal(t, jsonResult.Errors(), cborResult.Errors())
+
+	_, err = NewCBORLoader([]byte{0xa1, 0x81, 0x01, 0x01}).LoadJSON()
+	assert.NotNil(t, err, "array map keys are not supported")
+	_, err = NewCBORLoader([]byte{0x82, 0x01}).LoadJSON()
+	assert.NotNil(t, err, "truncated document")
+}
+
+func TestReaderLoaderWithBase(t *testing.T) {
+	sl := NewSchemaLoader()
+	err := sl.AddSchema("http://example.com/schemas/address.json", NewStringLoader(`{
+		"properties" : {"zip" : {"type" : "string"}}
+	}`))
+	require.Nil(t, err)
+
+	reader := strings.NewReader(`{"properties" : {"address" : {"$ref" : "address.json"}}}`)
+	schema, err := sl.Compile(NewReaderLoaderWithBase(reader, "http://example.com/schemas/person.json"))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"address" : {"zip" : 1234}}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "address.zip", result.Errors()[0].Field())
+	}
+
+	document := NewReaderLoaderWithBase(strings.NewReader(`{"address" : {"zip" : "1234"}}`), "")
+	result, err = schema.Validate(document)
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+}
+
+func TestReferenceLoaderFS(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"bundle/root.json": `{
+			"properties" : {
+				"id" : {"$ref" : "common/types.json#/definitions/id"},
+				"tags" : {"type" : "array", "items" : {"$ref" : "common/types.json#/definitions/tag"}}
+			}
+		}`,
+		"bundle/common/types.json": `{
+			"definitions" : {
+				"id" : {"type" : "integer", "minimum" : 1},
+				"tag" : {"type" : "string"}
+			}
+		}`,
+	} {
+		w, err := archive.Create(name)
+		require.Nil(t, err)
+		_, err = w.Write([]byte(content))
+		require.Nil(t, err)
+	}
+	require.Nil(t, archive.Close())
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.Nil(t, err)
+
+	schema, err := NewSchema(NewReferenceLoaderFS("file:///bundle/root.json", zipReader))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"id" : 1, "tags" : ["a"]}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`{"id" : 0, "tags" : [1]}`))
+	require.Nil(t, err)
+	fields := map[string]string{}
+	for _, resultErr := range result.Errors() {
+		fields[resultErr.Field()] = resultErr.Type()
+	}
+	assert.Equal(t, map[string]string{"id": ErrorTypeNumberGTE, "tags.0": ErrorTypeInvalidType}, fields)
+
+	_, err = NewSchema(NewReferenceLoaderFS("file:///bundle/missing.json", zipReader))
+	assert.NotNil(t, err)
+}
+
+// countingReader counts the bytes read from it
+type countingReader struct {
+	r    io.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += n
+	return n, err
+}
+
+func TestSizeLimitedLoader(t *testing.T) {
+	large := `{"type" : "string", "description" : "` + strings.Repeat("x", 1<<20) + `"}`
+
+	reader := &countingReader{r: strings.NewReader(large)}
+	_, err := NewSizeLimitedLoader(NewReaderLoaderWithBase(reader, ""), 1024).LoadJSON()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Document is larger than the maximum size of 1024 bytes", err.Error())
+	}
+	assert.True(t, reader.read < len(large), "the reader should not be consumed entirely")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small.json":
+			w.Write([]byte(`{"properties" : {"a" : {"$ref" : "large.json"}}}`))
+		case "/large.json":
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(large))
+			gz.Close()
+		}
+	}))
+	defer server.Close()
+
+	_, err = NewSchema(NewSizeLimitedLoader(NewReferenceLoader(server.URL+"/large.json"), 1024))
+	assert.NotNil(t, err)
+	_, err = NewSchema(NewSizeLimitedLoader(NewReferenceLoader(server.URL+"/small.json"), 1024))
+	assert.NotNil(t, err, "the limit applies to references as well")
+	_, err = NewSchema(NewReferenceLoader(server.URL + "/small.json"))
+	assert.Nil(t, err)
+
+	_, err = NewSizeLimitedLoader(NewStringLoader(large), 1024).LoadJSON()
+	assert.NotNil(t, err)
+	_, err = NewSizeLimitedLoader(NewStringLoader(`{"type" : "string"}`), 1024).LoadJSON()
+	assert.Nil(t, err)
+
+	// Wrapped loaders are limited as well
+	schema := NewStringLoader(`{"type" : "object"}`)
+	_, err = Validate(schema, NewSizeLimitedLoader(NewStrictLoader(NewStringLoader(large)), 1024))
+	assert.EqualError(t, err, "Document is larger than the maximum size of 1024 bytes")
+	reader = &countingReader{r: strings.NewReader(large)}
+	_, err = NewSizeLimitedLoader(NewStrictUTF8Loader(NewReaderLoaderWithBase(reader, "")), 1024).LoadJSON()
+	assert.EqualError(t, err, "Document is larger than the maximum size of 1024 bytes")
+	assert.True(t, reader.read < len(large), "the reader should not be consumed entirely")
+	result, err := Validate(schema, NewSizeLimitedLoader(NewStrictLoader(NewStringLoader(`{"a" : 1}`)), 1024))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	_, err = NewSizeLimitedLoader(NewGoLoader(map[string]string{"description": large}), 1024).LoadJSON()
+	assert.NotNil(t, err)
+	_, err = NewSizeLimitedLoader(NewMergedLoader(NewStringLoader(`{}`), NewStringLoader(large)), 1024).LoadJSON()
+	assert.NotNil(t, err)
+	_, err = NewSizeLimitedLoader(NewRawLoader(map[string]interface{}{"description": large}), 1024).LoadJSON()
+	assert.NotNil(t, err)
+
+	// The documents of custom loaders are loaded as is
+	document, err := NewSizeLimitedLoader(customLoader{NewStringLoader(large)}, 1024).LoadJSON()
+	require.Nil(t, err)
+	assert.Equal(t, "string", document.(map[string]interface{})["type"])
+}
+
+// customLoader is a JSONLoader that isn't one of the package
+type customLoader struct {
+	JSONLoader
+}
+
+func TestMergedLoader(t *testing.T) {
+	base := NewStringLoader(`{
+		"type" : "object",
+		"properties" : {"name" : {"type" : "string"}},
+		"required" : ["name"]
+	}`)
+	overlay := NewStringLoader(`{
+		"properties" : {"email" : {"type" : "string", "format" : "email"}},
+		"required" : ["name", "email"]
+	}`)
+
+	document, err := NewMergedLoader(base, overlay).LoadJSON()
+	require.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name":  map[string]interface{}{"type": "string"},
+			"email": map[string]interface{}{"type": "string", "format": "email"},
+		},
+		"required": []interface{}{"name", "email"},
+	}, document)
+
+	schema, err := NewSchema(NewMergedLoader(base, overlay))
+	require.Nil(t, err)
+	result, err := schema.Validate(NewStringLoader(`{"name" : "John"}`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+	result, err = schema.Validate(NewStringLoader(`{"name" : "John", "email" : "john@example.com"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	schema, err = NewSchema(NewMergedLoader(base))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`{"name" : "John"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+}
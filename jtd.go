@@ -0,0 +1,536 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON Type Definition keywords, see RFC 8927
+const (
+	jtdKeyDefinitions          = "definitions"
+	jtdKeyMetadata             = "metadata"
+	jtdKeyNullable             = "nullable"
+	jtdKeyRef                  = "ref"
+	jtdKeyType                 = "type"
+	jtdKeyEnum                 = "enum"
+	jtdKeyElements             = "elements"
+	jtdKeyProperties           = "properties"
+	jtdKeyOptionalProperties   = "optionalProperties"
+	jtdKeyAdditionalProperties = "additionalProperties"
+	jtdKeyValues               = "values"
+	jtdKeyDiscriminator        = "discriminator"
+	jtdKeyMapping              = "mapping"
+)
+
+// jtdTypeRanges holds the bounds of the JSON Type Definition integer types
+var jtdTypeRanges = map[string][2]int64{
+	"int8":   {-128, 127},
+	"uint8":  {0, 255},
+	"int16":  {-32768, 32767},
+	"uint16": {0, 65535},
+	"int32":  {-2147483648, 2147483647},
+	"uint32": {0, 4294967295},
+}
+
+type (
+	// JTDSchema holds a compiled JSON Type Definition (RFC 8927) schema
+	JTDSchema struct {
+		root        *jtdSchema
+		definitions map[string]*jtdSchema
+	}
+
+	// JTDError is an error indicator as defined by RFC 8927, the JSON pointers to the part of the instance
+	// that is rejected and to the part of the schema that rejected it
+	JTDError struct {
+		InstancePath string
+		SchemaPath   string
+	}
+
+	// JTDResult holds the result of a validation against a JTDSchema
+	JTDResult struct {
+		errors []JTDError
+	}
+
+	jtdSchema struct {
+		nullable bool
+
+		ref                *string
+		_type              string
+		enum               map[string]bool
+		elements           *jtdSchema
+		properties         map[string]*jtdSchema
+		optionalProperties map[string]*jtdSchema
+		additional         bool
+		values             *jtdSchema
+		discriminator      string
+		mapping            map[string]*jtdSchema
+
+		// Which form the schema has, named after its keyword or empty for the empty form
+		form string
+	}
+)
+
+// Valid indicates if no errors were found
+func (r *JTDResult) Valid() bool {
+	return len(r.errors) == 0
+}
+
+// Errors returns the error indicators that were found
+func (r *JTDResult) Errors() []JTDError {
+	return r.errors
+}
+
+// NewJTDSchema compiles a JSON Type Definition schema
+func NewJTDSchema(l JSONLoader) (*JTDSchema, error) {
+	document, err := l.LoadJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := document.(map[string]interface{})
+	if !ok {
+		return nil, newJTDSchemaError("", "must be an object")
+	}
+
+	s := &JTDSchema{definitions: map[string]*jtdSchema{}}
+
+	if definitions, ok := m[jtdKeyDefinitions]; ok {
+		definitionsMap, ok := definitions.(map[string]interface{})
+		if !ok {
+			return nil, newJTDSchemaError("/"+jtdKeyDefinitions, "must be an object")
+		}
+		for _, name := range sortedKeys(definitionsMap) {
+			path := "/" + jtdKeyDefinitions + "/" + jsonPointerEscaper.Replace(name)
+			if s.definitions[name], err = parseJTDSchema(definitionsMap[name], path, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if s.root, err = parseJTDSchema(m, "", true); err != nil {
+		return nil, err
+	}
+
+	// Every reference must be to one of the definitions
+	if err := s.checkRefs(s.root, ""); err != nil {
+		return nil, err
+	}
+	for _, name := range jtdSortedNames(s.definitions) {
+		if err := s.checkRefs(s.definitions[name], "/"+jtdKeyDefinitions+"/"+jsonPointerEscaper.Replace(name)); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// jtdSortedNames returns the names of the schemas in m in sorted order
+func jtdSortedNames(m map[string]*jtdSchema) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newJTDSchemaError(path string, reason string) error {
+	if path == "" {
+		path = "/"
+	}
+	return errors.New(formatErrorDescription(
+		Locale.InvalidJTDSchema(),
+		ErrorDetails{"path": path, "reason": reason},
+	))
+}
+
+func parseJTDSchema(document interface{}, path string, root bool) (*jtdSchema, error) {
+	m, ok := document.(map[string]interface{})
+	if !ok {
+		return nil, newJTDSchemaError(path, "must be an object")
+	}
+
+	s := &jtdSchema{}
+
+	// The keywords every schema may have, the form decides which others are allowed
+	allowed := map[string]bool{jtdKeyMetadata: true, jtdKeyNullable: true}
+	if root {
+		allowed[jtdKeyDefinitions] = true
+	}
+
+	if metadata, ok := m[jtdKeyMetadata]; ok {
+		if _, ok := metadata.(map[string]interface{}); !ok {
+			return nil, newJTDSchemaError(path+"/"+jtdKeyMetadata, "must be an object")
+		}
+	}
+	if nullable, ok := m[jtdKeyNullable]; ok {
+		if s.nullable, ok = nullable.(bool); !ok {
+			return nil, newJTDSchemaError(path+"/"+jtdKeyNullable, "must be a boolean")
+		}
+	}
+
+	for _, form := range []string{jtdKeyRef, jtdKeyType, jtdKeyEnum, jtdKeyElements, jtdKeyProperties, jtdKeyOptionalProperties, jtdKeyValues, jtdKeyDiscriminator} {
+		if _, ok := m[form]; !ok {
+			continue
+		}
+		// properties and optionalProperties are the same form
+		if s.form != "" && !(s.form == jtdKeyProperties && form == jtdKeyOptionalProperties) {
+			return nil, newJTDSchemaError(path, "can't have both "+s.form+" and "+form)
+		}
+		if s.form == "" {
+			s.form = form
+		}
+	}
+	if s.form == jtdKeyOptionalProperties {
+		s.form = jtdKeyProperties
+	}
+
+	var err error
+
+	switch s.form {
+	case jtdKeyRef:
+		ref, ok := m[jtdKeyRef].(string)
+		if !ok {
+			return nil, newJTDSchemaError(path+"/"+jtdKeyRef, "must be a string")
+		}
+		s.ref = &ref
+		allowed[jtdKeyRef] = true
+
+	case jtdKeyType:
+		t, ok := m[jtdKeyType].(string)
+		if _, isInt := jtdTypeRanges[t]; !ok || !isInt && t != "boolean" && t != "string" && t != "timestamp" && t != "float32" && t != "float64" {
+			return nil, newJTDSchemaError(path+"/"+jtdKeyType, "must be the name of a type")
+		}
+		s._type = t
+		allowed[jtdKeyType] = true
+
+	case jtdKeyEnum:
+		values, ok := m[jtdKeyEnum].([]interface{})
+		if !ok || len(values) == 0 {
+			return nil, newJTDSchemaError(path+"/"+jtdKeyEnum, "must be a non-empty array of strings")
+		}
+		s.enum = make(map[string]bool)
+		for _, v := range values {
+			value, ok := v.(string)
+			if !ok || s.enum[value] {
+				return nil, newJTDSchemaError(path+"/"+jtdKeyEnum, "must be an array of unique strings")
+			}
+			s.enum[value] = true
+		}
+		allowed[jtdKeyEnum] = true
+
+	case jtdKeyElements:
+		if s.elements, err = parseJTDSchema(m[jtdKeyElements], path+"/"+jtdKeyElements, false); err != nil {
+			return nil, err
+		}
+		allowed[jtdKeyElements] = true
+
+	case jtdKeyProperties:
+		if s.properties, err = parseJTDSchemaMap(m, jtdKeyProperties, path); err != nil {
+			return nil, err
+		}
+		if s.optionalProperties, err = parseJTDSchemaMap(m, jtdKeyOptionalProperties, path); err != nil {
+			return nil, err
+		}
+		for name := range s.optionalProperties {
+			if _, ok := s.properties[name]; ok {
+				return nil, newJTDSchemaError(path+"/"+jtdKeyOptionalProperties, "property "+name+" is also in properties")
+			}
+		}
+		if additional, ok := m[jtdKeyAdditionalProperties]; ok {
+			if s.additional, ok = additional.(bool); !ok {
+				return nil, newJTDSchemaError(path+"/"+jtdKeyAdditionalProperties, "must be a boolean")
+			}
+		}
+		allowed[jtdKeyProperties] = true
+		allowed[jtdKeyOptionalProperties] = true
+		allowed[jtdKeyAdditionalProperties] = true
+
+	case jtdKeyValues:
+		if s.values, err = parseJTDSchema(m[jtdKeyValues], path+"/"+jtdKeyValues, false); err != nil {
+			return nil, err
+		}
+		allowed[jtdKeyValues] = true
+
+	case jtdKeyDiscriminator:
+		if s.discriminator, ok = m[jtdKeyDiscriminator].(string); !ok {
+			return nil, newJTDSchemaError(path+"/"+jtdKeyDiscriminator, "must be a string")
+		}
+		if _, ok := m[jtdKeyMapping]; !ok {
+			return nil, newJTDSchemaError(path, "discriminator requires mapping")
+		}
+		if s.mapping, err = parseJTDSchemaMap(m, jtdKeyMapping, path); err != nil {
+			return nil, err
+		}
+		for _, tag := range jtdSortedNames(s.mapping) {
+			mapped := s.mapping[tag]
+			mappedPath := path + "/" + jtdKeyMapping + "/" + jsonPointerEscaper.Replace(tag)
+			if mapped.form != jtdKeyProperties || mapped.nullable {
+				return nil, newJTDSchemaError(mappedPath, "must be a non-nullable properties schema")
+			}
+			_, required := mapped.properties[s.discriminator]
+			_, optional := mapped.optionalProperties[s.discriminator]
+			if required || optional {
+				return nil, newJTDSchemaError(mappedPath, "must not define the discriminator "+s.discriminator)
+			}
+		}
+		allowed[jtdKeyDiscriminator] = true
+		allowed[jtdKeyMapping] = true
+	}
+
+	for _, k := range sortedKeys(m) {
+		if !allowed[k] {
+			return nil, newJTDSchemaError(path+"/"+jsonPointerEscaper.Replace(k), "keyword is not allowed here")
+		}
+	}
+
+	return s, nil
+}
+
+// parseJTDSchemaMap parses the schemas of a keyword that maps names to schemas, like properties
+func parseJTDSchemaMap(m map[string]interface{}, keyword string, path string) (map[string]*jtdSchema, error) {
+	value, ok := m[keyword]
+	if !ok {
+		return nil, nil
+	}
+	schemas, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, newJTDSchemaError(path+"/"+keyword, "must be an object")
+	}
+
+	parsed := make(map[string]*jtdSchema, len(schemas))
+	for _, name := range sortedKeys(schemas) {
+		schema, err := parseJTDSchema(schemas[name], path+"/"+keyword+"/"+jsonPointerEscaper.Replace(name), false)
+		if err != nil {
+			return nil, err
+		}
+		parsed[name] = schema
+	}
+	return parsed, nil
+}
+
+func (s *JTDSchema) checkRefs(schema *jtdSchema, path string) error {
+	if schema.ref != nil {
+		if _, ok := s.definitions[*schema.ref]; !ok {
+			return newJTDSchemaError(path+"/"+jtdKeyRef, "there is no definition "+*schema.ref)
+		}
+	}
+
+	children := map[string]*jtdSchema{}
+	if schema.elements != nil {
+		children["/"+jtdKeyElements] = schema.elements
+	}
+	if schema.values != nil {
+		children["/"+jtdKeyValues] = schema.values
+	}
+	for keyword, schemas := range map[string]map[string]*jtdSchema{
+		jtdKeyProperties:         schema.properties,
+		jtdKeyOptionalProperties: schema.optionalProperties,
+		jtdKeyMapping:            schema.mapping,
+	} {
+		for name, child := range schemas {
+			children["/"+keyword+"/"+jsonPointerEscaper.Replace(name)] = child
+		}
+	}
+
+	for _, childPath := range jtdSortedNames(children) {
+		if err := s.checkRefs(children[childPath], path+childPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate loads and validates a JSON document against the schema
+func (s *JTDSchema) Validate(l JSONLoader) (*JTDResult, error) {
+	instance, err := l.LoadJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	v := &jtdValidator{schema: s, result: &JTDResult{}}
+	v.validate(s.root, instance, "", "", "")
+	if v.tooDeep != "" {
+		return nil, errors.New(formatErrorDescription(
+			Locale.MaxDepthExceeded(),
+			ErrorDetails{"max": defaultMaxDepth, "context": v.tooDeep},
+		))
+	}
+	return v.result, nil
+}
+
+type jtdValidator struct {
+	schema *JTDSchema
+	result *JTDResult
+	// The number of references followed, which could otherwise go on forever for circular definitions
+	depth   int
+	tooDeep string
+}
+
+func (v *jtdValidator) addError(instancePath string, schemaPath string) {
+	v.result.errors = append(v.result.errors, JTDError{InstancePath: instancePath, SchemaPath: schemaPath})
+}
+
+// validate validates instance against schema. discriminator is the tag of the discriminator the schema
+// is mapped from, which is allowed in addition to the properties of the schema
+func (v *jtdValidator) validate(schema *jtdSchema, instance interface{}, instancePath string, schemaPath string, discriminator string) {
+	if v.tooDeep != "" {
+		return
+	}
+
+	if schema.nullable && instance == nil {
+		return
+	}
+
+	switch schema.form {
+	case jtdKeyRef:
+		if v.depth >= defaultMaxDepth {
+			v.tooDeep = instancePath
+			return
+		}
+		v.depth++
+		v.validate(v.schema.definitions[*schema.ref], instance, instancePath, "/"+jtdKeyDefinitions+"/"+jsonPointerEscaper.Replace(*schema.ref), "")
+		v.depth--
+
+	case jtdKeyType:
+		if !jtdIsType(schema._type, instance) {
+			v.addError(instancePath, schemaPath+"/"+jtdKeyType)
+		}
+
+	case jtdKeyEnum:
+		if value, ok := instance.(string); !ok || !schema.enum[value] {
+			v.addError(instancePath, schemaPath+"/"+jtdKeyEnum)
+		}
+
+	case jtdKeyElements:
+		elements, ok := instance.([]interface{})
+		if !ok {
+			v.addError(instancePath, schemaPath+"/"+jtdKeyElements)
+			return
+		}
+		for i, element := range elements {
+			v.validate(schema.elements, element, instancePath+"/"+strconv.Itoa(i), schemaPath+"/"+jtdKeyElements, "")
+		}
+
+	case jtdKeyProperties:
+		object, ok := instance.(map[string]interface{})
+		if !ok {
+			if schema.properties != nil {
+				v.addError(instancePath, schemaPath+"/"+jtdKeyProperties)
+			} else {
+				v.addError(instancePath, schemaPath+"/"+jtdKeyOptionalProperties)
+			}
+			return
+		}
+		for _, name := range jtdSortedNames(schema.properties) {
+			propertySchemaPath := schemaPath + "/" + jtdKeyProperties + "/" + jsonPointerEscaper.Replace(name)
+			if value, ok := object[name]; ok {
+				v.validate(schema.properties[name], value, instancePath+"/"+jsonPointerEscaper.Replace(name), propertySchemaPath, "")
+			} else {
+				v.addError(instancePath, propertySchemaPath)
+			}
+		}
+		for _, name := range jtdSortedNames(schema.optionalProperties) {
+			if value, ok := object[name]; ok {
+				propertySchemaPath := schemaPath + "/" + jtdKeyOptionalProperties + "/" + jsonPointerEscaper.Replace(name)
+				v.validate(schema.optionalProperties[name], value, instancePath+"/"+jsonPointerEscaper.Replace(name), propertySchemaPath, "")
+			}
+		}
+		if !schema.additional {
+			for _, name := range sortedKeys(object) {
+				_, required := schema.properties[name]
+				_, optional := schema.optionalProperties[name]
+				if !required && !optional && name != discriminator {
+					v.addError(instancePath+"/"+jsonPointerEscaper.Replace(name), schemaPath)
+				}
+			}
+		}
+
+	case jtdKeyValues:
+		object, ok := instance.(map[string]interface{})
+		if !ok {
+			v.addError(instancePath, schemaPath+"/"+jtdKeyValues)
+			return
+		}
+		for _, name := range sortedKeys(object) {
+			v.validate(schema.values, object[name], instancePath+"/"+jsonPointerEscaper.Replace(name), schemaPath+"/"+jtdKeyValues, "")
+		}
+
+	case jtdKeyDiscriminator:
+		object, ok := instance.(map[string]interface{})
+		if !ok {
+			v.addError(instancePath, schemaPath+"/"+jtdKeyDiscriminator)
+			return
+		}
+		tagValue, ok := object[schema.discriminator]
+		if !ok {
+			v.addError(instancePath, schemaPath+"/"+jtdKeyDiscriminator)
+			return
+		}
+		tagPath := instancePath + "/" + jsonPointerEscaper.Replace(schema.discriminator)
+		tag, ok := tagValue.(string)
+		if !ok {
+			v.addError(tagPath, schemaPath+"/"+jtdKeyDiscriminator)
+			return
+		}
+		mapped, ok := schema.mapping[tag]
+		if !ok {
+			v.addError(tagPath, schemaPath+"/"+jtdKeyMapping)
+			return
+		}
+		v.validate(mapped, instance, instancePath, schemaPath+"/"+jtdKeyMapping+"/"+jsonPointerEscaper.Replace(tag), schema.discriminator)
+	}
+}
+
+// jtdIsType checks whether instance is of one of the types of the type form
+func jtdIsType(t string, instance interface{}) bool {
+	switch t {
+	case "boolean":
+		_, ok := instance.(bool)
+		return ok
+	case "string":
+		_, ok := instance.(string)
+		return ok
+	case "timestamp":
+		s, ok := instance.(string)
+		return ok && jtdIsTimestamp(s)
+	case "float32", "float64":
+		return isJSONNumber(instance)
+	}
+
+	// The integer types
+	number := mustBeNumber(instance)
+	if number == nil || !number.IsInt() {
+		return false
+	}
+	bounds := jtdTypeRanges[t]
+	return number.Num().Cmp(big.NewInt(bounds[0])) >= 0 && number.Num().Cmp(big.NewInt(bounds[1])) <= 0
+}
+
+// jtdIsTimestamp checks whether s is an RFC 3339 timestamp, which allows leap seconds unlike time.Parse
+func jtdIsTimestamp(s string) bool {
+	s = strings.ToUpper(s)
+	if i := strings.IndexByte(s, 'T'); i >= 0 && len(s) > i+8 && s[i+6:i+9] == ":60" {
+		s = s[:i+7] + "59" + s[i+9:]
+	}
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
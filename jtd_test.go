@@ -0,0 +1,118 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validateJTD(t *testing.T, schema string, instance string) []JTDError {
+	s, err := NewJTDSchema(NewStringLoader(schema))
+	require.Nil(t, err)
+	result, err := s.Validate(NewStringLoader(instance))
+	require.Nil(t, err)
+	assert.Equal(t, len(result.Errors()) == 0, result.Valid())
+	return result.Errors()
+}
+
+func TestJTDProperties(t *testing.T) {
+	schema := `{
+		"properties" : {
+			"name" : {"type" : "string"},
+			"age" : {"type" : "uint8"},
+			"birthday" : {"type" : "timestamp", "nullable" : true}
+		},
+		"optionalProperties" : {"tags" : {"elements" : {"type" : "string"}}}
+	}`
+
+	assert.Empty(t, validateJTD(t, schema, `{"name" : "John", "age" : 42, "birthday" : "1990-12-31T23:59:60Z", "tags" : []}`))
+	assert.Empty(t, validateJTD(t, schema, `{"name" : "John", "age" : 42.0, "birthday" : null}`))
+
+	assert.Equal(t, []JTDError{
+		{InstancePath: "/age", SchemaPath: "/properties/age/type"},
+		{InstancePath: "", SchemaPath: "/properties/birthday"},
+		{InstancePath: "/tags/1", SchemaPath: "/optionalProperties/tags/elements/type"},
+		{InstancePath: "/extra", SchemaPath: ""},
+	}, validateJTD(t, schema, `{"name" : "John", "age" : 256, "tags" : ["a", 1], "extra" : true}`))
+
+	assert.Equal(t, []JTDError{{InstancePath: "", SchemaPath: "/properties"}}, validateJTD(t, schema, `[]`))
+
+	assert.Empty(t, validateJTD(t, `{"properties" : {}, "additionalProperties" : true}`, `{"extra" : true}`))
+}
+
+func TestJTDElements(t *testing.T) {
+	schema := `{"elements" : {"ref" : "point"}, "definitions" : {"point" : {"properties" : {"x" : {"type" : "float64"}, "y" : {"type" : "float64"}}}}}`
+
+	assert.Empty(t, validateJTD(t, schema, `[{"x" : 1.5, "y" : 2}]`))
+	assert.Equal(t, []JTDError{
+		{InstancePath: "/1/y", SchemaPath: "/definitions/point/properties/y/type"},
+	}, validateJTD(t, schema, `[{"x" : 1.5, "y" : 2}, {"x" : 0, "y" : "2"}]`))
+	assert.Equal(t, []JTDError{{InstancePath: "", SchemaPath: "/elements"}}, validateJTD(t, schema, `{}`))
+}
+
+func TestJTDValues(t *testing.T) {
+	schema := `{"values" : {"enum" : ["on", "off"]}}`
+
+	assert.Empty(t, validateJTD(t, schema, `{"a" : "on", "b" : "off"}`))
+	assert.Equal(t, []JTDError{
+		{InstancePath: "/b", SchemaPath: "/values/enum"},
+	}, validateJTD(t, schema, `{"a" : "on", "b" : "maybe"}`))
+	assert.Equal(t, []JTDError{{InstancePath: "", SchemaPath: "/values"}}, validateJTD(t, schema, `"on"`))
+}
+
+func TestJTDDiscriminator(t *testing.T) {
+	schema := `{
+		"discriminator" : "kind",
+		"mapping" : {
+			"circle" : {"properties" : {"radius" : {"type" : "float32"}}},
+			"square" : {"properties" : {"side" : {"type" : "int32"}}}
+		}
+	}`
+
+	assert.Empty(t, validateJTD(t, schema, `{"kind" : "circle", "radius" : 1.5}`))
+	assert.Equal(t, []JTDError{
+		{InstancePath: "/side", SchemaPath: "/mapping/square/properties/side/type"},
+	}, validateJTD(t, schema, `{"kind" : "square", "side" : 1.5}`))
+	assert.Equal(t, []JTDError{
+		{InstancePath: "/kind", SchemaPath: "/mapping"},
+	}, validateJTD(t, schema, `{"kind" : "triangle"}`))
+	assert.Equal(t, []JTDError{
+		{InstancePath: "/kind", SchemaPath: "/discriminator"},
+	}, validateJTD(t, schema, `{"kind" : 1}`))
+	assert.Equal(t, []JTDError{
+		{InstancePath: "", SchemaPath: "/discriminator"},
+	}, validateJTD(t, schema, `{"radius" : 1.5}`))
+}
+
+func TestJTDInvalidSchemas(t *testing.T) {
+	for schema, message := range map[string]string{
+		`[]`:                                  `Invalid JSON Type Definition at /: must be an object`,
+		`{"type" : "integer"}`:                `Invalid JSON Type Definition at /type: must be the name of a type`,
+		`{"type" : "string", "enum" : ["a"]}`: `Invalid JSON Type Definition at /: can't have both type and enum`,
+		`{"ref" : "missing"}`:                 `Invalid JSON Type Definition at /ref: there is no definition missing`,
+		`{"elements" : {"definitions" : {}}}`: `Invalid JSON Type Definition at /elements/definitions: keyword is not allowed here`,
+		`{"properties" : {"a" : {}}, "optionalProperties" : {"a" : {}}}`:                 `Invalid JSON Type Definition at /optionalProperties: property a is also in properties`,
+		`{"discriminator" : "kind", "mapping" : {"a" : {"type" : "string"}}}`:            `Invalid JSON Type Definition at /mapping/a: must be a non-nullable properties schema`,
+		`{"discriminator" : "kind", "mapping" : {"a" : {"properties" : {"kind" : {}}}}}`: `Invalid JSON Type Definition at /mapping/a: must not define the discriminator kind`,
+	} {
+		_, err := NewJTDSchema(NewStringLoader(schema))
+		if assert.NotNil(t, err, schema) {
+			assert.Equal(t, message, err.Error(), schema)
+		}
+	}
+}
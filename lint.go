@@ -0,0 +1,234 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LintExamples validates the values of every "examples" keyword against the schema it appears in
+// and returns an error for every example that is invalid. The errors contain the JSON pointer of the schema
+func (d *Schema) LintExamples() []error {
+	var errs []error
+	walkSubSchemas(d.rootSchema, "#", func(s *subSchema, path string) {
+		if node, ok := s.documentNode.(map[string]interface{}); ok {
+			if examples, ok := node[KEY_EXAMPLES].([]interface{}); ok {
+				d.lintSchemaExamples(s, examples, path, &errs)
+			}
+		}
+	})
+	return errs
+}
+
+// LintConstraints returns an error for every constraint in the schema that can never be satisfied, such as a
+// "minimum" that is greater than the "maximum", and for every keyword that has no effect because it doesn't apply
+// to any of the types the schema allows, such as "minLength" next to "type": "integer". It is diagnostic only,
+// validation is not affected. The errors contain the JSON pointer of the schema
+func (d *Schema) LintConstraints() []error {
+	var errs []error
+	walkSchemaDocument(d.rootSchema.documentNode, "#", func(node map[string]interface{}, path string) {
+		lintContradictoryBounds(node, path, &errs)
+		lintIrrelevantKeywords(node, path, &errs)
+	})
+	return errs
+}
+
+// contradictoryBounds lists pairs of keywords of which the lower bound must not exceed the upper bound,
+// and whether the bounds also contradict each other when they are equal
+var contradictoryBounds = []struct {
+	min, max  string
+	exclusive bool
+}{
+	{KEY_MINIMUM, KEY_MAXIMUM, false},
+	{KEY_EXCLUSIVE_MINIMUM, KEY_MAXIMUM, true},
+	{KEY_MINIMUM, KEY_EXCLUSIVE_MAXIMUM, true},
+	{KEY_EXCLUSIVE_MINIMUM, KEY_EXCLUSIVE_MAXIMUM, true},
+	{KEY_MIN_LENGTH, KEY_MAX_LENGTH, false},
+	{KEY_MIN_ITEMS, KEY_MAX_ITEMS, false},
+	{KEY_MIN_PROPERTIES, KEY_MAX_PROPERTIES, false},
+}
+
+func lintContradictoryBounds(node map[string]interface{}, path string, errs *[]error) {
+	for _, bounds := range contradictoryBounds {
+		// Draft 4 "exclusiveMinimum" and "exclusiveMaximum" are booleans, so they aren't numbers here
+		min, max := mustBeNumber(node[bounds.min]), mustBeNumber(node[bounds.max])
+		if min == nil || max == nil {
+			continue
+		}
+		if cmp := min.Cmp(max); cmp > 0 || (cmp == 0 && bounds.exclusive) {
+			*errs = append(*errs, errors.New(formatErrorDescription(
+				Locale.ContradictoryKeywords(),
+				ErrorDetails{"min": bounds.min, "max": bounds.max, "path": path},
+			)))
+		}
+	}
+}
+
+// typeKeywords lists the keywords that only apply to values of one type, "integer" counts as "number"
+var typeKeywords = []struct {
+	keyword, typ string
+}{
+	{KEY_MULTIPLE_OF, TYPE_NUMBER},
+	{KEY_MINIMUM, TYPE_NUMBER},
+	{KEY_MAXIMUM, TYPE_NUMBER},
+	{KEY_EXCLUSIVE_MINIMUM, TYPE_NUMBER},
+	{KEY_EXCLUSIVE_MAXIMUM, TYPE_NUMBER},
+	{KEY_MIN_LENGTH, TYPE_STRING},
+	{KEY_MAX_LENGTH, TYPE_STRING},
+	{KEY_PATTERN, TYPE_STRING},
+	{KEY_ITEMS, TYPE_ARRAY},
+	{KEY_ADDITIONAL_ITEMS, TYPE_ARRAY},
+	{KEY_MIN_ITEMS, TYPE_ARRAY},
+	{KEY_MAX_ITEMS, TYPE_ARRAY},
+	{KEY_UNIQUE_ITEMS, TYPE_ARRAY},
+	{KEY_CONTAINS, TYPE_ARRAY},
+	{KEY_PROPERTIES, TYPE_OBJECT},
+	{KEY_PATTERN_PROPERTIES, TYPE_OBJECT},
+	{KEY_ADDITIONAL_PROPERTIES, TYPE_OBJECT},
+	{KEY_PROPERTY_NAMES, TYPE_OBJECT},
+	{KEY_REQUIRED, TYPE_OBJECT},
+	{KEY_MIN_PROPERTIES, TYPE_OBJECT},
+	{KEY_MAX_PROPERTIES, TYPE_OBJECT},
+	{KEY_DEPENDENCIES, TYPE_OBJECT},
+}
+
+func lintIrrelevantKeywords(node map[string]interface{}, path string, errs *[]error) {
+	var types []string
+	switch t := node[KEY_TYPE].(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	if len(types) == 0 {
+		return
+	}
+
+	for _, tk := range typeKeywords {
+		if _, ok := node[tk.keyword]; !ok {
+			continue
+		}
+		applies := isStringInSlice(types, tk.typ) || (tk.typ == TYPE_NUMBER && isStringInSlice(types, TYPE_INTEGER))
+		if !applies {
+			*errs = append(*errs, errors.New(formatErrorDescription(
+				Locale.IrrelevantKeyword(),
+				ErrorDetails{"keyword": tk.keyword, "path": path, "type": strings.Join(types, ", ")},
+			)))
+		}
+	}
+}
+
+// walkSchemaDocument calls visit for every object in a schema document that is a (sub)schema, with its JSON pointer.
+// Objects are visited before their children and keys in sorted order
+func walkSchemaDocument(documentNode interface{}, path string, visit func(node map[string]interface{}, path string)) {
+	switch node := documentNode.(type) {
+	case []interface{}:
+		for i, v := range node {
+			walkSchemaDocument(v, path+"/"+strconv.Itoa(i), visit)
+		}
+
+	case map[string]interface{}:
+		visit(node, path)
+
+		for _, k := range sortedKeys(node) {
+			childPath := path + "/" + jsonPointerEscaper.Replace(k)
+			switch k {
+			// These keywords hold values instead of schemas
+			case KEY_CONST, KEY_ENUM, KEY_DEFAULT, KEY_EXAMPLES:
+			// These keywords map names to schemas, the map itself isn't a schema
+			case KEY_PROPERTIES, KEY_PATTERN_PROPERTIES, KEY_DEPENDENCIES, KEY_DEFINITIONS, KEY_DEFS:
+				if children, ok := node[k].(map[string]interface{}); ok {
+					for _, name := range sortedKeys(children) {
+						walkSchemaDocument(children[name], childPath+"/"+jsonPointerEscaper.Replace(name), visit)
+					}
+				}
+			default:
+				walkSchemaDocument(node[k], childPath, visit)
+			}
+		}
+	}
+}
+
+// walkSubSchemas calls visit for every subSchema of the compiled schema s, with the JSON pointer of its node in the
+// document. Subschemas are visited before their children. References aren't followed, the schemas they point
+// to are visited where they appear, such as in "definitions"
+func walkSubSchemas(s *subSchema, path string, visit func(s *subSchema, path string)) {
+	visit(s, path)
+
+	child := func(c *subSchema) {
+		childPath := path
+		for _, segment := range c.relativeLocation() {
+			childPath += "/" + jsonPointerEscaper.Replace(fmt.Sprint(segment))
+		}
+		walkSubSchemas(c, childPath, visit)
+	}
+
+	for _, c := range s.definitionsChildren {
+		child(c)
+	}
+	for _, c := range s.propertiesChildren {
+		child(c)
+	}
+	for _, pattern := range sortedPatterns(s.patternProperties) {
+		child(s.patternProperties[pattern])
+	}
+	for _, property := range sortedKeys(s.dependencies) {
+		if c, ok := s.dependencies[property].(*subSchema); ok {
+			child(c)
+		}
+	}
+	for _, c := range s.itemsChildren {
+		child(c)
+	}
+	for _, keyword := range []interface{}{s.additionalProperties, s.additionalItems} {
+		if c, ok := keyword.(*subSchema); ok {
+			child(c)
+		}
+	}
+	for _, group := range [][]*subSchema{
+		{s.propertyNames, s.contains, s.unevaluatedItems, s.not, s._if, s._then, s._else}, s.allOf, s.anyOf, s.oneOf,
+	} {
+		for _, c := range group {
+			if c != nil {
+				child(c)
+			}
+		}
+	}
+}
+
+func (d *Schema) lintSchemaExamples(schema *subSchema, examples []interface{}, path string, errs *[]error) {
+	for i, example := range examples {
+		result := schema.subValidateWithContext(example, NewJsonContext(STRING_CONTEXT_ROOT, nil), &validationState{schema: d})
+		if result.Valid() {
+			continue
+		}
+
+		descriptions := make([]string, 0, len(result.Errors()))
+		for _, err := range result.Errors() {
+			descriptions = append(descriptions, err.String())
+		}
+		*errs = append(*errs, errors.New(formatErrorDescription(
+			Locale.InvalidExample(),
+			ErrorDetails{"index": i, "path": path, "errors": strings.Join(descriptions, "; ")},
+		)))
+	}
+}
@@ -25,7 +25,17 @@
 
 package gojsonschema
 
+import (
+	"math/big"
+)
+
 type (
+	// NumberFormatter can be implemented by a locale to format the numbers in error descriptions according to its
+	// rules, i.e. 1000 as "1.000". Numbers are given exactly, the details of errors keep the numbers themselves
+	NumberFormatter interface {
+		FormatNumber(number *big.Float) string
+	}
+
 	// locale is an interface for defining custom error strings
 	locale interface {
 
@@ -184,6 +194,84 @@ type (
 		// ConditionElse returns a format-string for ConditionElseError errors
 		ConditionElse() string
 
+		// PropertyOrder returns a format-string for PropertyOrderError errors
+		PropertyOrder() string
+
+		// Discriminator returns a format-string for DiscriminatorError errors
+		Discriminator() string
+
+		// ExtensionPanic returns a format-string for ExtensionPanicError errors
+		ExtensionPanic() string
+
+		// InvalidJSONString returns a format-string for InvalidJSONStringError errors
+		InvalidJSONString() string
+
+		// DeprecatedKeyword returns a format-string for warnings about deprecated keywords
+		DeprecatedKeyword() string
+
+		// ValidationTimeout returns a format-string for validations exceeding their timeout
+		ValidationTimeout() string
+
+		// DuplicateKey returns a format-string for objects with duplicate keys found by a strict loader
+		DuplicateKey() string
+
+		// InvalidUTF8 returns a format-string for strings with invalid UTF-8 found by a strict UTF-8 loader
+		InvalidUTF8() string
+
+		// InvalidExample returns a format-string for examples that don't validate against their schema
+		InvalidExample() string
+
+		// ContradictoryKeywords returns a format-string for bounds that can never both be satisfied, see Schema.LintConstraints
+		ContradictoryKeywords() string
+
+		// IrrelevantKeyword returns a format-string for keywords that don't apply to the type of a schema, see Schema.LintConstraints
+		IrrelevantKeyword() string
+
+		// PathNotDescribed returns a format-string for instance paths that no subschema describes
+		PathNotDescribed() string
+
+		// MaxDepthExceeded returns a format-string for documents nested deeper than the maximum depth
+		MaxDepthExceeded() string
+
+		// InvalidJTDSchema returns a format-string for invalid JSON Type Definition schemas
+		InvalidJTDSchema() string
+
+		// UnresolvedReference returns a format-string for references that could not be resolved
+		UnresolvedReference() string
+
+		// DocumentTooLarge returns a format-string for documents larger than the maximum size
+		DocumentTooLarge() string
+
+		// CircularInstanceReference returns a format-string for documents with circular references, see SchemaLoader.ResolveInstanceRefs
+		CircularInstanceReference() string
+
+		// UnsupportedVocabulary returns a format-string for meta-schemas requiring a vocabulary that isn't supported
+		UnsupportedVocabulary() string
+
+		// RemoteReferencesDisabled returns a format-string for remote references when they are disabled, see SchemaLoader.SetRemoteRefsDisabled
+		RemoteReferencesDisabled() string
+
+		// ObjectStreamUnsupportedKeyword returns a format-string for root keywords that can't be validated by Schema.ValidateObjectStream
+		ObjectStreamUnsupportedKeyword() string
+
+		// ArrayValidatorUnsupportedKeyword returns a format-string for root keywords that can't be validated by an ArrayValidator
+		ArrayValidatorUnsupportedKeyword() string
+
+		// UnknownDialect returns a format-string for a "$schema" that is not known, see SchemaLoader.SetRejectUnknownDialects
+		UnknownDialect() string
+
+		// NoMetaSchema returns a format-string for drafts without a meta-schema, see ValidateAgainstMetaSchema
+		NoMetaSchema() string
+
+		// SchemaNotRegistered returns a format-string for ids without a schema in the registry, see ValidateBySelector
+		SchemaNotRegistered() string
+
+		// UnknownFormat returns a format-string for formats without a checker, see SchemaLoader.RejectUnknownFormats
+		UnknownFormat() string
+
+		// UnknownKeyword returns a format-string for keywords that aren't known, see SchemaLoader.RejectUnknownKeywords
+		UnknownKeyword() string
+
 		// ErrorFormat returns a format string for errors
 		ErrorFormat() string
 	}
@@ -224,7 +312,7 @@ func (l DefaultLocale) NumberAllOf() string {
 
 // NumberNot returns a format-string to format a NumberNotError
 func (l DefaultLocale) NumberNot() string {
-	return `Must not validate the schema (not)`
+	return `Must not validate the schema (not): {{.schema}}`
 }
 
 // MissingDependency returns a format-string for "missing dependency" schema validation errors
@@ -244,7 +332,7 @@ func (l DefaultLocale) Const() string {
 
 // Enum returns a format-string to format an EnumError
 func (l DefaultLocale) Enum() string {
-	return `{{.field}} must be one of the following: {{.allowed}}`
+	return `{{.field}} must be one of the following: {{.allowed}}{{if .suggestion}}, did you mean "{{.suggestion}}"?{{end}}`
 }
 
 // ArrayNoAdditionalItems returns a format-string to format an ArrayNoAdditionalItemsError
@@ -456,6 +544,136 @@ func (l DefaultLocale) ConditionElse() string {
 	return `Must validate "else" as "if" was not valid`
 }
 
+// PropertyOrder returns a format-string for PropertyOrderError errors
+func (l DefaultLocale) PropertyOrder() string {
+	return `Property {{.property}} must appear before {{.before}}`
+}
+
+// Discriminator returns a format-string for DiscriminatorError errors
+func (l DefaultLocale) Discriminator() string {
+	return `{{.property}} must be one of {{.allowed}}, given {{.value}}`
+}
+
+// ExtensionPanic returns a format-string for ExtensionPanicError errors
+func (l DefaultLocale) ExtensionPanic() string {
+	return `{{.extension}} panicked: {{.panic}}`
+}
+
+// InvalidJSONString returns a format-string for InvalidJSONStringError errors
+func (l DefaultLocale) InvalidJSONString() string {
+	return `Must be a string holding valid JSON: {{.error}}`
+}
+
+// DeprecatedKeyword returns a format-string for warnings about deprecated keywords
+func (l DefaultLocale) DeprecatedKeyword() string {
+	return `{{.keyword}} is deprecated, use {{.replacement}} instead`
+}
+
+// ValidationTimeout returns a format-string for validations exceeding their timeout
+func (l DefaultLocale) ValidationTimeout() string {
+	return `Validation did not finish within {{.timeout}}`
+}
+
+// DuplicateKey returns a format-string for objects with duplicate keys found by a strict loader
+func (l DefaultLocale) DuplicateKey() string {
+	return `Duplicate key "{{.key}}" in {{.context}}`
+}
+
+// InvalidUTF8 returns a format-string for strings with invalid UTF-8 found by a strict UTF-8 loader
+func (l DefaultLocale) InvalidUTF8() string {
+	return `String at offset {{.offset}} contains invalid UTF-8 or an unpaired surrogate`
+}
+
+// InvalidExample returns a format-string for examples that don't validate against their schema
+func (l DefaultLocale) InvalidExample() string {
+	return `Example {{.index}} at {{.path}} is invalid: {{.errors}}`
+}
+
+// ContradictoryKeywords returns a format-string for bounds that can never both be satisfied, see Schema.LintConstraints
+func (l DefaultLocale) ContradictoryKeywords() string {
+	return `{{.min}} and {{.max}} at {{.path}} can never both be satisfied`
+}
+
+// IrrelevantKeyword returns a format-string for keywords that don't apply to the type of a schema, see Schema.LintConstraints
+func (l DefaultLocale) IrrelevantKeyword() string {
+	return `{{.keyword}} at {{.path}} has no effect for type {{.type}}`
+}
+
+// PathNotDescribed returns a format-string for instance paths that no subschema describes
+func (l DefaultLocale) PathNotDescribed() string {
+	return `The schema does not describe {{.path}}`
+}
+
+// MaxDepthExceeded returns a format-string for documents nested deeper than the maximum depth
+func (l DefaultLocale) MaxDepthExceeded() string {
+	return `Document is nested deeper than the maximum depth of {{.max}} at {{.context}}`
+}
+
+// InvalidJTDSchema returns a format-string for invalid JSON Type Definition schemas
+func (l DefaultLocale) InvalidJTDSchema() string {
+	return `Invalid JSON Type Definition at {{.path}}: {{.reason}}`
+}
+
+// UnresolvedReference returns a format-string for references that could not be resolved
+func (l DefaultLocale) UnresolvedReference() string {
+	return `Reference {{.reference}} could not be resolved`
+}
+
+// DocumentTooLarge returns a format-string for documents larger than the maximum size
+func (l DefaultLocale) DocumentTooLarge() string {
+	return `Document is larger than the maximum size of {{.max}} bytes`
+}
+
+// CircularInstanceReference returns a format-string for documents with circular references, see SchemaLoader.ResolveInstanceRefs
+func (l DefaultLocale) CircularInstanceReference() string {
+	return `Circular reference {{.reference}} in the document`
+}
+
+// UnsupportedVocabulary returns a format-string for meta-schemas requiring a vocabulary that isn't supported
+func (l DefaultLocale) UnsupportedVocabulary() string {
+	return `Vocabulary {{.vocabulary}} is required by the meta-schema but not supported`
+}
+
+// RemoteReferencesDisabled returns a format-string for remote references when they are disabled, see SchemaLoader.SetRemoteRefsDisabled
+func (l DefaultLocale) RemoteReferencesDisabled() string {
+	return `Reference {{.reference}} can't be loaded as remote references are disabled`
+}
+
+// ObjectStreamUnsupportedKeyword returns a format-string for root keywords that can't be validated by Schema.ValidateObjectStream
+func (l DefaultLocale) ObjectStreamUnsupportedKeyword() string {
+	return `Keyword {{.keyword}} of the root schema can't be validated while streaming an object`
+}
+
+// ArrayValidatorUnsupportedKeyword returns a format-string for root keywords that can't be validated by an ArrayValidator
+func (l DefaultLocale) ArrayValidatorUnsupportedKeyword() string {
+	return `Keyword {{.keyword}} of the root schema can't be validated one item at a time`
+}
+
+// UnknownDialect returns a format-string for a "$schema" that is not known, see SchemaLoader.SetRejectUnknownDialects
+func (l DefaultLocale) UnknownDialect() string {
+	return `$schema {{.schema}} is not a known dialect`
+}
+
+// NoMetaSchema returns a format-string for drafts without a meta-schema, see ValidateAgainstMetaSchema
+func (l DefaultLocale) NoMetaSchema() string {
+	return `Draft {{.draft}} has no meta-schema`
+}
+
+// SchemaNotRegistered returns a format-string for ids without a schema in the registry, see ValidateBySelector
+func (l DefaultLocale) SchemaNotRegistered() string {
+	return `No schema is registered for {{.id}}`
+}
+
+// UnknownFormat returns a format-string for formats without a checker, see SchemaLoader.RejectUnknownFormats
+func (l DefaultLocale) UnknownFormat() string {
+	return `No checker is registered for format {{.format}}`
+}
+
+// UnknownKeyword returns a format-string for keywords that aren't known, see SchemaLoader.RejectUnknownKeywords
+func (l DefaultLocale) UnknownKeyword() string {
+	return `Unknown keyword {{.keyword}}`
+}
+
 // constants
 const (
 	STRING_NUMBER                     = "number"
@@ -466,6 +684,7 @@ const (
 	STRING_PROPERTIES                 = "properties"
 	STRING_DEPENDENCY                 = "dependency"
 	STRING_PROPERTY                   = "property"
+	STRING_BUNDLE                     = "bundle"
 	STRING_UNDEFINED                  = "undefined"
 	STRING_CONTEXT_ROOT               = "(root)"
 	STRING_ROOT_SCHEMA_PROPERTY       = "(root)"
@@ -0,0 +1,280 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaMetadata holds the annotations describing a (sub)schema
+type SchemaMetadata struct {
+	Title       string
+	Description string
+	// Comment holds "$comment", which is meant for schema authors and tools and never affects validation
+	Comment string
+}
+
+// Metadata returns the title and description of the schema of the property at the given path,
+// i.e. Metadata("address", "street"). Without a path the metadata of the root schema is returned.
+// ok is false when there is no schema for the path.
+// When SchemaLoader.ResolveMetadataRefs is set, a property schema without a title or description of its own
+// reports those of the schema it references
+func (d *Schema) Metadata(path ...string) (metadata SchemaMetadata, ok bool) {
+	current := d.rootSchema
+	for _, property := range path {
+		current = findProperty(resolveRefSchema(current), property)
+		if current == nil {
+			return SchemaMetadata{}, false
+		}
+	}
+
+	title, description := current.title, current.description
+	if d.resolveMetadataRefs && current.refSchema != nil {
+		// Only the first title and description along the chain of references is reported
+		visited := map[*subSchema]bool{}
+		for s := current.refSchema; s != nil && !visited[s]; s = s.refSchema {
+			visited[s] = true
+			if title == nil {
+				title = s.title
+			}
+			if description == nil {
+				description = s.description
+			}
+		}
+	}
+
+	if title != nil {
+		metadata.Title = *title
+	}
+	if description != nil {
+		metadata.Description = *description
+	}
+	if current.comment != nil {
+		metadata.Comment = *current.comment
+	}
+	return metadata, true
+}
+
+// Comments returns the "$comment" of every subschema of the schema document, keyed by the JSON pointer of the subschema,
+// i.e. "#/properties/age". Comments in referenced documents are not included
+func (d *Schema) Comments() map[string]string {
+	comments := make(map[string]string)
+	walkSchemaDocument(d.rootSchema.documentNode, "#", func(node map[string]interface{}, path string) {
+		if comment, ok := node[KEY_COMMENT].(string); ok {
+			comments[path] = comment
+		}
+	})
+	return comments
+}
+
+// AllowedValues returns the values allowed by "enum" or "const" of every subschema of the schema document that has
+// either, keyed by the JSON pointer of the subschema, i.e. "#/properties/color". The values are decoded JSON, with
+// numbers as json.Number. "const" takes precedence over "enum" as it allows a single value only.
+// Only the subschemas that were compiled are included, so values in referenced documents and in unknown keywords are not
+func (d *Schema) AllowedValues() map[string][]interface{} {
+	values := make(map[string][]interface{})
+	walkSubSchemas(d.rootSchema, "#", func(s *subSchema, path string) {
+		if s._const != nil {
+			values[path] = []interface{}{s.documentNode.(map[string]interface{})[KEY_CONST]}
+		} else if s.enumValues != nil {
+			values[path] = s.enumValues
+		}
+	})
+	return values
+}
+
+// SchemaForPath returns the subschema that describes the value at the given path in a document,
+// i.e. "address.zip" or "tags.0". References are resolved, so the returned subschema is never just a "$ref".
+// Objects are walked through "properties", "patternProperties" and "additionalProperties" and arrays
+// through "items" and "additionalItems". The index "*" stands for any item of an array that has a single "items" schema.
+// An empty path returns the root schema
+func (d *Schema) SchemaForPath(path string) (interface{}, error) {
+	current, err := d.schemaForPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return current.documentNode, nil
+}
+
+func (d *Schema) schemaForPath(path string) (*subSchema, error) {
+	current := resolveRefSchema(d.rootSchema)
+	if path != "" {
+		for _, segment := range strings.Split(path, ".") {
+			current = findSchemaForSegment(current, segment)
+			if current == nil {
+				return nil, errors.New(formatErrorDescription(
+					Locale.PathNotDescribed(),
+					ErrorDetails{"path": path},
+				))
+			}
+			current = resolveRefSchema(current)
+		}
+	}
+	return current, nil
+}
+
+// InstancePaths returns the paths of all values in a document that the schema describes, sorted, i.e. "address.zip" or
+// "tags[*].name". Array items are written as "[0]" or "[*]" for any item, and the property "*" stands for any property
+// described by "patternProperties" or "additionalProperties". Subschemas of "allOf", "anyOf", "oneOf", "if", "then",
+// "else" and "dependencies" describe the same value, so their paths are included as well. Where a schema refers back to
+// one it is part of, the paths below repeat endlessly, so they are reported as "**" instead, i.e. "children[*].**"
+func (d *Schema) InstancePaths() []string {
+	found := make(map[string]bool)
+	collectInstancePaths(d.rootSchema, "", make(map[*subSchema]bool), found)
+
+	paths := make([]string, 0, len(found))
+	for path := range found {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// collectInstancePaths adds the paths below path described by s to found. walking holds the schemas that
+// are being walked at path or its parents, reaching one of them again means the schema is recursive
+func collectInstancePaths(s *subSchema, path string, walking map[*subSchema]bool, found map[string]bool) {
+	s = resolveRefSchema(s)
+	if walking[s] {
+		found[joinInstancePath(path, "**")] = true
+		return
+	}
+	walking[s] = true
+	defer delete(walking, s)
+
+	child := func(childPath string, c *subSchema) {
+		found[childPath] = true
+		collectInstancePaths(c, childPath, walking, found)
+	}
+	item := func(index string, c *subSchema) {
+		child(path+"["+index+"]", c)
+	}
+
+	for _, c := range s.propertiesChildren {
+		child(joinInstancePath(path, c.property), c)
+	}
+	for _, pattern := range sortedPatterns(s.patternProperties) {
+		child(joinInstancePath(path, "*"), s.patternProperties[pattern])
+	}
+	if c, ok := s.additionalProperties.(*subSchema); ok {
+		child(joinInstancePath(path, "*"), c)
+	}
+
+	if s.itemsChildrenIsSingleSchema {
+		item("*", s.itemsChildren[0])
+	} else {
+		for i, c := range s.itemsChildren {
+			item(strconv.Itoa(i), c)
+		}
+		if c, ok := s.additionalItems.(*subSchema); ok && len(s.itemsChildren) > 0 {
+			item("*", c)
+		}
+	}
+	if s.contains != nil {
+		item("*", s.contains)
+	}
+	if s.unevaluatedItems != nil {
+		item("*", s.unevaluatedItems)
+	}
+
+	for _, group := range [][]*subSchema{s.allOf, s.anyOf, s.oneOf, {s._if, s._then, s._else}} {
+		for _, c := range group {
+			if c != nil {
+				collectInstancePaths(c, path, walking, found)
+			}
+		}
+	}
+	for _, property := range sortedKeys(s.dependencies) {
+		if c, ok := s.dependencies[property].(*subSchema); ok {
+			collectInstancePaths(c, path, walking, found)
+		}
+	}
+}
+
+func joinInstancePath(path string, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "." + segment
+}
+
+// PropertyNames returns the names of the properties declared in "properties" of the subschema that describes
+// the value at the given path, see SchemaForPath. The names are in the order they are declared in for schemas
+// compiled from text, such as with NewStringLoader, and sorted otherwise
+func (d *Schema) PropertyNames(path string) ([]string, error) {
+	current, err := d.schemaForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(current.propertiesChildren))
+	for _, child := range current.propertiesChildren {
+		names = append(names, child.property)
+	}
+	return names, nil
+}
+
+// findSchemaForSegment returns the subschema that describes the property or item named segment, or nil
+func findSchemaForSegment(s *subSchema, segment string) *subSchema {
+	if child := findProperty(s, segment); child != nil {
+		return child
+	}
+	for _, pattern := range sortedPatterns(s.patternProperties) {
+		if s.patternPropertiesRegexps[pattern].MatchString(segment) {
+			return s.patternProperties[pattern]
+		}
+	}
+	if additional, ok := s.additionalProperties.(*subSchema); ok {
+		return additional
+	}
+
+	if s.itemsChildrenIsSingleSchema && segment == "*" {
+		return s.itemsChildren[0]
+	}
+	if index, err := strconv.Atoi(segment); err == nil && index >= 0 {
+		if s.itemsChildrenIsSingleSchema {
+			return s.itemsChildren[0]
+		}
+		if index < len(s.itemsChildren) {
+			return s.itemsChildren[index]
+		}
+		if additional, ok := s.additionalItems.(*subSchema); ok && len(s.itemsChildren) > 0 {
+			return additional
+		}
+	}
+	return nil
+}
+
+func sortedPatterns(m map[string]*subSchema) []string {
+	patterns := make([]string, 0, len(m))
+	for pattern := range m {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	return patterns
+}
+
+// resolveRefSchema follows "$ref" until it reaches a schema that isn't just a reference.
+// Circular references resolve to the last schema before the cycle repeats
+func resolveRefSchema(s *subSchema) *subSchema {
+	visited := map[*subSchema]bool{}
+	for s.refSchema != nil && !visited[s.refSchema] {
+		visited[s] = true
+		s = s.refSchema
+	}
+	return s
+}
@@ -0,0 +1,220 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ValidateObjectStream validates a document holding a JSON object without loading the whole object into memory.
+// The top-level properties are decoded and validated one at a time, so only the largest property has to fit in memory.
+//
+// The root schema may only use keywords that can be checked one property at a time or from the keys alone:
+// "type", "properties", "patternProperties", "additionalProperties", "propertyNames", "required",
+// "minProperties", "maxProperties" and "dependencies" listing properties. Other keywords that apply to
+// objects, like "allOf" or "enum", result in an error before anything is read. Nested schemas aren't restricted.
+// Documents that aren't objects are decoded and validated as a whole. Validation hooks aren't called
+func (v *Schema) ValidateObjectStream(r io.Reader) (*Result, error) {
+	root := resolveRefSchema(v.rootSchema)
+	if keyword := unstreamableKeyword(root); keyword != "" {
+		return nil, errors.New(formatErrorDescription(
+			Locale.ObjectStreamUnsupportedKeyword(),
+			ErrorDetails{"keyword": keyword},
+		))
+	}
+
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	state := &validationState{}
+	if token != json.Delim('{') || root.pass != nil {
+		document, err := decodeRemainder(decoder, token)
+		if err != nil {
+			return nil, err
+		}
+		return v.validateDocumentWithState(document, state)
+	}
+
+	state.schema = v
+	if v.traceEvaluatedKeywords {
+		state.evaluatedKeywords = make(map[string][]string)
+	}
+	result := &Result{state: state}
+	context := NewJsonContext(STRING_CONTEXT_ROOT, nil)
+
+	if root.types.IsTyped() && !root.types.Contains(TYPE_OBJECT) {
+		result.addInternalError(
+			new(InvalidTypeError),
+			context,
+			nil,
+			ErrorDetails{
+				"expected": root.types.String(),
+				"given":    TYPE_OBJECT,
+			},
+		)
+		return result, nil
+	}
+
+	// Every property is validated as an object on its own, without the keywords that depend on the other properties
+	perProperty := *root
+	perProperty.minProperties = nil
+	perProperty.maxProperties = nil
+	perProperty.required = nil
+	perProperty.dependencies = nil
+
+	keys := make(map[string]interface{})
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if v.emptyStringAsAbsent {
+			if value == "" {
+				continue
+			}
+			value = removeEmptyStrings(value, nil)
+		}
+		keys[key] = nil
+
+		root.validateObject(&perProperty, map[string]interface{}{key: value}, result, context)
+		if child := findProperty(root, key); child != nil {
+			root.validateRecursive(child, value, result, NewJsonContext(key, context))
+		}
+		if state.tooDeep != nil {
+			break
+		}
+	}
+	if state.tooDeep == nil {
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	// The remaining keywords only need to know which properties are present
+	whole := &subSchema{
+		draft:         root.draft,
+		minProperties: root.minProperties,
+		maxProperties: root.maxProperties,
+		required:      root.required,
+		dependencies:  root.dependencies,
+	}
+	root.validateObject(whole, keys, result, context)
+	root.validateSchema(whole, keys, result, context)
+
+	if state.tooDeep != nil {
+		return nil, errors.New(formatErrorDescription(
+			state.locale().MaxDepthExceeded(),
+			ErrorDetails{"max": v.maxDepth, "context": state.tooDeep.String()},
+		))
+	}
+	rootURI := v.documentReference
+	if v.rootSchema.id != nil {
+		rootURI = *v.rootSchema.id
+	}
+	result.setSchemaURI(0, rootURI.String())
+	if v.maxErrorsPerField > 0 {
+		result.limitErrorsPerField(v.maxErrorsPerField)
+	}
+	return result, nil
+}
+
+// unstreamableKeyword returns a keyword of s that needs the whole object to be validated, if any
+func unstreamableKeyword(s *subSchema) string {
+	if keyword := wholeValueKeyword(s); keyword != "" {
+		return keyword
+	}
+	switch {
+	case s.propertyOrder != nil:
+		return KEY_PROPERTY_ORDER
+	case s.discriminator != nil:
+		return KEY_DISCRIMINATOR
+	}
+	for _, dependency := range s.dependencies {
+		if _, ok := dependency.(*subSchema); ok {
+			return KEY_DEPENDENCIES
+		}
+	}
+	return ""
+}
+
+// wholeValueKeyword returns a keyword of s that applies to a value as a whole, so it can't be checked
+// one property or item at a time, if any
+func wholeValueKeyword(s *subSchema) string {
+	switch {
+	case s.unresolvedRef:
+		return KEY_REF
+	case len(s.allOf) > 0:
+		return KEY_ALL_OF
+	case len(s.anyOf) > 0:
+		return KEY_ANY_OF
+	case len(s.oneOf) > 0:
+		return KEY_ONE_OF
+	case s.not != nil:
+		return KEY_NOT
+	case s._if != nil:
+		return KEY_IF
+	case s.enum != nil:
+		return KEY_ENUM
+	case s._const != nil:
+		return KEY_CONST
+	case len(s.customKeywords) > 0:
+		return s.customKeywords[0].name
+	}
+	return ""
+}
+
+// decodeRemainder decodes the rest of the value that starts with token
+func decodeRemainder(decoder *json.Decoder, token json.Token) (interface{}, error) {
+	switch token {
+	case json.Delim('['):
+		array := []interface{}{}
+		for decoder.More() {
+			var item interface{}
+			if err := decoder.Decode(&item); err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		_, err := decoder.Token()
+		return array, err
+	case json.Delim('{'):
+		object := map[string]interface{}{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				return nil, err
+			}
+			object[key.(string)] = value
+		}
+		_, err := decoder.Token()
+		return object, err
+	}
+	return token, nil
+}
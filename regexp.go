@@ -0,0 +1,72 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"regexp"
+	"sync"
+)
+
+// RegexpEngine compiles the regular expressions used by the "pattern" and "patternProperties" keywords.
+// It can be set on a SchemaLoader to use a different regular expression dialect than Go's RE2,
+// for example one that supports all of ECMA-262
+type RegexpEngine interface {
+	Compile(pattern string) (Regexp, error)
+}
+
+// Regexp is a compiled regular expression as returned by a RegexpEngine
+type Regexp interface {
+	// MatchString reports whether s contains any match of the regular expression.
+	// Patterns in JSON Schema are not anchored, so a match of only part of s counts as well
+	MatchString(s string) bool
+}
+
+// re2Engine is the default RegexpEngine, backed by the regexp package of the standard library
+type re2Engine struct{}
+
+func (re2Engine) Compile(pattern string) (Regexp, error) {
+	return regexp.Compile(pattern)
+}
+
+// NewCachingRegexpEngine returns a RegexpEngine that compiles every pattern only once using engine, or Go's RE2 when
+// engine is nil, and returns the same compiled Regexp for that pattern afterwards. Setting it on several SchemaLoaders
+// shares the compiled patterns between all their schemas. Patterns that fail to compile are not cached.
+// It is safe for concurrent use, compiled patterns are kept for as long as the engine is
+func NewCachingRegexpEngine(engine RegexpEngine) RegexpEngine {
+	if engine == nil {
+		engine = re2Engine{}
+	}
+	return &cachingRegexpEngine{engine: engine}
+}
+
+// cachingRegexpEngine is the RegexpEngine returned by NewCachingRegexpEngine
+type cachingRegexpEngine struct {
+	engine RegexpEngine
+	// Compiled patterns, from pattern to Regexp
+	compiled sync.Map
+}
+
+func (e *cachingRegexpEngine) Compile(pattern string) (Regexp, error) {
+	if compiled, ok := e.compiled.Load(pattern); ok {
+		return compiled.(Regexp), nil
+	}
+	compiled, err := e.engine.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	// Another goroutine may have compiled the same pattern in the meantime, only one of the results is kept
+	actual, _ := e.compiled.LoadOrStore(pattern, compiled)
+	return actual.(Regexp), nil
+}
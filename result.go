@@ -27,9 +27,14 @@ package gojsonschema
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
+// FormatResultKeyword is the keyword of the annotations holding a FormatResult, see SchemaLoader.ReportFormatResults.
+// It differs from "format", so the annotations of that keyword keep holding the name of the format
+const FormatResultKeyword = "formatResult"
+
 type (
 	// ErrorDetails is a map of details specific to each error.
 	// While the values will vary, every error will contain a "field" value
@@ -40,6 +45,12 @@ type (
 		// Field returns the field name without the root context
 		// i.e. firstName or person.firstName instead of (root).firstName or (root).person.firstName
 		Field() string
+		// FieldPointer returns the location of the field as a JSON Pointer, i.e. /person/firstName.
+		// Unlike Field it is unambiguous for property names that contain dots
+		FieldPointer() string
+		// Path returns the location of the field as segments, a string for every object key and an int for every array index.
+		// Unlike Field it is unambiguous regardless of the contents of the keys
+		Path() []interface{}
 		// SetType sets the error-type
 		SetType(string)
 		// Type returns the error-type
@@ -64,6 +75,18 @@ type (
 		SetDetails(ErrorDetails)
 		// Details returns details about the error
 		Details() ErrorDetails
+		// SetSchemaURI sets the URI of the schema the error originates from
+		SetSchemaURI(string)
+		// SchemaURI returns the URI of the schema the error originates from
+		SchemaURI() string
+		// Severity returns the severity of the error, see SchemaLoader.Severities
+		Severity() Severity
+		// SchemaSnippet returns the JSON text of the schema keyword the error originates from, i.e. "minimum" : 0.
+		// It is only available for schemas compiled from text, such as with NewStringLoader, with SchemaLoader.SchemaSnippets set
+		SchemaSnippet() string
+		// Draft returns the draft the subschema the error originates from is interpreted as,
+		// i.e. Draft6 for errors from a draft-06 schema referenced by a draft-07 schema
+		Draft() Draft
 		// String returns a string representation of the error
 		String() string
 	}
@@ -78,23 +101,117 @@ type (
 		descriptionFormat string       // A format for human readable error message
 		value             interface{}  // Value given by the JSON file that is the source of the error
 		details           ErrorDetails
+		schemaURI         string // URI of the (referenced) schema the error originates from
+		severity          Severity
+		// The subSchemas validated from the root schema down to the one the error originates from,
+		// the keyword, schema path, draft and snippet of the error are derived from them when asked for
+		origin *errorOrigin
+	}
+
+	// Severity is how serious a ResultError is, see SchemaLoader.Severities
+	Severity int
+
+	// Annotation holds information collected during validation that doesn't affect the validity of the document
+	Annotation struct {
+		// Context is the location in the document the annotation applies to
+		Context *JsonContext
+		// Keyword is the keyword that produced the annotation
+		Keyword string
+		// Value holds the annotation itself, its type depends on the keyword
+		Value interface{}
+	}
+
+	// Rejection is a value of the document that failed a constraint, see Result.Rejections
+	Rejection struct {
+		// Path is the location of the value in the document, in the notation of ResultError.Field
+		Path string
+		// Value is the value that was rejected
+		Value interface{}
+		// Type is the type of the error the value was rejected with, see ResultError.Type
+		Type string
+	}
+
+	// PythonStyleError is an error in the shape of the ValidationError of Python's jsonschema package, see Result.PythonStyleErrors
+	PythonStyleError struct {
+		// Message is the description of the error
+		Message string `json:"message"`
+		// Path holds the segments of the location of the value in the document, see ResultError.Path
+		Path []interface{} `json:"path"`
+		// SchemaPath holds the keywords leading from the root schema to the keyword that failed,
+		// i.e. ["properties", "age", "minimum"]
+		SchemaPath []interface{} `json:"schema_path"`
+		// Validator is the keyword that failed, i.e. "minimum"
+		Validator string `json:"validator"`
+		// ValidatorValue is the value of the keyword in the schema
+		ValidatorValue interface{} `json:"validator_value"`
+	}
+
+	// NormalizedValue is the value of the annotations for the canonical form of formatted values,
+	// see SchemaLoader.NormalizeFormats. The keyword of these annotations is "format"
+	NormalizedValue struct {
+		// Format is the format of the value, i.e. "date-time"
+		Format string
+		// Value is the canonical form of the value
+		Value interface{}
+	}
+
+	// FormatResult is the value of the annotations for the outcome of "format" checks, see SchemaLoader.ReportFormatResults.
+	// The keyword of these annotations is FormatResultKeyword
+	FormatResult struct {
+		// Format is the format that was checked, i.e. "email"
+		Format string
+		// Valid reports whether the value has the format
+		Valid bool
 	}
 
 	// Result holds the result of a validation
 	Result struct {
-		errors []ResultError
+		errors      []ResultError
+		annotations []Annotation
+		// The items of the arrays at each context that were evaluated by a keyword,
+		// only tracked for schemas that use unevaluatedItems
+		evaluatedItems map[*JsonContext][]bool
 		// Scores how well the validation matched. Useful in generating
 		// better error messages for anyOf and oneOf.
 		score int
+		// State of the validation run this result belongs to
+		state *validationState
 	}
 )
 
+const (
+	// SeverityError is the severity of errors unless configured otherwise, they make a document invalid
+	SeverityError Severity = iota
+	// SeverityWarning is the severity of errors that are reported, but don't make a document invalid
+	SeverityWarning
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
 // Field returns the field name without the root context
 // i.e. firstName or person.firstName instead of (root).firstName or (root).person.firstName
 func (v *ResultErrorFields) Field() string {
 	return strings.TrimPrefix(v.context.String(), STRING_ROOT_SCHEMA_PROPERTY+".")
 }
 
+// FieldPointer returns the location of the field as a JSON Pointer, i.e. /person/firstName.
+// Unlike Field it is unambiguous for property names that contain dots
+func (v *ResultErrorFields) FieldPointer() string {
+	return v.context.Pointer()
+}
+
+// Path returns the location of the field as segments, a string for every object key and an int for every array index.
+// Unlike Field it is unambiguous regardless of the contents of the keys
+func (v *ResultErrorFields) Path() []interface{} {
+	return v.context.Path()
+}
+
 // SetType sets the error-type
 func (v *ResultErrorFields) SetType(errorType string) {
 	v.errorType = errorType
@@ -107,6 +224,7 @@ func (v *ResultErrorFields) Type() string {
 
 // SetContext sets the JSON-context for the error
 func (v *ResultErrorFields) SetContext(context *JsonContext) {
+	context.retain()
 	v.context = context
 }
 
@@ -155,6 +273,80 @@ func (v *ResultErrorFields) Details() ErrorDetails {
 	return v.details
 }
 
+// SetSchemaURI sets the URI of the schema the error originates from
+func (v *ResultErrorFields) SetSchemaURI(uri string) {
+	v.schemaURI = uri
+}
+
+// Severity returns the severity of the error, see SchemaLoader.Severities
+func (v *ResultErrorFields) Severity() Severity {
+	return v.severity
+}
+
+func (v *ResultErrorFields) setSeverity(severity Severity) {
+	v.severity = severity
+}
+
+// SchemaSnippet returns the JSON text of the schema keyword the error originates from, i.e. "minimum" : 0.
+// It is only available for schemas compiled from text, such as with NewStringLoader, with SchemaLoader.SchemaSnippets set
+func (v *ResultErrorFields) SchemaSnippet() string {
+	if v.origin == nil || v.origin.source == nil || len(v.origin.path) == 0 {
+		return ""
+	}
+	node := v.origin.path[len(v.origin.path)-1].documentNode
+	return v.origin.source.snippet(node, errorKeyword(v.errorType, node))
+}
+
+// setOrigin records the subSchemas the error was found in, unless it was found in a subSchema nested deeper before
+func (v *ResultErrorFields) setOrigin(origin *errorOrigin) {
+	if v.origin == nil {
+		v.origin = origin
+	}
+}
+
+// Draft returns the draft the subschema the error originates from is interpreted as
+func (v *ResultErrorFields) Draft() Draft {
+	if v.origin == nil {
+		return 0
+	}
+	for i := len(v.origin.path) - 1; i >= 0; i-- {
+		if draft := v.origin.path[i].draft; draft != nil {
+			return *draft
+		}
+	}
+	return 0
+}
+
+// pythonStyle returns the error in the shape of the ValidationError of Python's jsonschema
+func (v *ResultErrorFields) pythonStyle() PythonStyleError {
+	python := PythonStyleError{
+		Message:    v.description,
+		Path:       v.context.Path(),
+		SchemaPath: []interface{}{},
+	}
+	if v.origin == nil || len(v.origin.path) == 0 {
+		return python
+	}
+
+	for _, s := range v.origin.path {
+		python.SchemaPath = append(python.SchemaPath, s.relativeLocation()...)
+	}
+	node := v.origin.path[len(v.origin.path)-1].documentNode
+	if python.Validator = errorKeyword(v.errorType, node); python.Validator != "" {
+		python.SchemaPath = append(python.SchemaPath, python.Validator)
+		if m, ok := node.(map[string]interface{}); ok {
+			python.ValidatorValue = m[python.Validator]
+		}
+	}
+	return python
+}
+
+// SchemaURI returns the URI of the schema the error originates from.
+// For errors found while validating against a "$ref" this is the URI of the referenced schema
+func (v *ResultErrorFields) SchemaURI() string {
+	return v.schemaURI
+}
+
 // String returns a string representation of the error
 func (v ResultErrorFields) String() string {
 	// as a fallback, the value is displayed go style
@@ -181,9 +373,19 @@ func (v ResultErrorFields) String() string {
 	})
 }
 
-// Valid indicates if no errors were found
+// Error returns the same as String, so errors can be used as an error value, see Result.Err
+func (v ResultErrorFields) Error() string {
+	return v.String()
+}
+
+// Valid indicates if no errors were found. Errors with SeverityWarning don't count, see SchemaLoader.Severities
 func (v *Result) Valid() bool {
-	return len(v.errors) == 0
+	for _, err := range v.errors {
+		if err.Severity() == SeverityError {
+			return false
+		}
+	}
+	return true
 }
 
 // Errors returns the errors that were found
@@ -191,6 +393,167 @@ func (v *Result) Errors() []ResultError {
 	return v.errors
 }
 
+// Annotations returns the annotations that were collected
+func (v *Result) Annotations() []Annotation {
+	return v.annotations
+}
+
+// EvaluatedKeywords returns the locations of the keywords that were evaluated against the value at the given JSON Pointer,
+// i.e. "/age". A location is the path to the keyword from the root schema as a JSON Pointer, i.e. "/properties/age/minimum",
+// running through the "$ref" keywords that led to it like the keywordLocation of the standard output format.
+// Keywords are only recorded when the schema was compiled with SchemaLoader.TraceEvaluatedKeywords.
+// The keywords of subschemas that were tried but didn't match, such as those of "anyOf", are included
+func (v *Result) EvaluatedKeywords(pointer string) []string {
+	if v.state == nil {
+		return nil
+	}
+	return v.state.evaluatedKeywords[pointer]
+}
+
+// Err returns nil if the document is valid, otherwise a *ValidationError holding the errors that make it invalid.
+// Warnings are left out, see SchemaLoader.Severities
+func (v *Result) Err() error {
+	var errs []ResultError
+	for _, err := range v.errors {
+		if err.Severity() == SeverityError {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// ValidationError is the error returned by Result.Err for an invalid document. The errors it holds can be
+// reached with errors.As, i.e. to a *RequiredError
+type ValidationError struct {
+	Errors []ResultError
+}
+
+// Error returns the first error and the number of other errors
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].String()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e.Errors[0].String(), len(e.Errors)-1)
+}
+
+// Unwrap returns the errors, custom ResultError types that don't implement error are left out
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, resultErr := range e.Errors {
+		if err, ok := resultErr.(error); ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// FilterByType returns the errors of the given types, see ResultError.Type, in the order they were found
+func (v *Result) FilterByType(types ...string) []ResultError {
+	var filtered []ResultError
+	for _, err := range v.errors {
+		if isStringInSlice(types, err.Type()) {
+			filtered = append(filtered, err)
+		}
+	}
+	return filtered
+}
+
+// Rejections returns the values that were rejected, one for every path and error type, in the order they were found.
+// Warnings don't count, see SchemaLoader.Severities. This is convenient for collecting invalid values, i.e. for a test corpus
+func (v *Result) Rejections() []Rejection {
+	var rejections []Rejection
+	seen := make(map[[2]string]bool)
+	for _, err := range v.errors {
+		key := [2]string{err.Field(), err.Type()}
+		if err.Severity() != SeverityError || seen[key] {
+			continue
+		}
+		seen[key] = true
+		rejections = append(rejections, Rejection{Path: err.Field(), Value: err.Value(), Type: err.Type()})
+	}
+	return rejections
+}
+
+// MissingRequired returns the required properties that were missing, keyed by the context of the object
+// they are missing from, i.e. (root).address
+func (v *Result) MissingRequired() map[string][]string {
+	missing := make(map[string][]string)
+	for _, err := range v.errors {
+		if _, ok := err.(*RequiredError); !ok {
+			continue
+		}
+		context := err.Context().String()
+		property, _ := err.Details()["property"].(string)
+		if !isStringInSlice(missing[context], property) {
+			missing[context] = append(missing[context], property)
+		}
+	}
+	return missing
+}
+
+// PythonStyleErrors returns the errors in the shape of the ValidationError of Python's jsonschema package,
+// so tools that consume its errors as JSON can consume these as well
+func (v *Result) PythonStyleErrors() []PythonStyleError {
+	errs := make([]PythonStyleError, 0, len(v.errors))
+	for _, err := range v.errors {
+		if setter, ok := err.(errorOriginSetter); ok {
+			errs = append(errs, setter.pythonStyle())
+		} else {
+			errs = append(errs, PythonStyleError{
+				Message:    err.Description(),
+				Path:       err.Context().Path(),
+				SchemaPath: []interface{}{},
+			})
+		}
+	}
+	return errs
+}
+
+// Report returns a human readable summary of the result for display in a terminal. Errors are grouped by field,
+// fields are sorted and so are the descriptions of their errors, so the same result always gives the same report
+func (v *Result) Report() string {
+	var b strings.Builder
+	if v.Valid() {
+		b.WriteString("The document is valid")
+	} else {
+		b.WriteString("The document is not valid")
+	}
+	if len(v.errors) == 0 {
+		b.WriteString("\n")
+		return b.String()
+	}
+	if len(v.errors) == 1 {
+		b.WriteString(", 1 error:\n")
+	} else {
+		fmt.Fprintf(&b, ", %d errors:\n", len(v.errors))
+	}
+
+	descriptions := make(map[string][]string)
+	for _, err := range v.errors {
+		description := err.Description()
+		if err.Severity() == SeverityWarning {
+			description = "warning: " + description
+		}
+		descriptions[err.Field()] = append(descriptions[err.Field()], description)
+	}
+	fields := make([]string, 0, len(descriptions))
+	for field := range descriptions {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		fmt.Fprintf(&b, "  %s\n", field)
+		sort.Strings(descriptions[field])
+		for _, description := range descriptions[field] {
+			fmt.Fprintf(&b, "    - %s\n", description)
+		}
+	}
+	return b.String()
+}
+
 // AddError appends a fully filled error to the error set
 // SetDescription() will be called with the result of the parsed err.DescriptionFormat()
 func (v *Result) AddError(err ResultError, details ErrorDetails) {
@@ -204,15 +567,133 @@ func (v *Result) AddError(err ResultError, details ErrorDetails) {
 }
 
 func (v *Result) addInternalError(err ResultError, context *JsonContext, value interface{}, details ErrorDetails) {
-	newError(err, context, value, Locale, details)
+	if v.state.failFast {
+		// Only the fact that there is an error matters, so the same empty error is used for all
+		v.errors = append(v.errors, failFastError)
+		v.score -= 2
+		return
+	}
+	newError(err, context, value, v.state.locale(), details)
+	v.addError(err)
+}
+
+// addError adds an error that was already built, applying the severity configured for its type
+func (v *Result) addError(err ResultError) {
+	if severity, ok := v.state.schema.severities[err.Type()]; ok {
+		if setter, ok := err.(severitySetter); ok {
+			setter.setSeverity(severity)
+		}
+	}
+	if setter, ok := err.(errorOriginSetter); ok && len(v.state.evaluationPath) > 0 {
+		// The path is copied, as the validation goes on with it
+		path := append([]*subSchema(nil), v.state.evaluationPath...)
+		setter.setOrigin(&errorOrigin{path: path, source: v.state.schema.source})
+	}
 	v.errors = append(v.errors, err)
 	v.score -= 2 // results in a net -1 when added to the +1 we get at the end of the validation function
 }
 
+// limitErrorsPerField keeps at most max errors for every field, in their original order.
+// Errors with SeverityError take precedence over warnings, so a field that is invalid stays invalid
+func (v *Result) limitErrorsPerField(max int) {
+	kept := make(map[string]int)
+	keep := make([]bool, len(v.errors))
+	for _, severity := range []Severity{SeverityError, SeverityWarning} {
+		for i, err := range v.errors {
+			field := err.Context().String()
+			if err.Severity() == severity && kept[field] < max {
+				keep[i] = true
+				kept[field]++
+			}
+		}
+	}
+	errs := v.errors[:0]
+	for i, err := range v.errors {
+		if keep[i] {
+			errs = append(errs, err)
+		}
+	}
+	v.errors = errs
+}
+
+// severitySetter is implemented by errors that embed ResultErrorFields
+type severitySetter interface {
+	setSeverity(severity Severity)
+}
+
+// errorOrigin is where an error was found, see ResultErrorFields.setOrigin
+type errorOrigin struct {
+	// The subSchemas validated from the root schema down to the one with the keyword the error originates from
+	path []*subSchema
+	// The text of the schema, nil unless SchemaLoader.SchemaSnippets is set
+	source *schemaSource
+}
+
+// errorOriginSetter is implemented by errors that embed ResultErrorFields
+type errorOriginSetter interface {
+	setOrigin(origin *errorOrigin)
+	pythonStyle() PythonStyleError
+}
+
+// failFastError stands in for every error found by a fail fast validation, it must never be modified
+var failFastError ResultError = &InternalError{}
+
+func (v *Result) addAnnotation(context *JsonContext, keyword string, value interface{}) {
+	context.retain()
+	v.annotations = append(v.annotations, Annotation{Context: context, Keyword: keyword, Value: value})
+}
+
+// addNormalizedValue adds the canonical form of a value that has the given format as an annotation, if it has one
+func (v *Result) addNormalizedValue(context *JsonContext, format string, input interface{}) {
+	if normalized, ok := FormatCheckers.normalize(format, input); ok {
+		v.addAnnotation(context, KEY_FORMAT, NormalizedValue{Format: format, Value: normalized})
+	}
+}
+
 // Used to copy errors from a sub-schema to the main one
 func (v *Result) mergeErrors(otherResult *Result) {
 	v.errors = append(v.errors, otherResult.Errors()...)
 	v.score += otherResult.score
+	v.mergeAnnotations(otherResult)
+}
+
+// Used to copy annotations from a sub-schema that passed but of which the errors are not merged
+func (v *Result) mergeAnnotations(otherResult *Result) {
+	v.annotations = append(v.annotations, otherResult.annotations...)
+	for context, evaluated := range otherResult.evaluatedItems {
+		for i, ok := range evaluated {
+			if ok {
+				v.markEvaluatedItem(context, len(evaluated), i)
+			}
+		}
+	}
+}
+
+// markEvaluatedItem records that the item at index i of the array of the given length at context was evaluated
+func (v *Result) markEvaluatedItem(context *JsonContext, length int, i int) {
+	if !v.state.schema.tracksEvaluatedItems {
+		return
+	}
+	context.retain()
+	if v.evaluatedItems == nil {
+		v.evaluatedItems = map[*JsonContext][]bool{}
+	}
+	if v.evaluatedItems[context] == nil {
+		v.evaluatedItems[context] = make([]bool, length)
+	}
+	v.evaluatedItems[context][i] = true
+}
+
+// setSchemaURI attributes the errors added since the given index that haven't been attributed yet to a schema
+func (v *Result) setSchemaURI(from int, uri string) {
+	if v.state.failFast {
+		return
+	}
+	for _, err := range v.errors[from:] {
+		if err.SchemaURI() == "" {
+			err.SetSchemaURI(uri)
+		}
+	}
 }
 
 func (v *Result) incrementScore() {
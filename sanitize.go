@@ -0,0 +1,86 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+// Sanitize loads a JSON document and returns a copy of it without the properties the schema doesn't allow: those of an
+// object whose schema has "additionalProperties" : false that are described by neither "properties" nor
+// "patternProperties". It follows "properties", "patternProperties", "additionalProperties", "items",
+// "additionalItems", "allOf" and "$ref". The returned Result is that of validating the copy
+func (v *Schema) Sanitize(l JSONLoader) (interface{}, *Result, error) {
+	state := &validationState{}
+	root, err := v.loadDocument(l, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	sanitized := copyJSON(root)
+	v.rootSchema.sanitize(sanitized)
+	result, err := v.validateDocumentWithState(sanitized, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sanitized, result, nil
+}
+
+// sanitize removes the properties that aren't allowed from node and its children, node is modified in place
+func (s *subSchema) sanitize(node interface{}) {
+	if s.refSchema != nil {
+		s.refSchema.sanitize(node)
+		return
+	}
+	for _, allOfSchema := range s.allOf {
+		allOfSchema.sanitize(node)
+	}
+
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			described := false
+			for _, property := range s.propertiesChildren {
+				if property.property == key {
+					described = true
+					property.sanitize(child)
+					break
+				}
+			}
+			for pattern, patternSchema := range s.patternProperties {
+				if s.patternPropertiesRegexps[pattern].MatchString(key) {
+					described = true
+					patternSchema.sanitize(child)
+				}
+			}
+			if described {
+				continue
+			}
+			switch additional := s.additionalProperties.(type) {
+			case bool:
+				if !additional {
+					delete(value, key)
+				}
+			case *subSchema:
+				additional.sanitize(child)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			if s.itemsChildrenIsSingleSchema {
+				s.itemsChildren[0].sanitize(item)
+			} else if i < len(s.itemsChildren) {
+				s.itemsChildren[i].sanitize(item)
+			} else if additional, ok := s.additionalItems.(*subSchema); ok {
+				additional.sanitize(item)
+			}
+		}
+	}
+}
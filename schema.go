@@ -30,8 +30,11 @@ import (
 	"errors"
 	"math/big"
 	"reflect"
-	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"text/template"
+	"time"
 
 	"github.com/xeipuuv/gojsonreference"
 )
@@ -56,6 +59,106 @@ type Schema struct {
 	rootSchema        *subSchema
 	pool              *schemaPool
 	referencePool     *schemaReferencePool
+	warnings          []string
+	regexpEngine      RegexpEngine
+	registry          *SchemaRegistry
+	parseLock         sync.Mutex
+
+	reportOverlappingPatterns bool
+	resolveMetadataRefs       bool
+	validationHook            func(path string, value interface{}, ok bool)
+	locale                    locale
+	maxDepth                  int
+	assertFormats             map[string]bool // nil if all formats are asserted
+	allowUnresolvedRefs       bool
+	unresolvedRefs            []string
+	strictIntegers            bool
+	tracksEvaluatedItems      bool // whether any subschema uses unevaluatedItems
+	clock                     func() time.Time
+	source                    *schemaSource // nil if the schema wasn't compiled from text
+	resolveInstanceRefs       bool
+	emptyStringAsAbsent       bool
+	traceEvaluatedKeywords    bool
+	severities                map[string]Severity
+	maxErrorsPerField         int // 0 if not limited
+	enforcePropertyOrder      bool
+	tracksPropertyOrder       bool // whether any subschema uses propertyOrder
+	openAPIDiscriminator      bool
+	normalizeFormats          bool
+	rejectUnknownFormats      bool
+	rejectUnknownKeywords     bool
+	shortCircuitAllOf         bool
+	reportFormatResults       bool
+	unknownFormatHook         func(format string)
+	unknownFormats            map[string]bool // formats without a checker found while compiling
+}
+
+// checkFormatKnown reports a format that no checker is registered for to the hook set with
+// SchemaLoader.SetUnknownFormatHook, once per format, or fails if SchemaLoader.RejectUnknownFormats is set
+func (d *Schema) checkFormatKnown(format string) error {
+	if FormatCheckers.Has(format) || d.unknownFormats[format] {
+		return nil
+	}
+	if d.rejectUnknownFormats {
+		return errors.New(formatErrorDescription(
+			Locale.UnknownFormat(),
+			ErrorDetails{"format": format},
+		))
+	}
+	if d.unknownFormats == nil {
+		d.unknownFormats = make(map[string]bool)
+	}
+	d.unknownFormats[format] = true
+	if d.unknownFormatHook != nil {
+		d.unknownFormatHook(format)
+	}
+	return nil
+}
+
+// checkKeywordsKnown fails if SchemaLoader.RejectUnknownKeywords is set and the schema has a keyword that is neither
+// a keyword of its draft, a custom keyword nor an alias registered with RegisterKeywordAlias
+func (d *Schema) checkKeywordsKnown(m map[string]interface{}, draft Draft) error {
+	if !d.rejectUnknownKeywords {
+		return nil
+	}
+	known := draftKeywords(draft)
+	for _, keyword := range sortedKeys(m) {
+		if !known[keyword] && !CustomKeywords.Has(keyword) && !isKeywordAlias(keyword) {
+			return errors.New(formatErrorDescription(
+				Locale.UnknownKeyword(),
+				ErrorDetails{"keyword": keyword},
+			))
+		}
+	}
+	return nil
+}
+
+// assertsFormat reports whether values are checked against the given format
+func (d *Schema) assertsFormat(format string) bool {
+	return d.assertFormats == nil || d.assertFormats[format]
+}
+
+// CheckReferences returns the references in the schema that could not be resolved, sorted and without duplicates.
+// This can only be the case for schemas compiled with SchemaLoader.AllowUnresolvedRefs, otherwise compiling fails
+// on the first reference that can't be resolved
+func (d *Schema) CheckReferences() []string {
+	var refs []string
+	for _, ref := range d.unresolvedRefs {
+		if !isStringInSlice(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// Warnings returns the non-fatal problems found while compiling the schema,
+// such as the use of keywords that are deprecated in the draft being used
+func (d *Schema) Warnings() []string {
+	// Subschemas of custom keywords are compiled while validating, which may add warnings
+	d.parseLock.Lock()
+	defer d.parseLock.Unlock()
+	return d.warnings
 }
 
 func (d *Schema) parse(document interface{}, draft Draft) error {
@@ -83,6 +186,8 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		currentSchema.draft = currentSchema.parent.draft
 	}
 
+	currentSchema.documentNode = documentNode
+
 	// As of draft 6 "true" is equivalent to an empty schema "{}" and false equals "{"not":{}}"
 	if *currentSchema.draft >= Draft6 && isKind(documentNode, reflect.Bool) {
 		b := documentNode.(bool)
@@ -99,7 +204,10 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		))
 	}
 
-	m := documentNode.(map[string]interface{})
+	m := applyKeywordAliases(documentNode.(map[string]interface{}))
+	if err := d.checkKeywordsKnown(m, *currentSchema.draft); err != nil {
+		return err
+	}
 
 	if currentSchema.parent == nil {
 		currentSchema.ref = &d.documentReference
@@ -124,6 +232,12 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		}
 	default:
 		keyID = KEY_ID_NEW
+		if existsMapKey(m, KEY_ID) && !existsMapKey(m, KEY_ID_NEW) {
+			d.warnings = append(d.warnings, formatErrorDescription(
+				Locale.DeprecatedKeyword(),
+				ErrorDetails{"keyword": KEY_ID, "replacement": KEY_ID_NEW},
+			))
+		}
 	}
 	if existsMapKey(m, keyID) && !isKind(m[keyID], reflect.String) {
 		return errors.New(formatErrorDescription(
@@ -139,24 +253,31 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		if err != nil {
 			return err
 		}
-		if currentSchema == d.rootSchema {
-			currentSchema.id = &jsonReference
-		} else {
-			ref, err := currentSchema.parent.id.Inherits(jsonReference)
-			if err != nil {
-				return err
-			}
-			currentSchema.id = ref
+		// The id is resolved against the enclosing scope, which for a referenced schema is the reference itself
+		ref, err := currentSchema.id.Inherits(jsonReference)
+		if err != nil {
+			return err
 		}
+		currentSchema.id = ref
 	}
 
 	// definitions
-	if existsMapKey(m, KEY_DEFINITIONS) {
-		if isKind(m[KEY_DEFINITIONS], reflect.Map, reflect.Bool) {
-			for _, dv := range m[KEY_DEFINITIONS].(map[string]interface{}) {
+	// Draft 2019-09 renamed definitions to $defs, which is also accepted in hybrid mode
+	keysDefinitions := []string{KEY_DEFINITIONS}
+	if *currentSchema.draft == Hybrid {
+		keysDefinitions = append(keysDefinitions, KEY_DEFS)
+	}
+	for _, keyDefinitions := range keysDefinitions {
+		if !existsMapKey(m, keyDefinitions) {
+			continue
+		}
+		if isKind(m[keyDefinitions], reflect.Map, reflect.Bool) {
+			for dk, dv := range m[keyDefinitions].(map[string]interface{}) {
 				if isKind(dv, reflect.Map, reflect.Bool) {
 
-					newSchema := &subSchema{property: KEY_DEFINITIONS, parent: currentSchema}
+					newSchema := &subSchema{property: keyDefinitions, parent: currentSchema}
+					newSchema.location = []interface{}{keyDefinitions, dk}
+					currentSchema.definitionsChildren = append(currentSchema.definitionsChildren, newSchema)
 
 					err := d.parseSchema(dv, newSchema)
 
@@ -168,7 +289,7 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 						Locale.InvalidType(),
 						ErrorDetails{
 							"expected": STRING_ARRAY_OF_SCHEMAS,
-							"given":    KEY_DEFINITIONS,
+							"given":    keyDefinitions,
 						},
 					))
 				}
@@ -178,11 +299,10 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 				Locale.InvalidType(),
 				ErrorDetails{
 					"expected": STRING_ARRAY_OF_SCHEMAS,
-					"given":    KEY_DEFINITIONS,
+					"given":    keyDefinitions,
 				},
 			))
 		}
-
 	}
 
 	// title
@@ -213,6 +333,20 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		currentSchema.description = &k
 	}
 
+	// $comment, which only became a keyword in draft 7
+	if *currentSchema.draft >= Draft7 && existsMapKey(m, KEY_COMMENT) && !isKind(m[KEY_COMMENT], reflect.String) {
+		return errors.New(formatErrorDescription(
+			Locale.InvalidType(),
+			ErrorDetails{
+				"expected": TYPE_STRING,
+				"given":    KEY_COMMENT,
+			},
+		))
+	}
+	if k, ok := m[KEY_COMMENT].(string); ok {
+		currentSchema.comment = &k
+	}
+
 	// $ref
 	if existsMapKey(m, KEY_REF) && !isKind(m[KEY_REF], reflect.String) {
 		return errors.New(formatErrorDescription(
@@ -233,8 +367,14 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 
 		currentSchema.ref = &jsonReference
 
+		if d.pool.stats != nil {
+			d.pool.stats.RefsResolved++
+		}
+
 		if sch, ok := d.referencePool.Get(currentSchema.ref.String()); ok {
 			currentSchema.refSchema = sch
+		} else if sch, ok := d.registry.get(*currentSchema.ref, d); ok {
+			currentSchema.refSchema = sch
 		} else {
 			err := d.parseReference(documentNode, currentSchema)
 
@@ -321,8 +461,9 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 			patternPropertiesMap := m[KEY_PATTERN_PROPERTIES].(map[string]interface{})
 			if len(patternPropertiesMap) > 0 {
 				currentSchema.patternProperties = make(map[string]*subSchema)
+				currentSchema.patternPropertiesRegexps = make(map[string]Regexp)
 				for k, v := range patternPropertiesMap {
-					_, err := regexp.MatchString(k, "")
+					regexpObject, err := d.regexpEngine.Compile(k)
 					if err != nil {
 						return errors.New(formatErrorDescription(
 							Locale.RegexPattern(),
@@ -330,11 +471,13 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 						))
 					}
 					newSchema := &subSchema{property: k, parent: currentSchema, ref: currentSchema.ref}
+					newSchema.location = []interface{}{KEY_PATTERN_PROPERTIES, k}
 					err = d.parseSchema(v, newSchema)
 					if err != nil {
 						return errors.New(err.Error())
 					}
 					currentSchema.patternProperties[k] = newSchema
+					currentSchema.patternPropertiesRegexps[k] = regexpObject
 				}
 			}
 		} else {
@@ -383,6 +526,7 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 				if isKind(itemElement, reflect.Map, reflect.Bool) {
 					newSchema := &subSchema{parent: currentSchema, property: KEY_ITEMS}
 					newSchema.ref = currentSchema.ref
+					newSchema.location = []interface{}{KEY_ITEMS, len(currentSchema.itemsChildren)}
 					currentSchema.itemsChildren = append(currentSchema.itemsChildren, newSchema)
 					err := d.parseSchema(itemElement, newSchema)
 					if err != nil {
@@ -652,7 +796,7 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 
 	if existsMapKey(m, KEY_PATTERN) {
 		if isKind(m[KEY_PATTERN], reflect.String) {
-			regexpObject, err := regexp.Compile(m[KEY_PATTERN].(string))
+			regexpObject, err := d.regexpEngine.Compile(m[KEY_PATTERN].(string))
 			if err != nil {
 				return errors.New(formatErrorDescription(
 					Locale.MustBeValidRegex(),
@@ -660,6 +804,7 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 				))
 			}
 			currentSchema.pattern = regexpObject
+			currentSchema.patternString = m[KEY_PATTERN].(string)
 		} else {
 			return errors.New(formatErrorDescription(
 				Locale.MustBeOfA(),
@@ -676,6 +821,9 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 				ErrorDetails{"key": KEY_FORMAT, "type": TYPE_STRING},
 			))
 		}
+		if err := d.checkFormatKnown(formatString); err != nil {
+			return err
+		}
 		currentSchema.format = formatString
 	}
 
@@ -751,6 +899,32 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		}
 	}
 
+	// propertyOrder is a vendor keyword that is only known when enabled
+	if existsMapKey(m, KEY_PROPERTY_ORDER) && d.enforcePropertyOrder {
+		if !isKind(m[KEY_PROPERTY_ORDER], reflect.Slice) {
+			return errors.New(formatErrorDescription(
+				Locale.MustBeOfAn(),
+				ErrorDetails{"x": KEY_PROPERTY_ORDER, "y": TYPE_ARRAY},
+			))
+		}
+		for _, property := range m[KEY_PROPERTY_ORDER].([]interface{}) {
+			if !isKind(property, reflect.String) {
+				return errors.New(formatErrorDescription(
+					Locale.KeyItemsMustBeOfType(),
+					ErrorDetails{"key": KEY_PROPERTY_ORDER, "type": TYPE_STRING},
+				))
+			}
+			if isStringInSlice(currentSchema.propertyOrder, property.(string)) {
+				return errors.New(formatErrorDescription(
+					Locale.KeyItemsMustBeUnique(),
+					ErrorDetails{"key": KEY_PROPERTY_ORDER},
+				))
+			}
+			currentSchema.propertyOrder = append(currentSchema.propertyOrder, property.(string))
+		}
+		d.tracksPropertyOrder = true
+	}
+
 	// validation : array
 
 	if existsMapKey(m, KEY_MIN_ITEMS) {
@@ -807,6 +981,17 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		}
 	}
 
+	// Draft 2019-09 added unevaluatedItems, which is also accepted in hybrid mode
+	if existsMapKey(m, KEY_UNEVALUATED_ITEMS) && *currentSchema.draft == Hybrid {
+		newSchema := &subSchema{property: KEY_UNEVALUATED_ITEMS, parent: currentSchema, ref: currentSchema.ref}
+		currentSchema.unevaluatedItems = newSchema
+		err := d.parseSchema(m[KEY_UNEVALUATED_ITEMS], newSchema)
+		if err != nil {
+			return err
+		}
+		d.tracksEvaluatedItems = true
+	}
+
 	// validation : all
 
 	if existsMapKey(m, KEY_CONST) && *currentSchema.draft >= Draft6 {
@@ -819,18 +1004,22 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 
 	if existsMapKey(m, KEY_ENUM) {
 		if isKind(m[KEY_ENUM], reflect.Slice) {
+			currentSchema.enumSet = make(map[string]bool)
+			currentSchema.enumValues = m[KEY_ENUM].([]interface{})
 			for _, v := range m[KEY_ENUM].([]interface{}) {
+				// Values are normalized to JSON so objects, arrays and numbers like 1 and 1.0 compare equal as strings
 				is, err := marshalWithoutNumber(v)
 				if err != nil {
 					return err
 				}
-				if isStringInSlice(currentSchema.enum, *is) {
+				if currentSchema.enumSet[*is] {
 					return errors.New(formatErrorDescription(
 						Locale.KeyItemsMustBeUnique(),
 						ErrorDetails{"key": KEY_ENUM},
 					))
 				}
 				currentSchema.enum = append(currentSchema.enum, *is)
+				currentSchema.enumSet[*is] = true
 			}
 		} else {
 			return errors.New(formatErrorDescription(
@@ -846,6 +1035,7 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		if isKind(m[KEY_ONE_OF], reflect.Slice) {
 			for _, v := range m[KEY_ONE_OF].([]interface{}) {
 				newSchema := &subSchema{property: KEY_ONE_OF, parent: currentSchema, ref: currentSchema.ref}
+				newSchema.location = []interface{}{KEY_ONE_OF, len(currentSchema.oneOf)}
 				currentSchema.oneOf = append(currentSchema.oneOf, newSchema)
 				err := d.parseSchema(v, newSchema)
 				if err != nil {
@@ -864,6 +1054,7 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		if isKind(m[KEY_ANY_OF], reflect.Slice) {
 			for _, v := range m[KEY_ANY_OF].([]interface{}) {
 				newSchema := &subSchema{property: KEY_ANY_OF, parent: currentSchema, ref: currentSchema.ref}
+				newSchema.location = []interface{}{KEY_ANY_OF, len(currentSchema.anyOf)}
 				currentSchema.anyOf = append(currentSchema.anyOf, newSchema)
 				err := d.parseSchema(v, newSchema)
 				if err != nil {
@@ -882,6 +1073,7 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		if isKind(m[KEY_ALL_OF], reflect.Slice) {
 			for _, v := range m[KEY_ALL_OF].([]interface{}) {
 				newSchema := &subSchema{property: KEY_ALL_OF, parent: currentSchema, ref: currentSchema.ref}
+				newSchema.location = []interface{}{KEY_ALL_OF, len(currentSchema.allOf)}
 				currentSchema.allOf = append(currentSchema.allOf, newSchema)
 				err := d.parseSchema(v, newSchema)
 				if err != nil {
@@ -896,6 +1088,13 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		}
 	}
 
+	// discriminator is an OpenAPI keyword that is only known when enabled
+	if existsMapKey(m, KEY_DISCRIMINATOR) && d.openAPIDiscriminator {
+		if err := d.parseDiscriminator(m[KEY_DISCRIMINATOR], currentSchema); err != nil {
+			return err
+		}
+	}
+
 	if existsMapKey(m, KEY_NOT) {
 		if isKind(m[KEY_NOT], reflect.Map, reflect.Bool) {
 			newSchema := &subSchema{property: KEY_NOT, parent: currentSchema, ref: currentSchema.ref}
@@ -962,9 +1161,27 @@ func (d *Schema) parseSchema(documentNode interface{}, currentSchema *subSchema)
 		}
 	}
 
+	currentSchema.customKeywords = CustomKeywords.find(m)
+
 	return nil
 }
 
+// parseDetachedSchema compiles a schema that is not part of the schema tree after compilation,
+// such as the subschemas passed by custom keywords.
+// Compiling a schema isn't safe for concurrent use, so this is guarded by a lock as
+// a compiled schema may be used by multiple validations at the same time
+func (d *Schema) parseDetachedSchema(documentNode interface{}, property string, parent *subSchema) (*subSchema, error) {
+	d.parseLock.Lock()
+	defer d.parseLock.Unlock()
+
+	newSchema := &subSchema{property: property, parent: parent, ref: parent.ref}
+	if err := d.parseSchema(documentNode, newSchema); err != nil {
+		return nil, err
+	}
+
+	return newSchema, nil
+}
+
 func (d *Schema) parseReference(documentNode interface{}, currentSchema *subSchema) error {
 	var (
 		refdDocumentNode interface{}
@@ -976,9 +1193,23 @@ func (d *Schema) parseReference(documentNode interface{}, currentSchema *subSche
 
 	d.referencePool.Add(currentSchema.ref.String(), newSchema)
 
-	dsp, err = d.pool.GetDocument(*currentSchema.ref)
+	if registered, ok := d.registry.lookup(*currentSchema.ref); ok {
+		// The document was compiled before, but not this part of it
+		unlock := registered.lockOther(d)
+		dsp, err = registered.pool.GetDocument(*currentSchema.ref)
+		unlock()
+	} else {
+		dsp, err = d.pool.GetDocument(*currentSchema.ref)
+	}
 	if err != nil {
-		return err
+		if !d.allowUnresolvedRefs {
+			return err
+		}
+		// The reference is reported by CheckReferences and fails validation instead
+		d.referencePool.Remove(currentSchema.ref.String())
+		currentSchema.unresolvedRef = true
+		d.unresolvedRefs = append(d.unresolvedRefs, currentSchema.ref.String())
+		return nil
 	}
 	newSchema.id = currentSchema.ref
 
@@ -1016,10 +1247,12 @@ func (d *Schema) parseProperties(documentNode interface{}, currentSchema *subSch
 		))
 	}
 
+	// The properties are kept in the order they are declared in, see Schema.PropertyNames
 	m := documentNode.(map[string]interface{})
-	for k := range m {
+	for _, k := range d.source.keys(m) {
 		schemaProperty := k
 		newSchema := &subSchema{property: schemaProperty, parent: currentSchema, ref: currentSchema.ref}
+		newSchema.location = []interface{}{KEY_PROPERTIES, schemaProperty}
 		currentSchema.propertiesChildren = append(currentSchema.propertiesChildren, newSchema)
 		err := d.parseSchema(m[k], newSchema)
 		if err != nil {
@@ -1030,6 +1263,84 @@ func (d *Schema) parseProperties(documentNode interface{}, currentSchema *subSch
 	return nil
 }
 
+// parseDiscriminator parses an OpenAPI discriminator, which selects the branch of "oneOf" or "anyOf" to validate against
+// by the value of a property. Values are mapped to branches by "mapping", branches that aren't mapped are selected
+// by the last segment of their reference, i.e. "Dog" for {"$ref" : "#/components/schemas/Dog"}
+func (d *Schema) parseDiscriminator(documentNode interface{}, currentSchema *subSchema) error {
+	m, ok := documentNode.(map[string]interface{})
+	if !ok {
+		return errors.New(formatErrorDescription(
+			Locale.MustBeOfAn(),
+			ErrorDetails{"x": KEY_DISCRIMINATOR, "y": TYPE_OBJECT},
+		))
+	}
+	propertyName, ok := m[KEY_PROPERTY_NAME].(string)
+	if !ok {
+		return errors.New(formatErrorDescription(
+			Locale.MustBeOfType(),
+			ErrorDetails{"key": KEY_PROPERTY_NAME, "type": TYPE_STRING},
+		))
+	}
+
+	keyword, branches := KEY_ONE_OF, currentSchema.oneOf
+	if len(branches) == 0 {
+		keyword, branches = KEY_ANY_OF, currentSchema.anyOf
+	}
+	if len(branches) == 0 {
+		// Discriminators can also be used for inheritance with allOf, which doesn't select anything to validate against
+		return nil
+	}
+
+	discriminator := &discriminator{property: propertyName, keyword: keyword, branches: make(map[string]*subSchema)}
+	if existsMapKey(m, KEY_MAPPING) {
+		mapping, ok := m[KEY_MAPPING].(map[string]interface{})
+		if !ok {
+			return errors.New(formatErrorDescription(
+				Locale.MustBeOfAn(),
+				ErrorDetails{"x": KEY_MAPPING, "y": TYPE_OBJECT},
+			))
+		}
+		for value, ref := range mapping {
+			ref, ok := ref.(string)
+			if !ok {
+				return errors.New(formatErrorDescription(
+					Locale.MustBeOfType(),
+					ErrorDetails{"key": value, "type": TYPE_STRING},
+				))
+			}
+			// The mapping holds references, which were made absolute like "$ref" when the document was loaded
+			for _, branch := range branches {
+				if branch.refSchema != nil && branch.ref.String() == ref {
+					discriminator.branches[value] = branch
+				}
+			}
+			if discriminator.branches[value] == nil {
+				return errors.New(formatErrorDescription(
+					Locale.UnresolvedReference(),
+					ErrorDetails{"reference": ref},
+				))
+			}
+		}
+	}
+	mapped := make(map[*subSchema]bool, len(discriminator.branches))
+	for _, branch := range discriminator.branches {
+		mapped[branch] = true
+	}
+	for _, branch := range branches {
+		if branch.refSchema == nil || mapped[branch] {
+			continue
+		}
+		ref := branch.ref.String()
+		name := ref[strings.LastIndex(ref, "/")+1:]
+		if _, ok := discriminator.branches[name]; !ok {
+			discriminator.branches[name] = branch
+		}
+	}
+
+	currentSchema.discriminator = discriminator
+	return nil
+}
+
 func (d *Schema) parseDependencies(documentNode interface{}, currentSchema *subSchema) error {
 
 	if !isKind(documentNode, reflect.Map) {
@@ -1065,6 +1376,7 @@ func (d *Schema) parseDependencies(documentNode interface{}, currentSchema *subS
 
 		case reflect.Map, reflect.Bool:
 			depSchema := &subSchema{property: k, parent: currentSchema, ref: currentSchema.ref}
+			depSchema.location = []interface{}{KEY_DEPENDENCIES, k}
 			err := d.parseSchema(m[k], depSchema)
 			if err != nil {
 				return err
@@ -0,0 +1,142 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"strconv"
+	"sync"
+)
+
+// DefaultSchemaCacheSize is the number of schemas held by the cache used by CompileCached
+const DefaultSchemaCacheSize = 128
+
+var defaultSchemaCache = NewSchemaCache(DefaultSchemaCacheSize)
+
+// CompileCached compiles a schema like NewSchema, but returns the same compiled schema for every schema with the
+// same source, see SchemaCache. The DefaultSchemaCacheSize most recently used schemas are kept
+func CompileCached(l JSONLoader) (*Schema, error) {
+	return defaultSchemaCache.CompileCached(l)
+}
+
+// SchemaCache holds compiled schemas by a hash of their source, so schemas that are compiled over and over,
+// i.e. for every request, are only compiled once. It is safe for concurrent use
+type SchemaCache struct {
+	lock     sync.Mutex
+	capacity int
+	// The cached schemas, the most recently used at the front
+	order   *list.List
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type schemaCacheEntry struct {
+	key    [sha256.Size]byte
+	schema *Schema
+}
+
+// NewSchemaCache creates a new, empty SchemaCache holding at most capacity schemas.
+// When it is full, the least recently used schema is evicted. A capacity of 0 or less means there is no limit
+func NewSchemaCache(capacity int) *SchemaCache {
+	return &SchemaCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// CompileCached compiles a schema like NewSchema, unless a schema with the same source and reference was
+// compiled by this cache before, in which case that compiled schema is returned.
+// The source is the text held by string, bytes and reader loaders, other loaders are hashed by their canonical JSON.
+// Reference loaders are cached by their reference alone, so changes to the document they load aren't picked up.
+// Schemas that fail to compile are not cached
+func (c *SchemaCache) CompileCached(l JSONLoader) (*Schema, error) {
+	key, err := schemaCacheKey(l)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		c.lock.Unlock()
+		return element.Value.(*schemaCacheEntry).schema, nil
+	}
+	c.lock.Unlock()
+
+	// Compiling can take a while, so it is done without holding the lock
+	schema, err := NewSchema(l)
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// Another goroutine may have compiled the same schema in the meantime, all callers get the same one
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return element.Value.(*schemaCacheEntry).schema, nil
+	}
+	c.entries[key] = c.order.PushFront(&schemaCacheEntry{key: key, schema: schema})
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*schemaCacheEntry).key)
+	}
+	return schema, nil
+}
+
+// Len returns the number of schemas in the cache
+func (c *SchemaCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
+
+// schemaCacheKey hashes the reference and the source of the schema. The reference is part of the key
+// as relative references in the same source resolve differently depending on where the schema is loaded from
+func schemaCacheKey(l JSONLoader) ([sha256.Size]byte, error) {
+	ref, err := l.JsonReference()
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	var source []byte
+	if reference, ok := l.(*jsonReferenceLoader); ok {
+		// Documents loaded by reference are only loaded to compile them, the reference alone is the key.
+		// A size limit may keep the document from compiling, so it is part of the key as well
+		source = []byte(strconv.FormatInt(reference.maxBytes, 10))
+	} else if text, err := loaderText(l, 0); err != nil {
+		return [sha256.Size]byte{}, err
+	} else if text != nil {
+		source = text.text
+	} else {
+		document, err := l.LoadJSON()
+		if err != nil {
+			return [sha256.Size]byte{}, err
+		}
+		if source, err = marshalCanonical(document); err != nil {
+			return [sha256.Size]byte{}, err
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(ref.String()))
+	h.Write([]byte{0})
+	h.Write(source)
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key, nil
+}
@@ -17,6 +17,8 @@ package gojsonschema
 import (
 	"bytes"
 	"errors"
+	"sort"
+	"time"
 
 	"github.com/xeipuuv/gojsonreference"
 )
@@ -27,6 +29,91 @@ type SchemaLoader struct {
 	AutoDetect bool
 	Validate   bool
 	Draft      Draft
+	// RegexpEngine is used to compile "pattern" and "patternProperties", when nil Go's RE2 is used
+	RegexpEngine RegexpEngine
+	// Registry holds compiled schemas that references are resolved to before loading documents
+	Registry *SchemaRegistry
+	// ReportOverlappingPatterns adds an annotation for every property matching more than one pattern in "patternProperties"
+	ReportOverlappingPatterns bool
+	// ResolveMetadataRefs makes Schema.Metadata report the title and description of the referenced schema
+	// for subschemas that consist of a "$ref" only
+	ResolveMetadataRefs bool
+	// AssertFormats lists the formats that are checked, other formats are only reported as annotations.
+	// When nil all formats are checked
+	AssertFormats []string
+	// AllowUnresolvedRefs compiles schemas with references that can't be resolved instead of failing,
+	// so they can all be listed by Schema.CheckReferences. Validating against such a reference results in an error
+	AllowUnresolvedRefs bool
+	// StrictIntegers makes "type": "integer" reject numbers written with a fraction or exponent, such as 1.0 or 1e2,
+	// even though their value is integral
+	StrictIntegers bool
+	// ResolveInstanceRefs replaces every object in a validated document that holds a "$ref" to a JSON pointer
+	// within the document, i.e. {"$ref" : "#/shared/address"}, by the value it points to before validating it.
+	// This is not part of the specification, documents with circular references fail to validate with an error
+	ResolveInstanceRefs bool
+	// EmptyStringAsAbsent treats properties of a validated document whose value is the empty string as if they
+	// weren't present, so they don't satisfy "required" and aren't validated against their schema
+	EmptyStringAsAbsent bool
+	// TraceEvaluatedKeywords records which keywords were evaluated against every value of a validated document,
+	// for debugging why a value passed or failed, see Result.EvaluatedKeywords
+	TraceEvaluatedKeywords bool
+	// Severities maps error types, i.e. ErrorTypeFormat, to their severity. Errors of other types have SeverityError.
+	// Errors with SeverityWarning are reported, but don't make a document invalid
+	Severities map[string]Severity
+	// MaxErrorsPerField, when positive, limits the number of errors reported for the same field,
+	// i.e. 1 keeps only the first error of every field. Errors are kept before warnings, so the validity is not affected
+	MaxErrorsPerField int
+	// EnforcePropertyOrder enables the "propertyOrder" keyword, which is not part of the specification.
+	// It holds property names that must appear in that order in objects, as far as they are present.
+	// The order is only known for documents loaded from text, such as with NewStringLoader, NewBytesLoader or NewReaderLoader
+	EnforcePropertyOrder bool
+	// OpenAPIDiscriminator enables the OpenAPI "discriminator" keyword next to "oneOf" or "anyOf". Objects are only
+	// validated against the branch selected by the value of the discriminator property, so the errors are those of that branch
+	OpenAPIDiscriminator bool
+	// NormalizeFormats adds the canonical form of values that have a format to the result as an annotation,
+	// for formats whose checker implements NormalizingFormatChecker, see NormalizedValue
+	NormalizeFormats bool
+	// RejectUnknownFormats makes compiling a schema fail when it uses a "format" that no checker is registered for
+	// in FormatCheckers, instead of ignoring that format. See SetUnknownFormatHook to only be notified
+	RejectUnknownFormats bool
+	// RejectUnknownKeywords makes compiling a schema fail when it uses a keyword that is neither a keyword of its draft,
+	// a custom keyword added to CustomKeywords nor an alias registered with RegisterKeywordAlias, i.e. to catch typos
+	RejectUnknownKeywords bool
+	// ShortCircuitAllOf stops validating the subschemas of "allOf" at the first one that fails, so only the errors of
+	// that subschema are reported. The index of the subschema is in the details of the NumberAllOfError
+	ShortCircuitAllOf bool
+	// ReportFormatResults adds the outcome of every "format" check to the result as an annotation, see FormatResult.
+	// Formats that are only annotations, see AssertFormats, are checked as well, but their outcome doesn't affect validity
+	ReportFormatResults bool
+	// SchemaSnippets keeps the text of schemas compiled from text, so errors can quote the keyword they originate from,
+	// see ResultErrorFields.SchemaSnippet
+	SchemaSnippets bool
+	// Stats, when set, is filled with diagnostics about every compilation
+	Stats *CompileStats
+
+	validationHook    func(path string, value interface{}, ok bool)
+	unknownFormatHook func(format string)
+	locale            locale
+	maxDepth          int
+	clock             func() time.Time
+}
+
+// defaultMaxDepth is the maximum nesting depth of documents unless set otherwise with SetMaxDepth,
+// the same as the limit of encoding/json
+const defaultMaxDepth = 10000
+
+// CompileStats holds diagnostics about the compilation of a schema, see SchemaLoader.Stats
+type CompileStats struct {
+	// DocumentsParsed is the number of documents whose references were parsed, including the root schema
+	DocumentsParsed int
+	// RefsResolved is the number of "$ref" keywords that were resolved
+	RefsResolved int
+	// RemoteFetches is the number of documents that were loaded over HTTP or from a file
+	RemoteFetches int
+	// FetchDuration is the time spent loading remote documents
+	FetchDuration time.Duration
+	// Duration is the time the whole compilation took
+	Duration time.Duration
 }
 
 // NewSchemaLoader creates a new NewSchemaLoader
@@ -39,6 +126,7 @@ func NewSchemaLoader() *SchemaLoader {
 		AutoDetect: true,
 		Validate:   false,
 		Draft:      Hybrid,
+		maxDepth:   defaultMaxDepth,
 	}
 	ps.pool.autoDetect = &ps.AutoDetect
 
@@ -52,7 +140,7 @@ func (sl *SchemaLoader) validateMetaschema(documentNode interface{}) error {
 		err    error
 	)
 	if sl.AutoDetect {
-		schema, _, err = parseSchemaURL(documentNode)
+		schema, _, err = sl.pool.parseSchemaURL(documentNode)
 		if err != nil {
 			return err
 		}
@@ -66,18 +154,32 @@ func (sl *SchemaLoader) validateMetaschema(documentNode interface{}) error {
 		schema = drafts.GetSchemaURL(sl.Draft)
 	}
 
+	// A registered dialect is validated against the meta-schema of its draft, unless its own meta-schema was added
+	if draft, ok := sl.pool.dialects[schema]; ok && !sl.pool.hasDocument(schema) {
+		if draft == Hybrid {
+			return nil
+		}
+		schema = drafts.GetSchemaURL(draft)
+	}
+
 	//Disable validation when loading the metaschema to prevent an infinite recursive loop
 	sl.Validate = false
 
-	metaSchema, err := sl.Compile(NewReferenceLoader(schema))
+	metaSchema, err := sl.compile(NewReferenceLoader(schema))
 
 	if err != nil {
 		return err
 	}
 
+	// The hook is meant for the validation of documents, not of schemas
+	metaSchema.validationHook = nil
+
 	sl.Validate = true
 
-	result := metaSchema.validateDocument(documentNode)
+	result, err := metaSchema.validateDocument(documentNode)
+	if err != nil {
+		return err
+	}
 
 	if !result.Valid() {
 		var res bytes.Buffer
@@ -91,6 +193,75 @@ func (sl *SchemaLoader) validateMetaschema(documentNode interface{}) error {
 	return nil
 }
 
+// SetValidationHook sets a function that is called for every node in the document that was validated, with
+// the context of the node, its value and whether the node and its children are valid. The hook is called after
+// validation has finished in document order and doesn't change the result. It applies to schemas compiled afterwards
+func (sl *SchemaLoader) SetValidationHook(hook func(path string, value interface{}, ok bool)) {
+	sl.validationHook = hook
+}
+
+// SetUnknownFormatHook sets a function that is called while compiling a schema for every "format" it uses that no checker
+// is registered for in FormatCheckers, once per format. Such formats are ignored during validation, so the hook can be
+// used to warn about typos. It applies to schemas compiled afterwards
+func (sl *SchemaLoader) SetUnknownFormatHook(hook func(format string)) {
+	sl.unknownFormatHook = hook
+}
+
+// SetURINormalizer sets a function that normalizes the absolute URIs of "$id" and "$ref", for example to
+// force https or strip default ports, so equivalent URIs resolve to the same document and it is only fetched once.
+// The function receives and returns a URI including its fragment. It applies to schemas added or compiled afterwards
+func (sl *SchemaLoader) SetURINormalizer(normalize func(uri string) string) {
+	sl.pool.normalizeURI = normalize
+}
+
+// SetRemoteRefsDisabled disables loading references over HTTP, so compiling a schema never accesses the network.
+// References to http and https URLs that aren't added to the SchemaLoader with AddSchema or AddSchemas, or
+// registered in its Registry, result in an error instead. References to files are still loaded
+func (sl *SchemaLoader) SetRemoteRefsDisabled(disabled bool) {
+	sl.pool.remoteRefsDisabled = disabled
+}
+
+// RegisterDialect makes schemas with the given "$schema" URI, such as the meta-schema of a custom vocabulary, be parsed
+// as the given draft instead of the draft of the SchemaLoader. Unless the meta-schema itself is added with AddSchema or
+// AddSchemas, Validate checks such schemas against the meta-schema of the draft
+func (sl *SchemaLoader) RegisterDialect(schemaURL string, draft Draft) error {
+	ref, err := gojsonreference.NewJsonReference(schemaURL)
+	if err != nil {
+		return err
+	}
+	if sl.pool.dialects == nil {
+		sl.pool.dialects = make(map[string]Draft)
+	}
+	sl.pool.dialects[ref.String()] = draft
+	return nil
+}
+
+// SetRejectUnknownDialects makes compiling fail for schemas with a "$schema" that isn't a supported draft,
+// registered with RegisterDialect or added with AddSchema or AddSchemas. By default such schemas are parsed
+// as the draft of the SchemaLoader
+func (sl *SchemaLoader) SetRejectUnknownDialects(reject bool) {
+	sl.pool.rejectUnknownDialects = reject
+}
+
+// SetClock sets the function that returns the current time for keywords and formats that depend on it,
+// see Validator.Now and ClockFormatChecker, so their results can be reproduced. It applies to schemas compiled afterwards
+func (sl *SchemaLoader) SetClock(clock func() time.Time) {
+	sl.clock = clock
+}
+
+// SetLocale sets the locale used for the errors of validations against schemas compiled afterwards,
+// instead of the global Locale. Errors while compiling a schema still use the global Locale
+func (sl *SchemaLoader) SetLocale(l locale) {
+	sl.locale = l
+}
+
+// SetMaxDepth sets how deeply documents validated against schemas compiled afterwards may be nested.
+// Deeper documents aren't validated but result in an error, as validating them could exhaust the stack.
+// The default is 10000, a limit of 0 or less disables the check
+func (sl *SchemaLoader) SetMaxDepth(n int) {
+	sl.maxDepth = n
+}
+
 // AddSchemas adds an arbritrary amount of schemas to the schema cache. As this function does not require
 // an explicit URL, every schema should contain an $id, so that it can be referenced by the main schema
 func (sl *SchemaLoader) AddSchemas(loaders ...JSONLoader) error {
@@ -119,7 +290,48 @@ func (sl *SchemaLoader) AddSchemas(loaders ...JSONLoader) error {
 	return nil
 }
 
-//AddSchema adds a schema under the provided URL to the schema cache
+// AddBundle adds all schemas contained in a single document to the schema cache. The document should
+// either be an array of schemas or an object of which every value is a schema. Like with AddSchemas
+// every schema should contain an $id, so that it can be referenced by the main schema
+func (sl *SchemaLoader) AddBundle(loader JSONLoader) error {
+	doc, err := loader.LoadJSON()
+
+	if err != nil {
+		return err
+	}
+
+	var members []interface{}
+
+	switch bundle := doc.(type) {
+	case []interface{}:
+		members = bundle
+	case map[string]interface{}:
+		// Sort the keys so schemas are always added in the same order
+		keys := make([]string, 0, len(bundle))
+		for k := range bundle {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			members = append(members, bundle[k])
+		}
+	default:
+		return errors.New(formatErrorDescription(
+			Locale.MustBeOfType(),
+			ErrorDetails{"key": STRING_BUNDLE, "type": TYPE_ARRAY + "/" + TYPE_OBJECT},
+		))
+	}
+
+	for _, member := range members {
+		if err := sl.AddSchemas(NewRawLoader(member)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddSchema adds a schema under the provided URL to the schema cache
 func (sl *SchemaLoader) AddSchema(url string, loader JSONLoader) error {
 
 	ref, err := gojsonreference.NewJsonReference(url)
@@ -145,6 +357,26 @@ func (sl *SchemaLoader) AddSchema(url string, loader JSONLoader) error {
 
 // Compile loads and compiles a schema
 func (sl *SchemaLoader) Compile(rootSchema JSONLoader) (*Schema, error) {
+	if sl.Stats == nil {
+		return sl.compile(rootSchema)
+	}
+
+	*sl.Stats = CompileStats{}
+	start := time.Now()
+
+	sl.pool.stats = sl.Stats
+	defer func() {
+		sl.pool.stats = nil
+	}()
+
+	d, err := sl.compile(rootSchema)
+
+	sl.Stats.Duration = time.Since(start)
+
+	return d, err
+}
+
+func (sl *SchemaLoader) compile(rootSchema JSONLoader) (*Schema, error) {
 
 	ref, err := rootSchema.JsonReference()
 
@@ -155,11 +387,51 @@ func (sl *SchemaLoader) Compile(rootSchema JSONLoader) (*Schema, error) {
 	d := Schema{}
 	d.pool = sl.pool
 	d.pool.jsonLoaderFactory = rootSchema.LoaderFactory()
-	d.documentReference = ref
+	d.documentReference = sl.pool.normalize(ref)
 	d.referencePool = newSchemaReferencePool()
+	d.regexpEngine = sl.RegexpEngine
+	if d.regexpEngine == nil {
+		d.regexpEngine = re2Engine{}
+	}
+	d.registry = sl.Registry
+	d.reportOverlappingPatterns = sl.ReportOverlappingPatterns
+	d.resolveMetadataRefs = sl.ResolveMetadataRefs
+	d.validationHook = sl.validationHook
+	d.locale = sl.locale
+	d.maxDepth = sl.maxDepth
+	d.allowUnresolvedRefs = sl.AllowUnresolvedRefs
+	d.strictIntegers = sl.StrictIntegers
+	d.clock = sl.clock
+	d.resolveInstanceRefs = sl.ResolveInstanceRefs
+	d.emptyStringAsAbsent = sl.EmptyStringAsAbsent
+	d.traceEvaluatedKeywords = sl.TraceEvaluatedKeywords
+	d.maxErrorsPerField = sl.MaxErrorsPerField
+	d.enforcePropertyOrder = sl.EnforcePropertyOrder
+	d.openAPIDiscriminator = sl.OpenAPIDiscriminator
+	d.normalizeFormats = sl.NormalizeFormats
+	d.rejectUnknownFormats = sl.RejectUnknownFormats
+	d.rejectUnknownKeywords = sl.RejectUnknownKeywords
+	d.shortCircuitAllOf = sl.ShortCircuitAllOf
+	d.reportFormatResults = sl.ReportFormatResults
+	d.unknownFormatHook = sl.unknownFormatHook
+	if sl.Severities != nil {
+		d.severities = make(map[string]Severity, len(sl.Severities))
+		for errorType, severity := range sl.Severities {
+			d.severities[errorType] = severity
+		}
+	}
+	if sl.AssertFormats != nil {
+		d.assertFormats = make(map[string]bool, len(sl.AssertFormats))
+		for _, format := range sl.AssertFormats {
+			d.assertFormats[format] = true
+		}
+	}
+
+	// A reader with a base URI holds the document itself, the reference is only used to resolve relative references
+	_, isReaderWithBase := rootSchema.(*jsonReaderLoader)
 
 	var doc interface{}
-	if ref.String() != "" {
+	if ref.String() != "" && !isReaderWithBase {
 		// Get document from schema pool
 		spd, err := d.pool.GetDocument(d.documentReference)
 		if err != nil {
@@ -167,8 +439,8 @@ func (sl *SchemaLoader) Compile(rootSchema JSONLoader) (*Schema, error) {
 		}
 		doc = spd.Document
 	} else {
-		// Load JSON directly
-		doc, err = rootSchema.LoadJSON()
+		// Load JSON directly, keeping the order of the properties of the schema, and its text for SchemaSnippets
+		doc, d.source, err = loadJSONWithSource(rootSchema, sl.SchemaSnippets)
 		if err != nil {
 			return nil, err
 		}
@@ -180,6 +452,15 @@ func (sl *SchemaLoader) Compile(rootSchema JSONLoader) (*Schema, error) {
 		}
 	}
 
+	vocabularies, err := sl.metaSchemaVocabularies(doc)
+	if err != nil {
+		return nil, err
+	}
+	if vocabularies != nil && sl.AssertFormats == nil && !assertsFormatVocabulary(vocabularies) {
+		// Without a format assertion vocabulary formats are only annotations
+		d.assertFormats = map[string]bool{}
+	}
+
 	if sl.Validate {
 		if err := sl.validateMetaschema(doc); err != nil {
 			return nil, err
@@ -188,7 +469,7 @@ func (sl *SchemaLoader) Compile(rootSchema JSONLoader) (*Schema, error) {
 
 	draft := sl.Draft
 	if sl.AutoDetect {
-		_, detectedDraft, err := parseSchemaURL(doc)
+		_, detectedDraft, err := sl.pool.parseSchemaURL(doc)
 		if err != nil {
 			return nil, err
 		}
@@ -201,6 +482,9 @@ func (sl *SchemaLoader) Compile(rootSchema JSONLoader) (*Schema, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !sl.SchemaSnippets {
+		d.source = nil
+	}
 
 	return &d, nil
 }
@@ -15,7 +15,15 @@
 package gojsonschema
 
 import (
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -174,3 +182,1143 @@ func TestParseSchemaURL_NotMap(t *testing.T) {
 	require.Error(t, err)
 	assert.EqualError(t, err, "schema is invalid")
 }
+
+func TestSchemaLoaderAddBundle(t *testing.T) {
+	for _, bundle := range []string{`[
+		{
+			"$id" : "http://localhost:1234/bundle/user.json",
+			"type" : "object",
+			"properties" : {
+				"address" : {"$ref" : "address.json"}
+			},
+			"required" : ["address"]
+		},
+		{
+			"$id" : "http://localhost:1234/bundle/address.json",
+			"type" : "object",
+			"properties" : {
+				"zip" : {"type" : "string"}
+			}
+		}
+	]`, `{
+		"user" : {
+			"$id" : "http://localhost:1234/bundle/user.json",
+			"type" : "object",
+			"properties" : {
+				"address" : {"$ref" : "http://localhost:1234/bundle/address.json"}
+			},
+			"required" : ["address"]
+		},
+		"address" : {
+			"$id" : "http://localhost:1234/bundle/address.json",
+			"type" : "object",
+			"properties" : {
+				"zip" : {"type" : "string"}
+			}
+		}
+	}`} {
+		sl := NewSchemaLoader()
+		err := sl.AddBundle(NewStringLoader(bundle))
+		require.Nil(t, err)
+
+		schema, err := sl.Compile(NewStringLoader(`{"$ref" : "http://localhost:1234/bundle/user.json"}`))
+		require.Nil(t, err)
+
+		result, err := schema.Validate(NewStringLoader(`{"address" : {"zip" : "1234AB"}}`))
+		require.Nil(t, err)
+		assert.True(t, result.Valid())
+
+		result, err = schema.Validate(NewStringLoader(`{"address" : {"zip" : 1234}}`))
+		require.Nil(t, err)
+		if assert.Len(t, result.Errors(), 1) {
+			assert.Equal(t, "address.zip", result.Errors()[0].Field())
+		}
+	}
+
+	err := NewSchemaLoader().AddBundle(NewStringLoader(`"not a bundle"`))
+	assert.NotNil(t, err)
+}
+
+// prefixEngine is a RegexpEngine that treats every pattern as a literal prefix
+type prefixEngine struct {
+	compiled []string
+}
+
+type prefixRegexp string
+
+func (e *prefixEngine) Compile(pattern string) (Regexp, error) {
+	e.compiled = append(e.compiled, pattern)
+	return prefixRegexp(pattern), nil
+}
+
+func (r prefixRegexp) MatchString(s string) bool {
+	return strings.HasPrefix(s, string(r))
+}
+
+func TestSchemaLoaderRegexpEngine(t *testing.T) {
+	engine := &prefixEngine{}
+	sl := NewSchemaLoader()
+	sl.RegexpEngine = engine
+
+	// Neither pattern is valid in RE2
+	schema, err := sl.Compile(NewStringLoader(`{
+		"properties" : {
+			"name" : {"pattern" : "(?<first>"}
+		},
+		"patternProperties" : {
+			"x-(?=" : {"type" : "integer"}
+		}
+	}`))
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []string{"(?<first>", "x-(?="}, engine.compiled)
+
+	result, err := schema.Validate(NewStringLoader(`{"name" : "(?<first> name", "x-(?=a" : 1}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`{"name" : "name", "x-(?=a" : "1"}`))
+	require.Nil(t, err)
+	assert.Len(t, result.Errors(), 2)
+
+	_, err = NewSchemaLoader().Compile(NewStringLoader(`{"pattern" : "(?<first>"}`))
+	assert.NotNil(t, err)
+}
+
+func TestCachingRegexpEngine(t *testing.T) {
+	counter := &prefixEngine{}
+	engine := NewCachingRegexpEngine(counter)
+	schemaJSON := `{"properties" : {"id" : {"pattern" : "id-"}}, "patternProperties" : {"id-" : {"type" : "string"}}}`
+
+	for i := 0; i < 3; i++ {
+		sl := NewSchemaLoader()
+		sl.RegexpEngine = engine
+		schema, err := sl.Compile(NewStringLoader(schemaJSON))
+		require.Nil(t, err)
+
+		result, err := schema.Validate(NewStringLoader(`{"id" : "x", "id-1" : 1}`))
+		require.Nil(t, err)
+		assert.Len(t, result.Errors(), 2)
+	}
+	assert.Equal(t, []string{"id-"}, counter.compiled, "the pattern is compiled only once")
+
+	engine = NewCachingRegexpEngine(nil)
+	_, err := engine.Compile("(")
+	assert.NotNil(t, err)
+	first, err := engine.Compile("^a+$")
+	require.Nil(t, err)
+	second, err := engine.Compile("^a+$")
+	require.Nil(t, err)
+	assert.True(t, first == second)
+	assert.True(t, first.MatchString("aaa"))
+}
+
+// sharedPatternsSchema returns a schema with many patterns, that are the same for every tenant
+func sharedPatternsSchema(tenant int) string {
+	properties := make([]string, 50)
+	for i := range properties {
+		properties[i] = fmt.Sprintf(`"p%d" : {"pattern" : "^[a-z]{%d}-[0-9]+(\\.[0-9]+)*$"}`, i, i+1)
+	}
+	return fmt.Sprintf(`{"title" : "tenant %d", "properties" : {%s}}`, tenant, strings.Join(properties, ", "))
+}
+
+func BenchmarkCompileSharedPatterns(b *testing.B) {
+	engines := []struct {
+		name   string
+		engine RegexpEngine
+	}{
+		{"re2", nil},
+		{"caching", NewCachingRegexpEngine(nil)},
+	}
+	for _, e := range engines {
+		engine := e.engine
+		b.Run(e.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sl := NewSchemaLoader()
+				sl.RegexpEngine = engine
+				if _, err := sl.Compile(NewStringLoader(sharedPatternsSchema(i))); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaLoaderRegistry(t *testing.T) {
+	dependency, err := NewSchema(NewStringLoader(`{
+		"$id" : "http://example.com/address.json",
+		"type" : "object",
+		"required" : ["city"],
+		"definitions" : {
+			"zip" : {"type" : "string"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	registry := NewSchemaRegistry()
+	require.Nil(t, registry.Register("http://example.com/address.json", dependency))
+
+	sl := NewSchemaLoader()
+	sl.Registry = registry
+	// http://example.com is never requested, as the references are resolved using the registry
+	schema, err := sl.Compile(NewStringLoader(`{
+		"properties" : {
+			"address" : {"$ref" : "http://example.com/address.json"},
+			"zip" : {"$ref" : "http://example.com/address.json#/definitions/zip"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	address := findProperty(schema.rootSchema, "address")
+	require.NotNil(t, address)
+	assert.True(t, address.refSchema == dependency.rootSchema)
+
+	result, err := schema.Validate(NewStringLoader(`{"address" : {"city" : "Amsterdam"}, "zip" : "1234AB"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`{"address" : {}, "zip" : 1234}`))
+	require.Nil(t, err)
+	assert.Len(t, result.Errors(), 2)
+
+	assert.NotNil(t, registry.Register(":invalid", dependency))
+}
+
+func TestSchemaRegistryConcurrentCompile(t *testing.T) {
+	CustomKeywords.Add("myAllOf", myAllOfKeyword{})
+	defer CustomKeywords.Remove("myAllOf")
+
+	// Validating against the dependency compiles the subschemas of myAllOf, which adds to its pools
+	dependency, err := NewSchema(NewStringLoader(`{
+		"$id" : "http://example.com/address.json",
+		"myAllOf" : [{"$ref" : "#/definitions/zip"}, {"$ref" : "#/definitions/city"}],
+		"definitions" : {
+			"zip" : {"type" : "string"},
+			"city" : {"type" : "string"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	registry := NewSchemaRegistry()
+	require.Nil(t, registry.Register("http://example.com/address.json", dependency))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			result, err := dependency.Validate(NewStringLoader(`1`))
+			assert.Nil(t, err)
+			assert.Len(t, result.Errors(), 2)
+		}()
+		go func() {
+			defer wg.Done()
+			sl := NewSchemaLoader()
+			sl.Registry = registry
+			schema, err := sl.Compile(NewStringLoader(`{"$ref" : "http://example.com/address.json#/definitions/zip"}`))
+			if assert.Nil(t, err) {
+				result, err := schema.Validate(NewStringLoader(`"1234AB"`))
+				assert.Nil(t, err)
+				assert.True(t, result.Valid())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestValidateBySelector(t *testing.T) {
+	order, err := NewSchema(NewStringLoader(`{"required" : ["orderId"], "properties" : {"orderId" : {"type" : "integer"}}}`))
+	require.Nil(t, err)
+	refund, err := NewSchema(NewStringLoader(`{"required" : ["amount"], "properties" : {"amount" : {"type" : "number"}}}`))
+	require.Nil(t, err)
+
+	registry := NewSchemaRegistry()
+	require.Nil(t, registry.Register("http://example.com/order.json", order))
+	require.Nil(t, registry.Register("http://example.com/refund.json", refund))
+
+	schemaRef := func(doc interface{}) (string, error) {
+		if object, ok := doc.(map[string]interface{}); ok {
+			if ref, ok := object["schemaRef"].(string); ok {
+				return ref, nil
+			}
+		}
+		return "", errors.New("document has no schemaRef")
+	}
+
+	result, err := ValidateBySelector(NewStringLoader(`{"schemaRef" : "http://example.com/order.json", "orderId" : 1}`), schemaRef, registry)
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = ValidateBySelector(NewStringLoader(`{"schemaRef" : "http://example.com/refund.json", "orderId" : 1}`), schemaRef, registry)
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "amount", result.Errors()[0].Details()["property"])
+	}
+
+	_, err = ValidateBySelector(NewStringLoader(`{"schemaRef" : "http://example.com/unknown.json"}`), schemaRef, registry)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "No schema is registered for http://example.com/unknown.json", err.Error())
+	}
+
+	_, err = ValidateBySelector(NewStringLoader(`[]`), schemaRef, registry)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "document has no schemaRef", err.Error())
+	}
+
+	// The document is read once, also for schemas that need the order of its properties
+	sl := NewSchemaLoader()
+	sl.EnforcePropertyOrder = true
+	ordered, err := sl.Compile(NewStringLoader(`{"propertyOrder" : ["schemaRef", "name"]}`))
+	require.Nil(t, err)
+	require.Nil(t, registry.Register("http://example.com/ordered.json", ordered))
+	reader := strings.NewReader(`{"name" : "a", "schemaRef" : "http://example.com/ordered.json"}`)
+	result, err = ValidateBySelector(NewReaderLoaderWithBase(reader, ""), schemaRef, registry)
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypePropertyOrder, result.Errors()[0].Type())
+	}
+}
+
+func TestSchemaLoaderStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.json":
+			w.Write([]byte(`{"type" : "integer"}`))
+		case "/b.json":
+			w.Write([]byte(`{"definitions" : {"x" : {"type" : "string"}, "y" : {"type" : "boolean"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sl := NewSchemaLoader()
+	sl.Stats = &CompileStats{}
+
+	_, err := sl.Compile(NewStringLoader(`{
+		"properties" : {
+			"a1" : {"$ref" : "` + server.URL + `/a.json"},
+			"a2" : {"$ref" : "` + server.URL + `/a.json"},
+			"x" : {"$ref" : "` + server.URL + `/b.json#/definitions/x"},
+			"y" : {"$ref" : "` + server.URL + `/b.json#/definitions/y"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	assert.Equal(t, 2, sl.Stats.RemoteFetches)
+	assert.Equal(t, 3, sl.Stats.DocumentsParsed)
+	assert.Equal(t, 4, sl.Stats.RefsResolved)
+	assert.True(t, sl.Stats.Duration >= sl.Stats.FetchDuration)
+
+	// The stats are reset for every compilation, documents loaded before are not fetched again
+	_, err = sl.Compile(NewReferenceLoader(server.URL + "/a.json"))
+	require.Nil(t, err)
+	assert.Equal(t, CompileStats{Duration: sl.Stats.Duration}, *sl.Stats)
+}
+
+type germanLocale struct {
+	DefaultLocale
+}
+
+func (germanLocale) Required() string {
+	return `{{.property}} ist erforderlich`
+}
+
+func TestSchemaLoaderSetLocale(t *testing.T) {
+	schemaJSON := `{"required" : ["name"]}`
+
+	sl := NewSchemaLoader()
+	sl.SetLocale(germanLocale{})
+	germanSchema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	englishSchema, err := NewSchema(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	result, err := germanSchema.Validate(NewStringLoader(`{}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "name ist erforderlich", result.Errors()[0].Description())
+	}
+
+	result, err = englishSchema.Validate(NewStringLoader(`{}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "name is required", result.Errors()[0].Description())
+	}
+}
+
+// germanNumberLocale formats numbers with "." between groups of thousands and "," before the fraction
+type germanNumberLocale struct {
+	DefaultLocale
+}
+
+func (germanNumberLocale) FormatNumber(number *big.Float) string {
+	text := number.Text('f', -1)
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	integer, fraction := text, ""
+	if i := strings.Index(text, "."); i >= 0 {
+		integer, fraction = text[:i], ","+text[i+1:]
+	}
+	for i := len(integer) - 3; i > 0; i -= 3 {
+		integer = integer[:i] + "." + integer[i:]
+	}
+	return sign + integer + fraction
+}
+
+func TestNumberFormattingLocale(t *testing.T) {
+	schemaJSON := `{"properties" : {"price" : {"minimum" : 1000}, "rate" : {"maximum" : 1234.5}, "tags" : {"minItems" : 2}}}`
+
+	sl := NewSchemaLoader()
+	sl.SetLocale(germanNumberLocale{})
+	schema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"price" : 999, "rate" : 2000, "tags" : []}`))
+	require.Nil(t, err)
+	descriptions := map[string]string{}
+	for _, resultErr := range result.Errors() {
+		descriptions[resultErr.Field()] = resultErr.Description()
+	}
+	assert.Equal(t, map[string]string{
+		"price": "Must be greater than or equal to 1.000",
+		"rate":  "Must be less than or equal to 1.234,5",
+		"tags":  "Array must have at least 2 items",
+	}, descriptions)
+
+	// The details keep the numbers themselves
+	for _, resultErr := range result.Errors() {
+		if resultErr.Field() == "price" {
+			assert.Equal(t, "1000", resultErr.Details()["min"].(*big.Float).String())
+		}
+	}
+}
+
+func TestSchemaLoaderAssertFormats(t *testing.T) {
+	sl := NewSchemaLoader()
+	sl.AssertFormats = []string{"date-time", "uuid"}
+	schema, err := sl.Compile(NewStringLoader(`{
+		"properties" : {
+			"created" : {"format" : "date-time"},
+			"email" : {"format" : "email"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"created" : "yesterday", "email" : "not an email"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "created", result.Errors()[0].Field())
+		assert.Equal(t, ErrorTypeFormat, result.Errors()[0].Type())
+	}
+	if assert.Len(t, result.Annotations(), 1) {
+		assert.Equal(t, "(root).email", result.Annotations()[0].Context.String())
+		assert.Equal(t, "email", result.Annotations()[0].Value)
+	}
+
+	// Without an allowlist every format is asserted
+	result, err = Validate(NewStringLoader(`{"format" : "email"}`), NewStringLoader(`"not an email"`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+}
+
+func TestSchemaLoaderReportFormatResults(t *testing.T) {
+	sl := NewSchemaLoader()
+	sl.ReportFormatResults = true
+	sl.AssertFormats = []string{"date-time"}
+	schema, err := sl.Compile(NewStringLoader(`{
+		"properties" : {
+			"created" : {"format" : "date-time"},
+			"updated" : {"format" : "date-time"},
+			"email" : {"format" : "email"},
+			"homepage" : {"format" : "uri"},
+			"other" : {"format" : "foobar"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{
+		"created" : "2018-11-13T20:20:39+00:00",
+		"updated" : "yesterday",
+		"email" : "john@example.com",
+		"homepage" : "not a uri",
+		"other" : "anything"
+	}`))
+	require.Nil(t, err)
+
+	// Only the asserted format fails validation
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "updated", result.Errors()[0].Field())
+	}
+	formatResults := map[string]FormatResult{}
+	for _, annotation := range result.Annotations() {
+		switch annotation.Keyword {
+		case FormatResultKeyword:
+			formatResults[annotation.Context.String()] = annotation.Value.(FormatResult)
+		case KEY_FORMAT:
+			assert.IsType(t, "", annotation.Value, "the annotations of format hold the name of the format")
+		}
+	}
+	assert.Equal(t, map[string]FormatResult{
+		"(root).created":  {Format: "date-time", Valid: true},
+		"(root).updated":  {Format: "date-time", Valid: false},
+		"(root).email":    {Format: "email", Valid: true},
+		"(root).homepage": {Format: "uri", Valid: false},
+	}, formatResults, "formats without a checker are not reported")
+
+	// A checker that panics doesn't make the document invalid if its format is only an annotation
+	FormatCheckers.Add("panicking", panickingFormat{})
+	defer FormatCheckers.Remove("panicking")
+	sl = NewSchemaLoader()
+	sl.ReportFormatResults = true
+	sl.AssertFormats = []string{"date-time"}
+	schema, err = sl.Compile(NewStringLoader(`{"format" : "panicking"}`))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`"anything"`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+	for _, annotation := range result.Annotations() {
+		assert.NotEqual(t, FormatResultKeyword, annotation.Keyword)
+	}
+}
+
+func TestSchemaLoaderUnknownFormats(t *testing.T) {
+	schemaJSON := `{
+		"properties" : {
+			"a" : {"format" : "foobar"},
+			"b" : {"format" : "foobar"},
+			"c" : {"format" : "email"}
+		}
+	}`
+
+	var unknown []string
+	sl := NewSchemaLoader()
+	sl.SetUnknownFormatHook(func(format string) {
+		unknown = append(unknown, format)
+	})
+	schema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"foobar"}, unknown, "the hook is called once per format")
+
+	result, err := schema.Validate(NewStringLoader(`{"a" : "anything"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	sl = NewSchemaLoader()
+	sl.RejectUnknownFormats = true
+	_, err = sl.Compile(NewStringLoader(schemaJSON))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "No checker is registered for format foobar", err.Error())
+	}
+
+	sl = NewSchemaLoader()
+	sl.RejectUnknownFormats = true
+	_, err = sl.Compile(NewStringLoader(`{"format" : "email"}`))
+	assert.Nil(t, err)
+}
+
+func TestSchemaLoaderAllowUnresolvedRefs(t *testing.T) {
+	schemaJSON := `{
+		"properties" : {
+			"a" : {"$ref" : "#/definitions/a"},
+			"b" : {"$ref" : "#/definitions/missing"},
+			"c" : {"items" : {"$ref" : "#/definitions/missing"}}
+		},
+		"definitions" : {"a" : {"type" : "string"}}
+	}`
+
+	_, err := NewSchema(NewStringLoader(schemaJSON))
+	assert.NotNil(t, err)
+
+	sl := NewSchemaLoader()
+	sl.AllowUnresolvedRefs = true
+	schema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"#/definitions/missing"}, schema.CheckReferences())
+
+	result, err := schema.Validate(NewStringLoader(`{"a" : "x"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`{"b" : "x"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeInternal, result.Errors()[0].Type())
+		assert.Equal(t, "Internal Error Reference #/definitions/missing could not be resolved", result.Errors()[0].Description())
+	}
+
+	schema, err = NewSchema(NewStringLoader(`{"$ref" : "#/definitions/a", "definitions" : {"a" : {}}}`))
+	require.Nil(t, err)
+	assert.Empty(t, schema.CheckReferences())
+}
+
+func TestSchemaLoaderStrictIntegers(t *testing.T) {
+	schemaJSON := NewStringLoader(`{"items" : {"type" : "integer"}}`)
+
+	defaultSchema, err := NewSchema(schemaJSON)
+	require.Nil(t, err)
+
+	sl := NewSchemaLoader()
+	sl.StrictIntegers = true
+	strictSchema, err := sl.Compile(schemaJSON)
+	require.Nil(t, err)
+
+	result, err := defaultSchema.Validate(NewStringLoader(`[1, 1.0, 1e2, -3]`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = strictSchema.Validate(NewStringLoader(`[1, 1.0, 1e2, -3]`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 2) {
+		assert.Equal(t, "1", result.Errors()[0].Field())
+		assert.Equal(t, "Invalid type. Expected: integer, given: number", result.Errors()[0].Description())
+		assert.Equal(t, "2", result.Errors()[1].Field())
+	}
+
+	result, err = strictSchema.Validate(NewStringLoader(`[1.5]`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+}
+
+func TestSchemaLoaderURINormalizer(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write([]byte(`{"definitions" : {"id" : {"type" : "integer"}}}`))
+	}))
+	defer server.Close()
+
+	schemaJSON := `{
+		"properties" : {
+			"a" : {"$ref" : "` + server.URL + `/common.json#/definitions/id"},
+			"b" : {"$ref" : "` + server.URL + `/common.json/#/definitions/id"}
+		}
+	}`
+
+	_, err := NewSchemaLoader().Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	assert.Equal(t, 2, fetches)
+
+	fetches = 0
+	sl := NewSchemaLoader()
+	sl.SetURINormalizer(func(uri string) string {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return uri
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		return u.String()
+	})
+	schema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	assert.Equal(t, 1, fetches)
+
+	result, err := schema.Validate(NewStringLoader(`{"a" : 1, "b" : "x"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "b", result.Errors()[0].Field())
+	}
+}
+
+func TestSchemaLoaderResolveInstanceRefs(t *testing.T) {
+	schemaJSON := `{
+		"properties" : {
+			"billing" : {"$ref" : "#/definitions/address"},
+			"shipping" : {"$ref" : "#/definitions/address"}
+		},
+		"definitions" : {
+			"address" : {"required" : ["zip"], "properties" : {"zip" : {"type" : "string"}}}
+		}
+	}`
+	document := `{
+		"billing" : {"$ref" : "#/shared/home"},
+		"shipping" : {"$ref" : "#/shared/home"},
+		"shared" : {"home" : {"zip" : 1234}}
+	}`
+
+	schema, err := NewSchema(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	result, err := schema.Validate(NewStringLoader(document))
+	require.Nil(t, err)
+	assert.Len(t, result.Errors(), 2, "without resolving, the references lack a zip")
+
+	sl := NewSchemaLoader()
+	sl.ResolveInstanceRefs = true
+	schema, err = sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	result, err = schema.Validate(NewStringLoader(document))
+	require.Nil(t, err)
+	fields := []string{}
+	for _, resultErr := range result.Errors() {
+		assert.Equal(t, ErrorTypeInvalidType, resultErr.Type())
+		fields = append(fields, resultErr.Field())
+	}
+	assert.ElementsMatch(t, []string{"billing.zip", "shipping.zip"}, fields)
+
+	result, err = schema.Validate(NewStringLoader(`{"billing" : {"$ref" : "#/shared"}, "shared" : {"zip" : "1234"}}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	_, err = schema.Validate(NewStringLoader(`{"billing" : {"$ref" : "#/a"}, "a" : {"$ref" : "#/billing"}}`))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Circular reference #/billing in the document", err.Error())
+	}
+
+	_, err = schema.Validate(NewStringLoader(`{"billing" : {"$ref" : "#/missing"}}`))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Reference #/missing could not be resolved", err.Error())
+	}
+
+	// The resolved objects keep the order of their keys
+	sl = NewSchemaLoader()
+	sl.ResolveInstanceRefs = true
+	sl.EnforcePropertyOrder = true
+	schema, err = sl.Compile(NewStringLoader(`{"propertyOrder" : ["name", "zip"], "properties" : {"billing" : {"propertyOrder" : ["name", "zip"]}}}`))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`{"zip" : "1", "name" : "a", "billing" : {"$ref" : "#/shared"}, "shared" : {"zip" : "1", "name" : "a"}}`))
+	require.Nil(t, err)
+	fields = []string{}
+	for _, resultErr := range result.Errors() {
+		assert.Equal(t, ErrorTypePropertyOrder, resultErr.Type())
+		fields = append(fields, resultErr.Field())
+	}
+	assert.ElementsMatch(t, []string{"(root)", "billing"}, fields)
+}
+
+func TestSchemaLoaderEmptyStringAsAbsent(t *testing.T) {
+	schemaJSON := `{
+		"required" : ["name"],
+		"properties" : {
+			"name" : {"type" : "string", "minLength" : 1},
+			"address" : {"required" : ["zip"], "properties" : {"zip" : {"pattern" : "^[0-9]+$"}}}
+		}
+	}`
+	document := NewStringLoader(`{"name" : "", "address" : {"zip" : ""}}`)
+
+	schema, err := NewSchema(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	result, err := schema.Validate(document)
+	require.Nil(t, err)
+	types := map[string]string{}
+	for _, resultErr := range result.Errors() {
+		types[resultErr.Field()] = resultErr.Type()
+	}
+	assert.Equal(t, map[string]string{"name": ErrorTypeStringGTE, "address.zip": ErrorTypePattern}, types)
+
+	sl := NewSchemaLoader()
+	sl.EmptyStringAsAbsent = true
+	schema, err = sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	result, err = schema.Validate(document)
+	require.Nil(t, err)
+	types = map[string]string{}
+	for _, resultErr := range result.Errors() {
+		types[resultErr.Details()["property"].(string)] = resultErr.Type()
+	}
+	assert.Equal(t, map[string]string{"name": ErrorTypeRequired, "zip": ErrorTypeRequired}, types)
+
+	result, err = schema.Validate(NewGoLoader(map[string]interface{}{"name": "gopher", "tags": []interface{}{"", "go"}}))
+	require.Nil(t, err)
+	assert.True(t, result.Valid(), "empty strings in arrays aren't absent")
+
+	// The objects without their empty strings keep the order of their keys
+	sl = NewSchemaLoader()
+	sl.EmptyStringAsAbsent = true
+	sl.EnforcePropertyOrder = true
+	schema, err = sl.Compile(NewStringLoader(`{"propertyOrder" : ["name", "version"]}`))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`{"version" : "1.0", "description" : "", "name" : "app"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypePropertyOrder, result.Errors()[0].Type())
+	}
+}
+
+func TestSchemaLoaderSeverities(t *testing.T) {
+	schemaJSON := `{
+		"properties" : {
+			"email" : {"type" : "string", "format" : "email"},
+			"age" : {"type" : "integer"}
+		}
+	}`
+
+	sl := NewSchemaLoader()
+	sl.Severities = map[string]Severity{ErrorTypeFormat: SeverityWarning}
+	schema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"email" : "not an email", "age" : 30}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeFormat, result.Errors()[0].Type())
+		assert.Equal(t, SeverityWarning, result.Errors()[0].Severity())
+		assert.Equal(t, "warning", result.Errors()[0].Severity().String())
+	}
+
+	valid, err := schema.IsValid(NewStringLoader(`{"email" : "not an email", "age" : 30}`))
+	require.Nil(t, err)
+	assert.True(t, valid)
+
+	result, err = schema.Validate(NewStringLoader(`{"email" : "not an email", "age" : "30"}`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+	severities := map[string]Severity{}
+	for _, resultErr := range result.Errors() {
+		severities[resultErr.Field()] = resultErr.Severity()
+	}
+	assert.Equal(t, map[string]Severity{"email": SeverityWarning, "age": SeverityError}, severities)
+
+	schema, err = NewSchema(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`{"email" : "not an email"}`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+}
+
+func TestSchemaLoaderVocabularies(t *testing.T) {
+	compileWithMeta := func(vocabulary string) (*Schema, error) {
+		sl := NewSchemaLoader()
+		err := sl.AddSchema("http://localhost:1234/meta", NewStringLoader(`{
+			"$schema" : "https://json-schema.org/draft/2020-12/schema",
+			"$vocabulary" : {`+vocabulary+`}
+		}`))
+		require.Nil(t, err)
+		return sl.Compile(NewStringLoader(`{
+			"$schema" : "http://localhost:1234/meta",
+			"properties" : {"email" : {"format" : "email"}}
+		}`))
+	}
+
+	schema, err := compileWithMeta(`
+		"https://json-schema.org/draft/2020-12/vocab/core" : true,
+		"https://json-schema.org/draft/2020-12/vocab/format-assertion" : true`)
+	require.Nil(t, err)
+	result, err := schema.Validate(NewStringLoader(`{"email" : "not an email"}`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+
+	schema, err = compileWithMeta(`
+		"https://json-schema.org/draft/2020-12/vocab/core" : true,
+		"https://json-schema.org/draft/2020-12/vocab/format-annotation" : true`)
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`{"email" : "not an email"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	_, err = compileWithMeta(`
+		"https://json-schema.org/draft/2020-12/vocab/core" : true,
+		"http://localhost:1234/vocab/optional" : false`)
+	assert.Nil(t, err)
+
+	_, err = compileWithMeta(`
+		"https://json-schema.org/draft/2020-12/vocab/core" : true,
+		"http://localhost:1234/vocab/required" : true`)
+	assert.EqualError(t, err, "Vocabulary http://localhost:1234/vocab/required is required by the meta-schema but not supported")
+
+	// Vocabularies of which not all keywords are implemented can only be optional
+	_, err = compileWithMeta(`
+		"https://json-schema.org/draft/2020-12/vocab/core" : true,
+		"https://json-schema.org/draft/2020-12/vocab/applicator" : true`)
+	assert.EqualError(t, err, "Vocabulary https://json-schema.org/draft/2020-12/vocab/applicator is required by the meta-schema but not supported")
+	_, err = compileWithMeta(`
+		"https://json-schema.org/draft/2020-12/vocab/core" : true,
+		"https://json-schema.org/draft/2020-12/vocab/applicator" : false`)
+	assert.Nil(t, err)
+}
+
+func TestSchemaLoaderMaxErrorsPerField(t *testing.T) {
+	schemaJSON := `{
+		"properties" : {
+			"zip" : {"type" : "string", "minLength" : 5, "pattern" : "^[0-9]+$"},
+			"city" : {"type" : "string", "minLength" : 2}
+		}
+	}`
+	document := `{"zip" : "ab", "city" : "x"}`
+
+	schema, err := NewSchema(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	result, err := schema.Validate(NewStringLoader(document))
+	require.Nil(t, err)
+	assert.Len(t, result.Errors(), 3)
+
+	sl := NewSchemaLoader()
+	sl.MaxErrorsPerField = 1
+	schema, err = sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(document))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+	fields := map[string]int{}
+	for _, resultErr := range result.Errors() {
+		fields[resultErr.Field()]++
+	}
+	assert.Equal(t, map[string]int{"zip": 1, "city": 1}, fields)
+}
+
+func TestSchemaLoaderEnforcePropertyOrder(t *testing.T) {
+	schemaJSON := `{
+		"propertyOrder" : ["name", "version", "dependencies"],
+		"properties" : {
+			"dependencies" : {"propertyOrder" : ["a", "b"]}
+		}
+	}`
+
+	sl := NewSchemaLoader()
+	sl.EnforcePropertyOrder = true
+	schema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"name" : "app", "description" : "", "version" : "1.0", "dependencies" : {"a" : 1, "c" : 2, "b" : 3}}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`{"version" : "1.0", "name" : "app", "dependencies" : {"b" : 1, "a" : 2}}`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+	descriptions := map[string]string{}
+	for _, resultErr := range result.Errors() {
+		assert.Equal(t, ErrorTypePropertyOrder, resultErr.Type())
+		descriptions[resultErr.Field()] = resultErr.Description()
+	}
+	assert.Equal(t, map[string]string{
+		"(root)":       "Property name must appear before version",
+		"dependencies": "Property a must appear before b",
+	}, descriptions)
+
+	// The order of documents that aren't loaded from text is unknown
+	result, err = schema.Validate(NewGoLoader(map[string]interface{}{"version": "1.0", "name": "app"}))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	// Without EnforcePropertyOrder the keyword is ignored
+	schema, err = NewSchema(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`{"version" : "1.0", "name" : "app"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	sl = NewSchemaLoader()
+	sl.EnforcePropertyOrder = true
+	_, err = sl.Compile(NewStringLoader(`{"propertyOrder" : ["a", 1]}`))
+	assert.NotNil(t, err)
+}
+
+func TestSchemaLoaderOpenAPIDiscriminator(t *testing.T) {
+	schemaJSON := `{
+		"oneOf" : [
+			{"$ref" : "#/definitions/Dog"},
+			{"$ref" : "#/definitions/Cat"},
+			{"$ref" : "#/definitions/Lizard"}
+		],
+		"discriminator" : {
+			"propertyName" : "petType",
+			"mapping" : {"dog" : "#/definitions/Dog", "cat" : "#/definitions/Cat"}
+		},
+		"definitions" : {
+			"Dog" : {"properties" : {"bark" : {"type" : "boolean"}}, "required" : ["bark"]},
+			"Cat" : {"properties" : {"lives" : {"type" : "integer"}}, "required" : ["lives"]},
+			"Lizard" : {"properties" : {"scales" : {"type" : "integer"}}}
+		}
+	}`
+
+	sl := NewSchemaLoader()
+	sl.OpenAPIDiscriminator = true
+	schema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"petType" : "dog", "bark" : true}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	// Without a discriminator the document matches both Dog and Lizard, which fails oneOf
+	result, err = schema.Validate(NewStringLoader(`{"petType" : "Lizard", "scales" : 3}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid(), "branches are also selected by the name of their reference")
+
+	result, err = schema.Validate(NewStringLoader(`{"petType" : "cat", "lives" : "nine"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeInvalidType, result.Errors()[0].Type())
+		assert.Equal(t, "lives", result.Errors()[0].Field())
+	}
+
+	result, err = schema.Validate(NewStringLoader(`{"petType" : "fish"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeDiscriminator, result.Errors()[0].Type())
+		assert.Equal(t, "petType", result.Errors()[0].Field())
+		assert.Equal(t, "petType must be one of Lizard, cat, dog, given fish", result.Errors()[0].Description())
+	}
+
+	result, err = schema.Validate(NewStringLoader(`{"bark" : true}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeRequired, result.Errors()[0].Type())
+		assert.Equal(t, "petType is required", result.Errors()[0].Description())
+	}
+
+	sl = NewSchemaLoader()
+	sl.OpenAPIDiscriminator = true
+	_, err = sl.Compile(NewStringLoader(`{
+		"oneOf" : [{"$ref" : "#/definitions/Dog"}],
+		"discriminator" : {"propertyName" : "petType", "mapping" : {"cat" : "#/definitions/Cat"}},
+		"definitions" : {"Dog" : {}, "Cat" : {}}
+	}`))
+	assert.EqualError(t, err, "Reference #/definitions/Cat could not be resolved")
+}
+
+func TestSchemaLoaderURNReferences(t *testing.T) {
+	sl := NewSchemaLoader()
+	err := sl.AddSchemas(NewStringLoader(`{
+		"$id" : "urn:example:user",
+		"type" : "object",
+		"properties" : {"name" : {"$ref" : "#/definitions/name"}},
+		"required" : ["name"],
+		"definitions" : {"name" : {"type" : "string"}}
+	}`))
+	require.Nil(t, err)
+
+	schema, err := sl.Compile(NewStringLoader(`{
+		"$id" : "http://localhost:1234/order.json",
+		"properties" : {
+			"customer" : {"$ref" : "urn:example:user"},
+			"contact" : {"$ref" : "URN:Example:user#/definitions/name"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"customer" : {"name" : "John"}, "contact" : "Jane"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`{"customer" : {"name" : 1}, "contact" : 2}`))
+	require.Nil(t, err)
+	fields := map[string]string{}
+	for _, resultErr := range result.Errors() {
+		fields[resultErr.Field()] = resultErr.Type()
+	}
+	assert.Equal(t, map[string]string{"customer.name": ErrorTypeInvalidType, "contact": ErrorTypeInvalidType}, fields)
+
+	_, err = NewSchema(NewStringLoader(`{"$ref" : "urn:example:unknown"}`))
+	assert.EqualError(t, err, "Reference urn:example:unknown could not be resolved")
+}
+
+func TestSchemaLoaderSetRemoteRefsDisabled(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"type" : "string"}`))
+	}))
+	defer server.Close()
+
+	sl := NewSchemaLoader()
+	sl.SetRemoteRefsDisabled(true)
+	_, err := sl.Compile(NewStringLoader(`{"properties" : {"name" : {"$ref" : "` + server.URL + `/name.json"}}}`))
+	assert.EqualError(t, err, "Reference "+server.URL+"/name.json can't be loaded as remote references are disabled")
+	_, err = sl.Compile(NewReferenceLoader(server.URL + "/name.json"))
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, requests)
+
+	sl = NewSchemaLoader()
+	sl.SetRemoteRefsDisabled(true)
+	err = sl.AddSchema(server.URL+"/name.json", NewStringLoader(`{"type" : "string"}`))
+	require.Nil(t, err)
+	schema, err := sl.Compile(NewStringLoader(`{"properties" : {"name" : {"$ref" : "` + server.URL + `/name.json"}}}`))
+	require.Nil(t, err)
+	result, err := schema.Validate(NewStringLoader(`{"name" : 1}`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+	assert.Equal(t, 0, requests)
+
+	sl = NewSchemaLoader()
+	_, err = sl.Compile(NewStringLoader(`{"properties" : {"name" : {"$ref" : "` + server.URL + `/name.json"}}}`))
+	require.Nil(t, err)
+	assert.Equal(t, 1, requests)
+}
+
+func TestSchemaLoaderRegisterDialect(t *testing.T) {
+	const dialect = "https://example.com/schemas/my-dialect"
+	schemaLoader := NewStringLoader(`{"$schema" : "` + dialect + `", "const" : 1}`)
+
+	// Unknown dialects are parsed as the draft of the SchemaLoader, draft 4 doesn't know "const"
+	sl := NewSchemaLoader()
+	sl.Draft = Draft4
+	schema, err := sl.Compile(schemaLoader)
+	require.Nil(t, err)
+	result, err := schema.Validate(NewStringLoader(`2`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	sl = NewSchemaLoader()
+	sl.Draft = Draft4
+	sl.Validate = true
+	require.Nil(t, sl.RegisterDialect(dialect, Draft7))
+	schema, err = sl.Compile(schemaLoader)
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`2`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+
+	sl = NewSchemaLoader()
+	sl.SetRejectUnknownDialects(true)
+	_, err = sl.Compile(schemaLoader)
+	assert.EqualError(t, err, "$schema "+dialect+" is not a known dialect")
+
+	sl = NewSchemaLoader()
+	sl.SetRejectUnknownDialects(true)
+	require.Nil(t, sl.RegisterDialect(dialect, Draft7))
+	_, err = sl.Compile(schemaLoader)
+	assert.Nil(t, err)
+
+	sl = NewSchemaLoader()
+	sl.SetRejectUnknownDialects(true)
+	_, err = sl.Compile(NewStringLoader(`{"$schema" : "http://json-schema.org/draft-07/schema#"}`))
+	assert.Nil(t, err)
+}
+
+func TestResultErrorDraft(t *testing.T) {
+	sl := NewSchemaLoader()
+	err := sl.AddBundle(NewStringLoader(`[
+		{
+			"$schema" : "http://json-schema.org/draft-06/schema#",
+			"$id" : "http://localhost:1234/drafts/six.json",
+			"type" : "integer",
+			"minimum" : 5
+		},
+		{
+			"$schema" : "http://json-schema.org/draft-04/schema#",
+			"id" : "http://localhost:1234/drafts/four.json",
+			"properties" : {
+				"count" : {"maximum" : 10}
+			}
+		}
+	]`))
+	require.Nil(t, err)
+
+	schema, err := sl.Compile(NewStringLoader(`{
+		"$schema" : "http://json-schema.org/draft-07/schema#",
+		"properties" : {
+			"six" : {"$ref" : "http://localhost:1234/drafts/six.json"},
+			"four" : {"$ref" : "http://localhost:1234/drafts/four.json"},
+			"seven" : {"type" : "string"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"six" : 1, "four" : {"count" : 20}, "seven" : 7}`))
+	require.Nil(t, err)
+
+	drafts := map[string]Draft{}
+	for _, resultErr := range result.Errors() {
+		drafts[resultErr.Field()] = resultErr.Draft()
+	}
+	assert.Equal(t, map[string]Draft{
+		"six":        Draft6,
+		"four.count": Draft4,
+		"seven":      Draft7,
+	}, drafts)
+}
@@ -30,6 +30,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/xeipuuv/gojsonreference"
 )
@@ -43,22 +45,105 @@ type schemaPool struct {
 	schemaPoolDocuments map[string]*schemaPoolDocument
 	jsonLoaderFactory   JSONLoaderFactory
 	autoDetect          *bool
+	// Collects diagnostics while a schema is compiled, nil otherwise
+	stats *CompileStats
+	// Set with SchemaLoader.SetURINormalizer, nil otherwise
+	normalizeURI func(uri string) string
+	// Set with SchemaLoader.SetRemoteRefsDisabled
+	remoteRefsDisabled bool
+	// Drafts of the "$schema" URIs registered with SchemaLoader.RegisterDialect
+	dialects map[string]Draft
+	// Set with SchemaLoader.SetRejectUnknownDialects
+	rejectUnknownDialects bool
+}
+
+// parseSchemaURL returns the "$schema" of a document and the draft it stands for, like the function of the same name,
+// also looking up dialects registered with SchemaLoader.RegisterDialect. Without a draft, the caller falls back to
+// its default draft, unless unknown dialects are rejected. Meta-schemas that were added to the pool are never rejected
+func (p *schemaPool) parseSchemaURL(document interface{}) (string, *Draft, error) {
+	schema, draft, err := parseSchemaURL(document)
+	if err != nil || schema == "" || draft != nil {
+		return schema, draft, err
+	}
+	if dialect, ok := p.dialects[schema]; ok {
+		return schema, &dialect, nil
+	}
+	if p.rejectUnknownDialects && !p.hasDocument(schema) {
+		return "", nil, errors.New(formatErrorDescription(
+			Locale.UnknownDialect(),
+			ErrorDetails{"schema": schema},
+		))
+	}
+	return schema, nil, nil
+}
+
+// hasDocument reports whether the document with the given URI, with or without an empty fragment, is in the pool
+func (p *schemaPool) hasDocument(uri string) bool {
+	for _, candidate := range []string{uri, strings.TrimSuffix(uri, "#"), uri + "#"} {
+		if _, ok := p.schemaPoolDocuments[candidate]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize applies the URI normalizer to absolute references and lower-cases the case-insensitive part of URNs,
+// so equivalent identifiers share an entry in the pool
+func (p *schemaPool) normalize(ref gojsonreference.JsonReference) gojsonreference.JsonReference {
+	if isURN(ref) {
+		return normalizeURN(ref)
+	}
+	if p.normalizeURI == nil || !ref.HasFullUrl {
+		return ref
+	}
+	normalized, err := gojsonreference.NewJsonReference(p.normalizeURI(ref.String()))
+	if err != nil {
+		return ref
+	}
+	return normalized
+}
+
+// isURN reports whether the reference is a URN, i.e. urn:example:user. URNs are location independent identifiers,
+// so they can only be resolved to schemas in the pool
+func isURN(ref gojsonreference.JsonReference) bool {
+	return strings.HasPrefix(strings.ToLower(ref.String()), "urn:")
+}
+
+// isRemoteReference reports whether loading the reference requires network access
+func isRemoteReference(ref gojsonreference.JsonReference) bool {
+	scheme := strings.ToLower(ref.GetUrl().Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+// normalizeURN lower-cases the "urn" scheme and the namespace identifier, which are case-insensitive
+func normalizeURN(ref gojsonreference.JsonReference) gojsonreference.JsonReference {
+	parts := strings.SplitN(ref.String(), ":", 3)
+	if len(parts) < 3 {
+		return ref
+	}
+	normalized, err := gojsonreference.NewJsonReference("urn:" + strings.ToLower(parts[1]) + ":" + parts[2])
+	if err != nil {
+		return ref
+	}
+	return normalized
 }
 
 func (p *schemaPool) parseReferences(document interface{}, ref gojsonreference.JsonReference, pooled bool) error {
 
 	var (
-		draft     *Draft
-		err       error
-		reference = ref.String()
+		draft *Draft
+		err   error
 	)
+	ref = p.normalize(ref)
+	reference := ref.String()
+
 	// Only the root document should be added to the schema pool if pooled is true
 	if _, ok := p.schemaPoolDocuments[reference]; pooled && ok {
 		return fmt.Errorf("Reference already exists: \"%s\"", reference)
 	}
 
 	if *p.autoDetect {
-		_, draft, err = parseSchemaURL(document)
+		_, draft, err = p.parseSchemaURL(document)
 		if err != nil {
 			return err
 		}
@@ -66,6 +151,10 @@ func (p *schemaPool) parseReferences(document interface{}, ref gojsonreference.J
 
 	err = p.parseReferencesRecursive(document, ref, draft)
 
+	if pooled && p.stats != nil {
+		p.stats.DocumentsParsed++
+	}
+
 	if pooled {
 		p.schemaPoolDocuments[reference] = &schemaPoolDocument{Document: document, Draft: draft}
 	}
@@ -97,6 +186,8 @@ func (p *schemaPool) parseReferencesRecursive(document interface{}, ref gojsonre
 			if err == nil {
 				localRef, err = ref.Inherits(jsonReference)
 				if err == nil {
+					normalized := p.normalize(*localRef)
+					localRef = &normalized
 					if _, ok := p.schemaPoolDocuments[localRef.String()]; ok {
 						return fmt.Errorf("Reference already exists: \"%s\"", localRef.String())
 					}
@@ -110,7 +201,24 @@ func (p *schemaPool) parseReferencesRecursive(document interface{}, ref gojsonre
 			if err == nil {
 				absoluteRef, err := localRef.Inherits(jsonReference)
 				if err == nil {
-					m[KEY_REF] = absoluteRef.String()
+					normalized := p.normalize(*absoluteRef)
+					m[KEY_REF] = normalized.String()
+				}
+			}
+		}
+
+		// The mapping of an OpenAPI discriminator holds references as well
+		if discriminator, ok := m[KEY_DISCRIMINATOR].(map[string]interface{}); ok {
+			if mapping, ok := discriminator[KEY_MAPPING].(map[string]interface{}); ok {
+				for value, ref := range mapping {
+					if ref, ok := ref.(string); ok {
+						if jsonReference, err := gojsonreference.NewJsonReference(ref); err == nil {
+							if absoluteRef, err := localRef.Inherits(jsonReference); err == nil {
+								normalized := p.normalize(*absoluteRef)
+								mapping[value] = normalized.String()
+							}
+						}
+					}
 				}
 			}
 		}
@@ -121,8 +229,8 @@ func (p *schemaPool) parseReferencesRecursive(document interface{}, ref gojsonre
 				continue
 			}
 			// Something like a property or a dependency is not a valid schema, as it might describe properties named "$ref", "$id" or "const", etc
-			// Therefore don't treat it like a schema.
-			if k == KEY_PROPERTIES || k == KEY_DEPENDENCIES || k == KEY_PATTERN_PROPERTIES {
+			// Therefore don't treat it like a schema. The same goes for definitions, which might be named "properties" or "enum"
+			if k == KEY_PROPERTIES || k == KEY_DEPENDENCIES || k == KEY_PATTERN_PROPERTIES || k == KEY_DEFINITIONS || k == KEY_DEFS {
 				if child, ok := v.(map[string]interface{}); ok {
 					for _, v := range child {
 						p.parseReferencesRecursive(v, *localRef, draft)
@@ -145,6 +253,8 @@ func (p *schemaPool) GetDocument(reference gojsonreference.JsonReference) (*sche
 		err   error
 	)
 
+	reference = p.normalize(reference)
+
 	if internalLogEnabled {
 		internalLog("Get Document ( %s )", reference.String())
 	}
@@ -184,6 +294,14 @@ func (p *schemaPool) GetDocument(reference gojsonreference.JsonReference) (*sche
 		return spd, nil
 	}
 
+	// URNs only identify schemas, there is nothing to load them from
+	if isURN(reference) {
+		return nil, errors.New(formatErrorDescription(
+			Locale.UnresolvedReference(),
+			ErrorDetails{"reference": reference.String()},
+		))
+	}
+
 	// It is not possible to load anything remotely that is not canonical...
 	if !reference.IsCanonical() {
 		return nil, errors.New(formatErrorDescription(
@@ -192,9 +310,23 @@ func (p *schemaPool) GetDocument(reference gojsonreference.JsonReference) (*sche
 		))
 	}
 
+	if p.remoteRefsDisabled && isRemoteReference(reference) {
+		return nil, errors.New(formatErrorDescription(
+			Locale.RemoteReferencesDisabled(),
+			ErrorDetails{"reference": reference.String()},
+		))
+	}
+
+	start := time.Now()
+
 	jsonReferenceLoader := p.jsonLoaderFactory.New(reference.String())
 	document, err := jsonReferenceLoader.LoadJSON()
 
+	if p.stats != nil {
+		p.stats.RemoteFetches++
+		p.stats.FetchDuration += time.Since(start)
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -202,7 +334,7 @@ func (p *schemaPool) GetDocument(reference gojsonreference.JsonReference) (*sche
 	// add the whole document to the pool for potential re-use
 	p.parseReferences(document, refToURL, true)
 
-	_, draft, _ = parseSchemaURL(document)
+	_, draft, _ = p.parseSchemaURL(document)
 
 	// resolve the potential fragment and also cache it
 	document, _, err = reference.GetPointer().Get(document)
@@ -25,10 +25,6 @@
 
 package gojsonschema
 
-import (
-	"fmt"
-)
-
 type schemaReferencePool struct {
 	documents map[string]*subSchema
 }
@@ -44,12 +40,12 @@ func newSchemaReferencePool() *schemaReferencePool {
 func (p *schemaReferencePool) Get(ref string) (r *subSchema, o bool) {
 
 	if internalLogEnabled {
-		internalLog(fmt.Sprintf("Schema Reference ( %s )", ref))
+		internalLog("Schema Reference ( %s )", ref)
 	}
 
 	if sch, ok := p.documents[ref]; ok {
 		if internalLogEnabled {
-			internalLog(fmt.Sprintf(" From pool"))
+			internalLog(" From pool")
 		}
 		return sch, true
 	}
@@ -60,9 +56,13 @@ func (p *schemaReferencePool) Get(ref string) (r *subSchema, o bool) {
 func (p *schemaReferencePool) Add(ref string, sch *subSchema) {
 
 	if internalLogEnabled {
-		internalLog(fmt.Sprintf("Add Schema Reference %s to pool", ref))
+		internalLog("Add Schema Reference %s to pool", ref)
 	}
 	if _, ok := p.documents[ref]; !ok {
 		p.documents[ref] = sch
 	}
 }
+
+func (p *schemaReferencePool) Remove(ref string) {
+	delete(p.documents, ref)
+}
@@ -0,0 +1,127 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/xeipuuv/gojsonreference"
+)
+
+// SchemaRegistry holds compiled schemas by their id. Schemas compiled by a SchemaLoader that uses the registry
+// resolve references to these ids using the already compiled schemas, instead of loading and compiling the
+// referenced documents again
+type SchemaRegistry struct {
+	lock    sync.RWMutex
+	schemas map[string]*Schema
+}
+
+// NewSchemaRegistry creates a new, empty SchemaRegistry
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{schemas: make(map[string]*Schema)}
+}
+
+// Register adds a compiled schema to the registry under the given id, any fragment of the id is ignored
+func (r *SchemaRegistry) Register(id string, s *Schema) error {
+	ref, err := gojsonreference.NewJsonReference(id)
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	r.schemas[registryKey(ref)] = s
+	r.lock.Unlock()
+
+	return nil
+}
+
+// ValidateBySelector validates a document against the registered schema whose id is returned by selector,
+// i.e. a function that reads the id from a field of the document itself. The selector is given the loaded document
+func ValidateBySelector(doc JSONLoader, selector func(doc interface{}) (string, error), registry *SchemaRegistry) (*Result, error) {
+	// The document is loaded once, keeping the order of its properties in case the selected schema needs it
+	root, source, err := loadJSONWithSource(doc, false)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := selector(root)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := gojsonreference.NewJsonReference(id)
+	if err != nil {
+		return nil, err
+	}
+	schema, ok := registry.lookup(ref)
+	if !ok {
+		return nil, errors.New(formatErrorDescription(Locale.SchemaNotRegistered(), ErrorDetails{"id": id}))
+	}
+
+	state := &validationState{}
+	if schema.tracksPropertyOrder {
+		state.documentSource = source
+	}
+	return schema.validateDocumentWithState(root, state)
+}
+
+// lookup returns the registered schema for the document ref points into
+func (r *SchemaRegistry) lookup(ref gojsonreference.JsonReference) (*Schema, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	r.lock.RLock()
+	s, ok := r.schemas[registryKey(ref)]
+	r.lock.RUnlock()
+
+	return s, ok
+}
+
+// get returns the compiled subSchema ref points to, if it was compiled as part of a registered schema.
+// from is the schema being compiled, see lockOther
+func (r *SchemaRegistry) get(ref gojsonreference.JsonReference, from *Schema) (*subSchema, bool) {
+	s, ok := r.lookup(ref)
+	if !ok {
+		return nil, false
+	}
+
+	if ref.GetUrl().Fragment == "" {
+		return s.rootSchema, true
+	}
+
+	// Subschemas that were referenced while compiling the registered schema are already compiled
+	defer s.lockOther(from)()
+	return s.referencePool.Get(ref.String())
+}
+
+// lockOther locks the compilation of d for the schema from, that is being compiled and refers to d through a registry,
+// and returns the function that unlocks it. The pools of d grow when subschemas of custom keywords are compiled while
+// validating, so they may only be read holding its parseLock. If d is from itself, its lock is held already or not needed
+func (d *Schema) lockOther(from *Schema) func() {
+	if d == from {
+		return func() {}
+	}
+	d.parseLock.Lock()
+	return d.parseLock.Unlock
+}
+
+// registryKey returns the URL of the document ref points into
+func registryKey(ref gojsonreference.JsonReference) string {
+	// Create a deep copy, so we can remove the fragment without altering the original
+	refToURL, _ := gojsonreference.NewJsonReference(ref.String())
+	refToURL.GetUrl().Fragment = ""
+	return refToURL.String()
+}
@@ -0,0 +1,164 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"reflect"
+	"sort"
+)
+
+// schemaSource holds the order of the keys of every object in the text a document was decoded from. For schemas
+// with SchemaLoader.SchemaSnippets set it holds the text as well and where in it every keyword appears, so errors
+// can quote the keyword they originate from
+type schemaSource struct {
+	// The keys of every object in the order they appear in, by the address of the decoded map
+	order map[uintptr][]string
+	// The text and the spans of the keywords of every object, nil unless they were asked for
+	text  []byte
+	spans map[uintptr]map[string]sourceSpan
+}
+
+// sourceSpan is the range of a key and its value in the text, i.e. "minimum" : 0
+type sourceSpan struct {
+	start, end int
+}
+
+// loadJSONWithSource loads the document of loaders that hold JSON text along with its source, which only holds
+// the text and the spans of the keywords if withSpans is set. For other loaders the source is nil
+func loadJSONWithSource(loader JSONLoader, withSpans bool) (interface{}, *schemaSource, error) {
+	text, err := loaderText(loader, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if text == nil {
+		document, err := loader.LoadJSON()
+		return document, nil, err
+	}
+
+	source := &schemaSource{order: map[uintptr][]string{}}
+	if withSpans {
+		source.text = text.text
+		source.spans = map[uintptr]map[string]sourceSpan{}
+	}
+	document, err := newTokenDecoder(text.text, text.strict, source).decode()
+	if err != nil {
+		return nil, nil, err
+	}
+	return document, source, nil
+}
+
+// snippet returns the text of keyword in the object node, or an empty string if it doesn't appear in the source
+func (s *schemaSource) snippet(node interface{}, keyword string) string {
+	m, ok := node.(map[string]interface{})
+	if !ok || keyword == "" || s.spans == nil {
+		return ""
+	}
+	span, ok := s.spans[reflect.ValueOf(m).Pointer()][keyword]
+	if !ok {
+		return ""
+	}
+	return string(s.text[span.start:span.end])
+}
+
+// keys returns the keys of the object in the order they appear in the source,
+// or sorted if the source is nil or the object doesn't appear in it
+func (s *schemaSource) keys(m map[string]interface{}) []string {
+	keys := sortedKeys(m)
+	if !s.hasOrder(m) {
+		return keys
+	}
+	position := map[string]int{}
+	for i, key := range s.order[reflect.ValueOf(m).Pointer()] {
+		position[key] = i
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return position[keys[i]] < position[keys[j]]
+	})
+	return keys
+}
+
+// hasOrder reports whether the object appears in the source, so the order of its keys is known
+func (s *schemaSource) hasOrder(m map[string]interface{}) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.order[reflect.ValueOf(m).Pointer()]
+	return ok
+}
+
+// copyOrder records the order of the keys of the object from for its copy to, so the copies made of a document
+// before it is validated keep the order of its source
+func (s *schemaSource) copyOrder(from, to map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	fromPointer, toPointer := reflect.ValueOf(from).Pointer(), reflect.ValueOf(to).Pointer()
+	if keys, ok := s.order[fromPointer]; ok {
+		s.order[toPointer] = keys
+	}
+	if spans, ok := s.spans[fromPointer]; ok {
+		s.spans[toPointer] = spans
+	}
+}
+
+// errorKeywords holds the keyword that produces each type of error
+var errorKeywords = map[string]string{
+	ErrorTypeInvalidType:                  KEY_TYPE,
+	ErrorTypeRequired:                     KEY_REQUIRED,
+	ErrorTypeNumberAnyOf:                  KEY_ANY_OF,
+	ErrorTypeNumberOneOf:                  KEY_ONE_OF,
+	ErrorTypeNumberAllOf:                  KEY_ALL_OF,
+	ErrorTypeNumberNot:                    KEY_NOT,
+	ErrorTypeMissingDependency:            KEY_DEPENDENCIES,
+	ErrorTypeConst:                        KEY_CONST,
+	ErrorTypeEnum:                         KEY_ENUM,
+	ErrorTypeArrayNoAdditionalItems:       KEY_ADDITIONAL_ITEMS,
+	ErrorTypeArrayMinItems:                KEY_MIN_ITEMS,
+	ErrorTypeArrayMaxItems:                KEY_MAX_ITEMS,
+	ErrorTypeUnique:                       KEY_UNIQUE_ITEMS,
+	ErrorTypeContains:                     KEY_CONTAINS,
+	ErrorTypeArrayMinProperties:           KEY_MIN_PROPERTIES,
+	ErrorTypeArrayMaxProperties:           KEY_MAX_PROPERTIES,
+	ErrorTypeAdditionalPropertyNotAllowed: KEY_ADDITIONAL_PROPERTIES,
+	ErrorTypeInvalidPropertyName:          KEY_PROPERTY_NAMES,
+	ErrorTypeStringGTE:                    KEY_MIN_LENGTH,
+	ErrorTypeStringLTE:                    KEY_MAX_LENGTH,
+	ErrorTypePattern:                      KEY_PATTERN,
+	ErrorTypeFormat:                       KEY_FORMAT,
+	ErrorTypeMultipleOf:                   KEY_MULTIPLE_OF,
+	ErrorTypeNumberGTE:                    KEY_MINIMUM,
+	ErrorTypeNumberGT:                     KEY_EXCLUSIVE_MINIMUM,
+	ErrorTypeNumberLTE:                    KEY_MAXIMUM,
+	ErrorTypeNumberLT:                     KEY_EXCLUSIVE_MAXIMUM,
+	ErrorTypeConditionThen:                KEY_THEN,
+	ErrorTypeConditionElse:                KEY_ELSE,
+	ErrorTypePropertyOrder:                KEY_PROPERTY_ORDER,
+	ErrorTypeDiscriminator:                KEY_DISCRIMINATOR,
+}
+
+// errorKeyword returns the keyword of the schema node that produces errors of errorType. In draft 4 "exclusiveMinimum"
+// and "exclusiveMaximum" are booleans that make "minimum" and "maximum" exclusive, which are the keywords then
+func errorKeyword(errorType string, node interface{}) string {
+	keyword := errorKeywords[errorType]
+	if keyword == KEY_EXCLUSIVE_MINIMUM || keyword == KEY_EXCLUSIVE_MAXIMUM {
+		if m, ok := node.(map[string]interface{}); ok && isKind(m[keyword], reflect.Bool) {
+			if keyword == KEY_EXCLUSIVE_MINIMUM {
+				return KEY_MINIMUM
+			}
+			return KEY_MAXIMUM
+		}
+	}
+	return keyword
+}
@@ -27,14 +27,18 @@ package gojsonschema
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const displayErrorMessages = false
@@ -368,6 +372,76 @@ func TestLocationIndependentIdentifier(t *testing.T) {
 	}
 }
 
+func TestNestedIdentifierScopes(t *testing.T) {
+	// Three nested scopes: root.json, t/outer.json and t/inner.json, with references crossing them
+	s, err := NewSchema(NewStringLoader(`{
+		"$id" : "http://localhost:1234/root.json",
+		"definitions" : {
+			"foo" : {"$id" : "#foo", "type" : "integer"},
+			"outer" : {
+				"$id" : "t/outer.json",
+				"definitions" : {
+					"bar" : {"$id" : "#bar", "type" : "string"},
+					"inner" : {
+						"$id" : "inner.json",
+						"definitions" : {
+							"baz" : {"$id" : "#baz", "minimum" : 10},
+							"properties" : {
+								"$id" : "nested.json",
+								"definitions" : {"qux" : {"type" : "boolean"}},
+								"items" : {"$ref" : "#/definitions/qux"}
+							}
+						},
+						"properties" : {
+							"root" : {"$ref" : "../root.json#foo"},
+							"outer" : {"$ref" : "outer.json#bar"},
+							"inner" : {"$ref" : "#baz"},
+							"pointer" : {"$ref" : "#/definitions/baz"},
+							"absolute" : {"$ref" : "/root.json#/definitions/outer/definitions/bar"},
+							"nested" : {"$ref" : "nested.json"}
+						}
+					}
+				}
+			}
+		},
+		"properties" : {
+			"inner" : {"$ref" : "t/inner.json"},
+			"pointer" : {"$ref" : "t/outer.json#/definitions/inner/definitions/baz"},
+			"foo" : {"$ref" : "#foo"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := s.Validate(NewStringLoader(`{
+		"inner" : {"root" : 1, "outer" : "a", "inner" : 10, "pointer" : 11, "absolute" : "b", "nested" : [true]},
+		"pointer" : 12,
+		"foo" : 2
+	}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid(), "%v", result.Errors())
+
+	result, err = s.Validate(NewStringLoader(`{
+		"inner" : {"root" : "1", "outer" : 1, "inner" : 1, "pointer" : 2, "absolute" : 3, "nested" : [4]},
+		"pointer" : 5,
+		"foo" : "6"
+	}`))
+	require.Nil(t, err)
+	fields := map[string]string{}
+	for _, resultErr := range result.Errors() {
+		fields[resultErr.Field()] = resultErr.Type()
+	}
+	assert.Equal(t, map[string]string{
+		"inner.root":     ErrorTypeInvalidType,
+		"inner.outer":    ErrorTypeInvalidType,
+		"inner.inner":    ErrorTypeNumberGTE,
+		"inner.pointer":  ErrorTypeNumberGTE,
+		"inner.absolute": ErrorTypeInvalidType,
+		"inner.nested.0": ErrorTypeInvalidType,
+		"pointer":        ErrorTypeNumberGTE,
+		"foo":            ErrorTypeInvalidType,
+	}, fields)
+}
+
 const incorrectRefSchema = `{
   "$ref" : "#/fail"
 }`
@@ -380,3 +454,476 @@ func TestIncorrectRef(t *testing.T) {
 	assert.Nil(t, s)
 	assert.Equal(t, "Object has no key 'fail'", err.Error())
 }
+
+func TestDeprecatedIDWarning(t *testing.T) {
+	s, err := NewSchema(NewStringLoader(`{
+		"$schema" : "http://json-schema.org/draft-06/schema#",
+		"id" : "http://localhost/legacy.json",
+		"type" : "string"
+	}`))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"id is deprecated, use $id instead"}, s.Warnings())
+
+	// Draft 4 still uses id, so it is not deprecated there
+	s, err = NewSchema(NewStringLoader(`{
+		"$schema" : "http://json-schema.org/draft-04/schema#",
+		"id" : "http://localhost/legacy.json",
+		"type" : "string"
+	}`))
+	require.Nil(t, err)
+	assert.Empty(t, s.Warnings())
+}
+
+func TestDefsReference(t *testing.T) {
+	s, err := NewSchema(NewStringLoader(`{
+		"$schema" : "https://json-schema.org/draft/2019-09/schema",
+		"properties" : {
+			"a" : {"$ref" : "#/$defs/x"},
+			"b" : {"$ref" : "#/definitions/y"}
+		},
+		"$defs" : {
+			"x" : {"type" : "integer"}
+		},
+		"definitions" : {
+			"y" : {"type" : "string"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := s.Validate(NewStringLoader(`{"a" : 1, "b" : "y"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = s.Validate(NewStringLoader(`{"a" : "x", "b" : 2}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 2) {
+		assert.Equal(t, "a", result.Errors()[0].Field())
+		assert.Equal(t, "b", result.Errors()[1].Field())
+	}
+
+	// The subschemas in $defs are parsed like the ones in definitions
+	_, err = NewSchema(NewStringLoader(`{"$defs" : {"x" : {"type" : 5}}}`))
+	assert.NotNil(t, err)
+}
+
+func TestLintExamples(t *testing.T) {
+	s, err := NewSchema(NewStringLoader(`{
+		"type" : "object",
+		"examples" : [{"age" : 30}],
+		"properties" : {
+			"age" : {
+				"type" : "integer",
+				"minimum" : 0,
+				"examples" : [42, -1]
+			},
+			"name" : {"$ref" : "#/definitions/name"}
+		},
+		"definitions" : {
+			"name" : {"type" : "string", "examples" : ["John"]}
+		}
+	}`))
+	require.Nil(t, err)
+
+	errs := s.LintExamples()
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "Example 1 at #/properties/age is invalid")
+	}
+
+	// References are resolved against the $id of the schema the examples appear in
+	s, err = NewSchema(NewStringLoader(`{
+		"$schema" : "http://json-schema.org/draft-07/schema#",
+		"definitions" : {
+			"code" : {"type" : "string"},
+			"nested" : {
+				"$id" : "http://example.com/nested.json",
+				"definitions" : {"code" : {"type" : "integer"}},
+				"properties" : {"code" : {"allOf" : [{"$ref" : "#/definitions/code"}], "examples" : [1, "A1"]}}
+			}
+		},
+		"properties" : {"legacy" : {"id" : "legacy", "examples" : [true]}}
+	}`))
+	require.Nil(t, err)
+	warnings := s.Warnings()
+
+	errs = s.LintExamples()
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "Example 1 at #/definitions/nested/properties/code is invalid")
+	}
+	assert.Equal(t, warnings, s.Warnings(), "the compiled schema is linted, it isn't compiled again")
+}
+
+func TestLintConstraints(t *testing.T) {
+	s, err := NewSchema(NewStringLoader(`{
+		"type" : "object",
+		"properties" : {
+			"age" : {"type" : "integer", "minimum" : 5, "maximum" : 3},
+			"name" : {"type" : "string", "minimum" : 3, "minLength" : 1},
+			"score" : {"type" : ["integer", "null"], "exclusiveMinimum" : 1, "exclusiveMaximum" : 2},
+			"ratio" : {"exclusiveMinimum" : 1, "maximum" : 1}
+		}
+	}`))
+	require.Nil(t, err)
+
+	var messages []string
+	for _, err := range s.LintConstraints() {
+		messages = append(messages, err.Error())
+	}
+	assert.Equal(t, []string{
+		"minimum and maximum at #/properties/age can never both be satisfied",
+		"minimum at #/properties/name has no effect for type string",
+		"exclusiveMinimum and maximum at #/properties/ratio can never both be satisfied",
+	}, messages)
+
+	s, err = NewSchema(NewStringLoader(`{"type" : "number", "minimum" : 1, "maximum" : 1}`))
+	require.Nil(t, err)
+	assert.Empty(t, s.LintConstraints())
+}
+
+func TestMetadataThroughRef(t *testing.T) {
+	schemaJSON := `{
+		"title" : "Person",
+		"properties" : {
+			"address" : {"$ref" : "#/definitions/address"},
+			"home" : {"$ref" : "#/definitions/address", "description" : "Where the person lives"},
+			"loop" : {"$ref" : "#/definitions/a"}
+		},
+		"definitions" : {
+			"address" : {
+				"title" : "Address",
+				"description" : "A postal address",
+				"properties" : {"street" : {"title" : "Street"}}
+			},
+			"a" : {"$ref" : "#/definitions/b"},
+			"b" : {"$ref" : "#/definitions/a"}
+		}
+	}`
+
+	sl := NewSchemaLoader()
+	sl.ResolveMetadataRefs = true
+	s, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	metadata, ok := s.Metadata()
+	assert.True(t, ok)
+	assert.Equal(t, SchemaMetadata{Title: "Person"}, metadata)
+
+	metadata, ok = s.Metadata("address")
+	assert.True(t, ok)
+	assert.Equal(t, SchemaMetadata{Title: "Address", Description: "A postal address"}, metadata)
+
+	metadata, ok = s.Metadata("home")
+	assert.True(t, ok)
+	assert.Equal(t, SchemaMetadata{Title: "Address", Description: "Where the person lives"}, metadata)
+
+	metadata, ok = s.Metadata("address", "street")
+	assert.True(t, ok)
+	assert.Equal(t, SchemaMetadata{Title: "Street"}, metadata)
+
+	metadata, ok = s.Metadata("loop")
+	assert.True(t, ok)
+	assert.Equal(t, SchemaMetadata{}, metadata)
+
+	_, ok = s.Metadata("unknown")
+	assert.False(t, ok)
+
+	// Without the option only the property's own metadata is reported
+	s, err = NewSchema(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	metadata, ok = s.Metadata("address")
+	assert.True(t, ok)
+	assert.Equal(t, SchemaMetadata{}, metadata)
+}
+
+func TestSchemaForPath(t *testing.T) {
+	s, err := NewSchema(NewStringLoader(simpleSchema))
+	require.Nil(t, err)
+
+	age, err := s.SchemaForPath("age")
+	require.Nil(t, err)
+	expected, _ := NewStringLoader(`{"description" : "Age in years", "type" : "integer", "minimum" : 0}`).LoadJSON()
+	assert.Equal(t, expected, age)
+
+	_, err = s.SchemaForPath("age.years")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "The schema does not describe age.years", err.Error())
+	}
+
+	s, err = NewSchema(NewStringLoader(`{
+		"properties" : {
+			"address" : {"$ref" : "#/definitions/address"},
+			"tags" : {"items" : {"type" : "string"}},
+			"point" : {"items" : [{"title" : "x"}, {"title" : "y"}], "additionalItems" : {"title" : "extra"}}
+		},
+		"patternProperties" : {"^x-" : {"title" : "extension"}},
+		"definitions" : {
+			"address" : {"properties" : {"zip" : {"title" : "zip"}}}
+		}
+	}`))
+	require.Nil(t, err)
+
+	for path, title := range map[string]string{
+		"address.zip": "zip",
+		"point.1":     "y",
+		"point.5":     "extra",
+		"x-vendor":    "extension",
+	} {
+		subschema, err := s.SchemaForPath(path)
+		if assert.Nil(t, err, path) {
+			assert.Equal(t, title, subschema.(map[string]interface{})["title"], path)
+		}
+	}
+
+	for _, path := range []string{"tags.*", "tags.3"} {
+		subschema, err := s.SchemaForPath(path)
+		if assert.Nil(t, err, path) {
+			assert.Equal(t, map[string]interface{}{"type": "string"}, subschema, path)
+		}
+	}
+
+	_, err = s.SchemaForPath("point.*")
+	assert.NotNil(t, err)
+}
+
+func TestInstancePaths(t *testing.T) {
+	s, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"address" : {"$ref" : "#/definitions/address"},
+			"items" : {
+				"type" : "array",
+				"items" : {
+					"properties" : {"name" : {"type" : "string"}},
+					"allOf" : [{"properties" : {"price" : {"type" : "number"}}}]
+				}
+			},
+			"point" : {"items" : [{"title" : "x"}, {"title" : "y"}]},
+			"category" : {"$ref" : "#/definitions/category"}
+		},
+		"definitions" : {
+			"address" : {"properties" : {"zip" : {"type" : "string"}, "city" : {"type" : "string"}}},
+			"category" : {
+				"properties" : {
+					"name" : {"type" : "string"},
+					"children" : {"items" : {"$ref" : "#/definitions/category"}}
+				}
+			}
+		}
+	}`))
+	require.Nil(t, err)
+
+	assert.Equal(t, []string{
+		"address",
+		"address.city",
+		"address.zip",
+		"category",
+		"category.children",
+		"category.children[*]",
+		"category.children[*].**",
+		"category.name",
+		"items",
+		"items[*]",
+		"items[*].name",
+		"items[*].price",
+		"point",
+		"point[0]",
+		"point[1]",
+	}, s.InstancePaths())
+}
+
+func TestPropertyNames(t *testing.T) {
+	s, err := NewSchema(NewStringLoader(simpleSchema))
+	require.Nil(t, err)
+
+	names, err := s.PropertyNames("")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"firstName", "lastName", "age"}, names)
+
+	names, err = s.PropertyNames("age")
+	require.Nil(t, err)
+	assert.Empty(t, names)
+
+	_, err = s.PropertyNames("address")
+	assert.NotNil(t, err)
+
+	var document interface{}
+	require.Nil(t, json.Unmarshal([]byte(simpleSchema), &document))
+	s, err = NewSchema(NewGoLoader(document))
+	require.Nil(t, err)
+
+	names, err = s.PropertyNames("")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"age", "firstName", "lastName"}, names, "without the text the names are sorted")
+}
+
+func TestComments(t *testing.T) {
+	s, err := NewSchema(NewStringLoader(`{
+		"$comment" : "root",
+		"properties" : {
+			"age" : {"$comment" : "ui:slider", "type" : "integer"},
+			"tags" : {"items" : {"$comment" : "ui:chip"}}
+		},
+		"definitions" : {"unused" : {"$comment" : "kept for v1 clients"}}
+	}`))
+	require.Nil(t, err)
+
+	assert.Equal(t, map[string]string{
+		"#":                       "root",
+		"#/properties/age":        "ui:slider",
+		"#/properties/tags/items": "ui:chip",
+		"#/definitions/unused":    "kept for v1 clients",
+	}, s.Comments())
+
+	metadata, ok := s.Metadata("age")
+	assert.True(t, ok)
+	assert.Equal(t, "ui:slider", metadata.Comment)
+
+	// Comments never affect validation
+	result, err := s.Validate(NewStringLoader(`{"age" : 5, "tags" : ["x"]}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	_, err = NewSchema(NewStringLoader(`{"$schema" : "http://json-schema.org/draft-07/schema#", "$comment" : 5}`))
+	assert.NotNil(t, err)
+}
+
+func TestCompileCached(t *testing.T) {
+	schemaJSON := `{"type" : "object", "required" : ["name"]}`
+
+	first, err := CompileCached(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	second, err := CompileCached(NewBytesLoader([]byte(schemaJSON)))
+	require.Nil(t, err)
+	assert.True(t, first == second, "the same source should return the same compiled schema")
+
+	other, err := CompileCached(NewStringLoader(`{"type" : "object", "required" : ["id"]}`))
+	require.Nil(t, err)
+	assert.False(t, first == other)
+
+	_, err = CompileCached(NewStringLoader(`{"type" : 1}`))
+	assert.NotNil(t, err)
+
+	cache := NewSchemaCache(1)
+	first, err = cache.CompileCached(NewGoLoader(map[string]interface{}{"type": "string"}))
+	require.Nil(t, err)
+	second, err = cache.CompileCached(NewStringLoader(`{"type" : "string"}`))
+	require.Nil(t, err)
+	assert.False(t, first == second, "the source of the string loader differs from the canonical JSON")
+	assert.Equal(t, 1, cache.Len())
+	third, err := cache.CompileCached(NewGoLoader(map[string]interface{}{"type": "string"}))
+	require.Nil(t, err)
+	assert.False(t, first == third, "the least recently used schema should have been evicted")
+	assert.Equal(t, 1, cache.Len())
+
+	result, err := third.Validate(NewStringLoader(`"text"`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	// Documents loaded by reference are only loaded to compile them
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(schemaJSON))
+	}))
+	defer server.Close()
+	cache = NewSchemaCache(0)
+	first, err = cache.CompileCached(NewReferenceLoader(server.URL + "/schema.json"))
+	require.Nil(t, err)
+	second, err = cache.CompileCached(NewReferenceLoader(server.URL + "/schema.json"))
+	require.Nil(t, err)
+	assert.True(t, first == second)
+	assert.Equal(t, 1, requests)
+}
+
+func TestAllowedValues(t *testing.T) {
+	s, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"color" : {"enum" : ["red", "green", "blue"]},
+			"size" : {"enum" : [1, 2.5, null]},
+			"kind" : {"const" : {"a" : true}},
+			"name" : {"type" : "string"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	assert.Equal(t, map[string][]interface{}{
+		"#/properties/color": {"red", "green", "blue"},
+		"#/properties/size":  {json.Number("1"), json.Number("2.5"), nil},
+		"#/properties/kind":  {map[string]interface{}{"a": true}},
+	}, s.AllowedValues())
+
+	// Unknown keywords aren't schemas and "const" is only known since draft 6
+	s, err = NewSchema(NewStringLoader(`{
+		"$schema" : "http://json-schema.org/draft-04/schema#",
+		"x-choices" : {"enum" : ["a", "b"]},
+		"properties" : {
+			"kind" : {"const" : "a", "enum" : ["a", "b"]}
+		}
+	}`))
+	require.Nil(t, err)
+	assert.Equal(t, map[string][]interface{}{"#/properties/kind": {"a", "b"}}, s.AllowedValues())
+}
+
+func TestValidateAgainstMetaSchema(t *testing.T) {
+	// A numeric exclusiveMinimum and "if" are only known since draft 6 and 7, draft 4 expects a boolean
+	schema := NewStringLoader(`{
+		"$schema" : "http://json-schema.org/draft-07/schema#",
+		"exclusiveMinimum" : 5,
+		"if" : {"type" : "integer"}
+	}`)
+
+	result, err := ValidateAgainstMetaSchema(schema, Draft7)
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = ValidateAgainstMetaSchema(schema, Draft4)
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+	if errs := result.FilterByType("invalid_type"); assert.Len(t, errs, 1) {
+		assert.Equal(t, "exclusiveMinimum", errs[0].Field())
+	}
+
+	_, err = ValidateAgainstMetaSchema(schema, Hybrid)
+	assert.NotNil(t, err)
+}
+
+func TestSchemaFingerprint(t *testing.T) {
+	fingerprint := func(schemaJSON string, address string) string {
+		sl := NewSchemaLoader()
+		require.Nil(t, sl.AddSchema("http://example.com/address.json", NewStringLoader(address)))
+		schema, err := sl.Compile(NewStringLoader(schemaJSON))
+		require.Nil(t, err)
+		return schema.Fingerprint()
+	}
+	address := `{"type" : "object", "required" : ["city"]}`
+
+	original := fingerprint(`{
+		"type" : "object",
+		"properties" : {
+			"age" : {"type" : "integer", "minimum" : 0},
+			"address" : {"$ref" : "http://example.com/address.json"}
+		}
+	}`, address)
+	assert.Len(t, original, 64)
+
+	reordered := fingerprint(`{"properties":{"address":{"$ref":"http://example.com/address.json"},
+		"age":{"minimum":0.0,"type":"integer"}},"type":"object"}`, `{"required":["city"],"type":"object"}`)
+	assert.Equal(t, original, reordered)
+
+	changed := fingerprint(`{
+		"type" : "object",
+		"properties" : {
+			"age" : {"type" : "integer", "minimum" : 1},
+			"address" : {"$ref" : "http://example.com/address.json"}
+		}
+	}`, address)
+	assert.NotEqual(t, original, changed)
+
+	changedReference := fingerprint(`{
+		"type" : "object",
+		"properties" : {
+			"age" : {"type" : "integer", "minimum" : 0},
+			"address" : {"$ref" : "http://example.com/address.json"}
+		}
+	}`, `{"type" : "object", "required" : ["street"]}`)
+	assert.NotEqual(t, original, changedReference, "referenced documents are part of the fingerprint")
+}
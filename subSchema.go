@@ -29,17 +29,22 @@ package gojsonschema
 import (
 	"github.com/xeipuuv/gojsonreference"
 	"math/big"
-	"regexp"
 )
 
 // Constants
 const (
 	KEY_SCHEMA                = "$schema"
+	KEY_VOCABULARY            = "$vocabulary"
 	KEY_ID                    = "id"
 	KEY_ID_NEW                = "$id"
 	KEY_REF                   = "$ref"
 	KEY_TITLE                 = "title"
 	KEY_DESCRIPTION           = "description"
+	KEY_DEFAULT               = "default"
+	KEY_READ_ONLY             = "readOnly"
+	KEY_WRITE_ONLY            = "writeOnly"
+	KEY_EXAMPLES              = "examples"
+	KEY_COMMENT               = "$comment"
 	KEY_TYPE                  = "type"
 	KEY_ITEMS                 = "items"
 	KEY_ADDITIONAL_ITEMS      = "additionalItems"
@@ -48,6 +53,7 @@ const (
 	KEY_ADDITIONAL_PROPERTIES = "additionalProperties"
 	KEY_PROPERTY_NAMES        = "propertyNames"
 	KEY_DEFINITIONS           = "definitions"
+	KEY_DEFS                  = "$defs"
 	KEY_MULTIPLE_OF           = "multipleOf"
 	KEY_MINIMUM               = "minimum"
 	KEY_MAXIMUM               = "maximum"
@@ -65,6 +71,11 @@ const (
 	KEY_MAX_ITEMS             = "maxItems"
 	KEY_UNIQUE_ITEMS          = "uniqueItems"
 	KEY_CONTAINS              = "contains"
+	KEY_UNEVALUATED_ITEMS     = "unevaluatedItems"
+	KEY_PROPERTY_ORDER        = "propertyOrder"
+	KEY_DISCRIMINATOR         = "discriminator"
+	KEY_PROPERTY_NAME         = "propertyName"
+	KEY_MAPPING               = "mapping"
 	KEY_CONST                 = "const"
 	KEY_ENUM                  = "enum"
 	KEY_ONE_OF                = "oneOf"
@@ -83,9 +94,16 @@ type subSchema struct {
 	id          *gojsonreference.JsonReference
 	title       *string
 	description *string
+	comment     *string
 
 	property string
 
+	// The JSON this subSchema was parsed from
+	documentNode interface{}
+	// The keywords leading from the parent to this subSchema, i.e. ["properties", "name"] or ["anyOf", 0].
+	// Nil if the subSchema is held by a keyword directly, in which case that keyword is the property
+	location []interface{}
+
 	// Quick pass/fail for boolean schemas
 	pass *bool
 
@@ -96,12 +114,15 @@ type subSchema struct {
 	ref *gojsonreference.JsonReference
 	// Schema referenced
 	refSchema *subSchema
+	// Whether the reference could not be resolved, see SchemaLoader.AllowUnresolvedRefs
+	unresolvedRef bool
 
 	// hierarchy
 	parent                      *subSchema
 	itemsChildren               []*subSchema
 	itemsChildrenIsSingleSchema bool
 	propertiesChildren          []*subSchema
+	definitionsChildren         []*subSchema
 
 	// validation : number / integer
 	multipleOf       *big.Rat
@@ -111,20 +132,23 @@ type subSchema struct {
 	exclusiveMinimum *big.Rat
 
 	// validation : string
-	minLength *int
-	maxLength *int
-	pattern   *regexp.Regexp
-	format    string
+	minLength     *int
+	maxLength     *int
+	pattern       Regexp
+	patternString string
+	format        string
 
 	// validation : object
 	minProperties *int
 	maxProperties *int
 	required      []string
 
-	dependencies         map[string]interface{}
-	additionalProperties interface{}
-	patternProperties    map[string]*subSchema
-	propertyNames        *subSchema
+	dependencies             map[string]interface{}
+	additionalProperties     interface{}
+	patternProperties        map[string]*subSchema
+	patternPropertiesRegexps map[string]Regexp
+	propertyNames            *subSchema
+	propertyOrder            []string
 
 	// validation : array
 	minItems    *int
@@ -132,11 +156,14 @@ type subSchema struct {
 	uniqueItems bool
 	contains    *subSchema
 
-	additionalItems interface{}
+	additionalItems  interface{}
+	unevaluatedItems *subSchema
 
 	// validation : all
-	_const *string //const is a golang keyword
-	enum   []string
+	_const     *string //const is a golang keyword
+	enum       []string
+	enumValues []interface{}   // enum as it appears in the schema
+	enumSet    map[string]bool // enum as a set, so large enums can be checked in constant time
 
 	// validation : subSchema
 	oneOf []*subSchema
@@ -146,4 +173,50 @@ type subSchema struct {
 	_if   *subSchema // if/else are golang keywords
 	_then *subSchema
 	_else *subSchema
+
+	// OpenAPI discriminator, see SchemaLoader.OpenAPIDiscriminator
+	discriminator *discriminator
+
+	// custom keywords, see CustomKeywords
+	customKeywords []customKeywordUse
+}
+
+// discriminator selects the branch of "oneOf" or "anyOf" to validate an object against by the value of one of its properties
+type discriminator struct {
+	property string
+	keyword  string
+	branches map[string]*subSchema
+}
+
+// relativeLocation returns the keywords leading from the parent to the subSchema, which are none for the root
+func (s *subSchema) relativeLocation() []interface{} {
+	if s.parent == nil {
+		return nil
+	}
+	if s.location != nil {
+		return s.location
+	}
+	return []interface{}{s.property}
+}
+
+// describe returns a short human readable description of the subSchema, used in error messages.
+// Referenced schemas are described by their title or resolved id, other schemas by their title or
+// otherwise their JSON representation
+func (s *subSchema) describe() string {
+	if s.refSchema != nil {
+		if s.refSchema.title != nil {
+			return *s.refSchema.title
+		}
+		if s.refSchema.id != nil {
+			return s.refSchema.id.String()
+		}
+		return s.ref.String()
+	}
+	if s.title != nil {
+		return *s.title
+	}
+	if str, err := marshalToJSONString(s.documentNode); err == nil {
+		return *str
+	}
+	return STRING_UNDEFINED
 }
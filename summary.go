@@ -0,0 +1,53 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+// Summary holds the aggregate outcome of validating several documents, see ValidateAll
+type Summary struct {
+	Total  int
+	Passed int
+	Failed int
+	// Errored counts the documents that could not be loaded or validated, their result is nil
+	Errored int
+	// ErrorTypes counts the errors of the failed documents by their type, e.g. "required"
+	ErrorTypes map[string]int
+}
+
+// ValidateAll validates every document against the schema and summarizes the outcome.
+// The results are in the same order as the loaders
+func ValidateAll(s *Schema, loaders []JSONLoader) (Summary, []*Result) {
+	summary := Summary{Total: len(loaders), ErrorTypes: map[string]int{}}
+	results := make([]*Result, len(loaders))
+
+	for i, loader := range loaders {
+		result, err := s.Validate(loader)
+		if err != nil {
+			summary.Errored++
+			continue
+		}
+		results[i] = result
+
+		if result.Valid() {
+			summary.Passed++
+			continue
+		}
+		summary.Failed++
+		for _, resultErr := range result.Errors() {
+			summary.ErrorTypes[resultErr.Type()]++
+		}
+	}
+
+	return summary, results
+}
@@ -26,11 +26,19 @@
 package gojsonschema
 
 import (
+	"bytes"
 	"encoding/json"
 	"math/big"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
+// jsonPointerEscaper escapes a reference token of a JSON Pointer, see RFC 6901
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
 func isKind(what interface{}, kinds ...reflect.Kind) bool {
 	target := what
 	if isJSONNumber(what) {
@@ -51,6 +59,16 @@ func existsMapKey(m map[string]interface{}, k string) bool {
 	return ok
 }
 
+// sortedKeys returns the keys of m in sorted order, to iterate over a map deterministically
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func isStringInSlice(s []string, what string) bool {
 	for i := range s {
 		if s[i] == what {
@@ -103,6 +121,178 @@ func marshalWithoutNumber(value interface{}) (*string, error) {
 	return marshalToJSONString(document)
 }
 
+// marshalCanonical marshals a document to a canonical form: object keys are sorted, there is no
+// insignificant whitespace and numbers are normalized, so equal documents always give the same bytes.
+// Integers are written without fraction or exponent, other numbers as exact decimals
+func marshalCanonical(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(canonicalizeNumbers(value)); err != nil {
+		return nil, err
+	}
+
+	// Encode always appends a newline
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func canonicalizeNumbers(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		return canonicalNumber(value)
+	case []interface{}:
+		canonical := make([]interface{}, len(value))
+		for i, v := range value {
+			canonical[i] = canonicalizeNumbers(v)
+		}
+		return canonical
+	case map[string]interface{}:
+		canonical := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			canonical[k] = canonicalizeNumbers(v)
+		}
+		return canonical
+	}
+	return value
+}
+
+// canonicalNumber normalizes a number, integers are written without fraction or exponent,
+// other numbers as exact decimals without trailing zeros
+func canonicalNumber(value json.Number) json.Number {
+	// Most numbers are small integers, which don't need the precision of big.Rat
+	if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+		return json.Number(strconv.FormatInt(i, 10))
+	}
+	if number, ok := new(big.Rat).SetString(string(value)); ok {
+		if number.IsInt() {
+			return json.Number(number.Num().String())
+		}
+		return json.Number(number.FloatString(decimalPlaces(number.Denom())))
+	}
+	return value
+}
+
+// decimalPlaces returns the number of decimals needed to write a fraction with denominator
+// exactly. The denominator of a JSON number only has the factors 2 and 5
+func decimalPlaces(denominator *big.Int) int {
+	places := 0
+	d := new(big.Int).Set(denominator)
+	ten, two, five := big.NewInt(10), big.NewInt(2), big.NewInt(5)
+	for mod := new(big.Int); d.Cmp(big.NewInt(1)) > 0; places++ {
+		switch {
+		case mod.Mod(d, ten).Sign() == 0:
+			d.Quo(d, ten)
+		case mod.Mod(d, two).Sign() == 0:
+			d.Quo(d, two)
+		default:
+			d.Quo(d, five)
+		}
+	}
+	return places
+}
+
+// writeComparable writes an encoding of value to buf that is the same for values that have the same canonical form,
+// see marshalCanonical, and different otherwise. It is not JSON, but it is cheaper to build for comparing values
+func writeComparable(buf *bytes.Buffer, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		buf.WriteByte('n')
+	case bool:
+		if value {
+			buf.WriteByte('t')
+		} else {
+			buf.WriteByte('f')
+		}
+	case string:
+		writeComparableString(buf, value)
+	case json.Number:
+		buf.WriteByte('d')
+		buf.WriteString(string(canonicalNumber(value)))
+		buf.WriteByte(';')
+	case []interface{}:
+		buf.WriteByte('[')
+		for _, item := range value {
+			if err := writeComparable(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		buf.WriteByte('{')
+		for _, k := range sortedKeys(value) {
+			writeComparableString(buf, k)
+			if err := writeComparable(buf, value[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		// Values that weren't decoded from JSON, i.e. by NewRawLoader, are compared as the JSON they marshal to
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		decoded, err := decodeJSONUsingNumber(bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		return writeComparable(buf, decoded)
+	}
+	return nil
+}
+
+// writeComparableString writes the length before the string, so it can't be confused with what follows
+func writeComparableString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('s')
+	buf.WriteString(strconv.Itoa(len(s)))
+	buf.WriteByte(':')
+	buf.WriteString(s)
+}
+
+// closestString returns the string in values that is closest to s by edit distance, as a suggestion for a typo.
+// Only strings that differ in at most a third of the characters of s, and at least one, are considered
+func closestString(s string, values []interface{}) (string, bool) {
+	maxDistance := utf8.RuneCountInString(s) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+
+	closest, closestDistance := "", maxDistance+1
+	for _, v := range values {
+		candidate, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if distance := editDistance(s, candidate); distance < closestDistance {
+			closest, closestDistance = candidate, distance
+		}
+	}
+	return closest, closestDistance <= maxDistance
+}
+
+// editDistance returns the Levenshtein distance between a and b, counted in runes
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
 func isJSONNumber(what interface{}) bool {
 
 	switch what.(type) {
@@ -124,6 +314,11 @@ func checkJSONInteger(what interface{}) (isInt bool) {
 
 }
 
+// isIntegerLiteral reports whether the number is written without a fraction or exponent
+func isIntegerLiteral(number json.Number) bool {
+	return !strings.ContainsAny(string(number), ".eE")
+}
+
 // same as ECMA Number.MAX_SAFE_INTEGER and Number.MIN_SAFE_INTEGER
 const (
 	maxJSONFloat = float64(1<<53 - 1)  // 9007199254740991.0 	 2^53 - 1
@@ -26,10 +26,12 @@
 package gojsonschema
 
 import (
+	"bytes"
 	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCheckJsonNumber(t *testing.T) {
@@ -57,3 +59,23 @@ func TestCheckJsonNumber(t *testing.T) {
 	}
 
 }
+
+func TestWriteComparable(t *testing.T) {
+	comparable := func(value interface{}) string {
+		var buf bytes.Buffer
+		require.Nil(t, writeComparable(&buf, value))
+		return buf.String()
+	}
+
+	assert.Equal(t, comparable(json.Number("1")), comparable(json.Number("1.0")))
+	assert.Equal(t, comparable(json.Number("1")), comparable(1.0))
+	assert.Equal(t, comparable(json.Number("25e-1")), comparable(json.Number("2.50")))
+	assert.Equal(t, comparable(map[string]interface{}{"a": "b", "c": nil}), comparable(map[string]interface{}{"c": nil, "a": "b"}))
+	assert.Equal(t, comparable([]interface{}{map[string]interface{}{"x": 1}}), comparable([]interface{}{map[string]interface{}{"x": json.Number("1")}}))
+
+	assert.NotEqual(t, comparable("1"), comparable(json.Number("1")))
+	assert.NotEqual(t, comparable([]interface{}{"ab", "c"}), comparable([]interface{}{"a", "bc"}))
+	assert.NotEqual(t, comparable(map[string]interface{}{"a": "b"}), comparable(map[string]interface{}{"ab": ""}))
+	assert.NotEqual(t, comparable(true), comparable("t"))
+	assert.NotEqual(t, comparable(nil), comparable("n"))
+}
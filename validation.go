@@ -26,12 +26,15 @@
 package gojsonschema
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"hash/fnv"
 	"math/big"
 	"reflect"
-	"regexp"
-	"strconv"
+	"sort"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -47,22 +50,299 @@ func Validate(ls JSONLoader, ld JSONLoader) (*Result, error) {
 
 // Validate loads and validates a JSON document
 func (v *Schema) Validate(l JSONLoader) (*Result, error) {
-	root, err := l.LoadJSON()
+	state := &validationState{}
+	root, err := v.loadDocument(l, state)
 	if err != nil {
 		return nil, err
 	}
-	return v.validateDocument(root), nil
+	return v.validateDocumentWithState(root, state)
 }
 
-func (v *Schema) validateDocument(root interface{}) *Result {
-	result := &Result{}
+// loadDocument loads the document to validate. The order of the keys in the document is only needed by "propertyOrder",
+// so it is only kept for schemas that use it
+func (v *Schema) loadDocument(l JSONLoader, state *validationState) (interface{}, error) {
+	if !v.tracksPropertyOrder {
+		return l.LoadJSON()
+	}
+	root, source, err := loadJSONWithSource(l, false)
+	state.documentSource = source
+	return root, err
+}
+
+// ValidateString validates a JSON document held in a string, see NewStringLoader
+func (v *Schema) ValidateString(s string) (*Result, error) {
+	return v.Validate(NewStringLoader(s))
+}
+
+// ValidateBytes validates a JSON document held in a byte slice, see NewBytesLoader
+func (v *Schema) ValidateBytes(b []byte) (*Result, error) {
+	return v.Validate(NewBytesLoader(b))
+}
+
+// ValidateCanonical loads and validates a JSON document and also returns the document in canonical form:
+// object keys are sorted, insignificant whitespace is removed and numbers are normalized, so documents
+// that are semantically equal give the same bytes
+func (v *Schema) ValidateCanonical(l JSONLoader) (*Result, []byte, error) {
+	state := &validationState{}
+	root, err := v.loadDocument(l, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	canonical, err := marshalCanonical(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := v.validateDocumentWithState(root, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, canonical, nil
+}
+
+// IsValid loads a JSON document and reports whether it is valid. It stops validating a (sub)schema at its first error
+// and doesn't build descriptions for errors, so it is faster than Validate when only the outcome matters
+func (v *Schema) IsValid(l JSONLoader) (bool, error) {
+	// Failing fast doesn't tell errors and warnings apart, so it is only possible without severities
+	state := &validationState{failFast: v.severities == nil}
+	root, err := v.loadDocument(l, state)
+	if err != nil {
+		return false, err
+	}
+	result, err := v.validateDocumentWithState(root, state)
+	if err != nil {
+		return false, err
+	}
+	return result.Valid(), nil
+}
+
+// ValidatePartial loads and validates a JSON document that only holds part of the data, like a JSON Merge Patch.
+// The "required" keyword is ignored throughout the document, all other keywords are applied to the values present
+func (v *Schema) ValidatePartial(l JSONLoader) (*Result, error) {
+	state := &validationState{partial: true}
+	root, err := v.loadDocument(l, state)
+	if err != nil {
+		return nil, err
+	}
+	return v.validateDocumentWithState(root, state)
+}
+
+// ValidateWithTimeout loads and validates a JSON document, but returns an error instead of a result
+// if validating takes longer than the given duration. The elapsed time is checked in between keywords,
+// so a single slow keyword like a custom format checker is not interrupted
+func (v *Schema) ValidateWithTimeout(l JSONLoader, d time.Duration) (*Result, error) {
+	state := &validationState{deadline: time.Now().Add(d)}
+	root, err := v.loadDocument(l, state)
+	if err != nil {
+		return nil, err
+	}
+	result, err := v.validateDocumentWithState(root, state)
+	if err != nil {
+		return nil, err
+	}
+	if state.timedOut {
+		return nil, errors.New(formatErrorDescription(
+			state.locale().ValidationTimeout(),
+			ErrorDetails{"timeout": d},
+		))
+	}
+	return result, nil
+}
+
+// validationState holds the state of a single validation run.
+// It is shared by the result of the run and the results of all subschema validations
+type validationState struct {
+	schema   *Schema
+	deadline time.Time
+	timedOut bool
+	// Whether the document is a partial update, in which case "required" is not checked
+	partial bool
+	// JSON Pointers of the visited nodes, only tracked when a validation hook is set
+	visited map[string]bool
+	// The first node found that is nested deeper than the schema's maximum depth
+	tooDeep *JsonContext
+	// Whether only the validity of the document matters, in which case errors are not filled in
+	failFast bool
+	// The text of the document with the order of its keys, nil unless the schema uses "propertyOrder"
+	// and the document was loaded from text
+	documentSource *schemaSource
+	// The locations of the keywords evaluated against every value by its JSON Pointer,
+	// only tracked when SchemaLoader.TraceEvaluatedKeywords is set
+	evaluatedKeywords map[string][]string
+	// The subSchemas being validated, from the root schema down to the current one, see ResultErrorFields.setOrigin.
+	// Not tracked when failFast is set
+	evaluationPath []*subSchema
+}
+
+func (s *validationState) popEvaluationPath() {
+	s.evaluationPath = s.evaluationPath[:len(s.evaluationPath)-1]
+}
+
+// locale returns the locale errors are reported in, which is the global Locale unless the schema has its own
+func (s *validationState) locale() locale {
+	if s.schema != nil && s.schema.locale != nil {
+		return s.schema.locale
+	}
+	return Locale
+}
+
+// now returns the current time of the clock set with SchemaLoader.SetClock
+func (s *validationState) now() time.Time {
+	if s.schema != nil && s.schema.clock != nil {
+		return s.schema.clock()
+	}
+	return time.Now()
+}
+
+// aborted reports whether the validation should stop without checking any further keywords
+func (s *validationState) aborted() bool {
+	if s.timedOut || s.tooDeep != nil {
+		return true
+	}
+	if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+		s.timedOut = true
+	}
+	return s.timedOut
+}
+
+func (v *Schema) validateDocument(root interface{}) (*Result, error) {
+	return v.validateDocumentWithState(root, &validationState{})
+}
+
+// removeEmptyStrings returns the document without the properties whose value is the empty string.
+// Only the objects and arrays that change are copied, the document itself is not modified. Copied objects keep
+// the order of the keys in source
+func removeEmptyStrings(node interface{}, source *schemaSource) interface{} {
+	cleaned, _ := removeEmptyStringsFrom(node, source)
+	return cleaned
+}
+
+// removeEmptyStringsFrom implements removeEmptyStrings and reports whether anything was removed
+func removeEmptyStringsFrom(node interface{}, source *schemaSource) (interface{}, bool) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		var copied map[string]interface{}
+		for key, value := range v {
+			cleaned, changed := removeEmptyStringsFrom(value, source)
+			removed := value == ""
+			if !removed && !changed {
+				continue
+			}
+			if copied == nil {
+				copied = make(map[string]interface{}, len(v))
+				for k, original := range v {
+					copied[k] = original
+				}
+			}
+			if removed {
+				delete(copied, key)
+			} else {
+				copied[key] = cleaned
+			}
+		}
+		if copied == nil {
+			return v, false
+		}
+		source.copyOrder(v, copied)
+		return copied, true
+
+	case []interface{}:
+		var copied []interface{}
+		for i, value := range v {
+			cleaned, changed := removeEmptyStringsFrom(value, source)
+			if !changed {
+				continue
+			}
+			if copied == nil {
+				copied = make([]interface{}, len(v))
+				copy(copied, v)
+			}
+			copied[i] = cleaned
+		}
+		if copied == nil {
+			return v, false
+		}
+		return copied, true
+	}
+
+	return node, false
+}
+
+func (v *Schema) validateDocumentWithState(root interface{}, state *validationState) (*Result, error) {
+	state.schema = v
+	if v.resolveInstanceRefs {
+		var err error
+		if root, err = resolveInstanceRefs(root, state.documentSource, state.locale()); err != nil {
+			return nil, err
+		}
+	}
+	if v.emptyStringAsAbsent {
+		root = removeEmptyStrings(root, state.documentSource)
+	}
+	// A fail fast validation only decides whether the document is valid, so there is nothing to report to the hook
+	callHook := v.validationHook != nil && !state.failFast
+	if callHook {
+		state.visited = make(map[string]bool)
+	}
+	if v.traceEvaluatedKeywords && !state.failFast {
+		state.evaluatedKeywords = make(map[string][]string)
+	}
+	result := &Result{state: state}
 	context := NewJsonContext(STRING_CONTEXT_ROOT, nil)
 	v.rootSchema.validateRecursive(v.rootSchema, root, result, context)
-	return result
+	if state.tooDeep != nil {
+		return nil, errors.New(formatErrorDescription(
+			state.locale().MaxDepthExceeded(),
+			ErrorDetails{"max": v.maxDepth, "context": state.tooDeep.String()},
+		))
+	}
+	// Boolean schemas don't have an id
+	rootURI := v.documentReference
+	if v.rootSchema.id != nil {
+		rootURI = *v.rootSchema.id
+	}
+	result.setSchemaURI(0, rootURI.String())
+	if v.maxErrorsPerField > 0 && !state.failFast {
+		result.limitErrorsPerField(v.maxErrorsPerField)
+	}
+	if callHook {
+		v.callValidationHook(root, context, result)
+	}
+	return result, nil
 }
 
-func (v *subSchema) subValidateWithContext(document interface{}, context *JsonContext) *Result {
-	result := &Result{}
+// callValidationHook calls the validation hook for every visited node after validation has finished.
+// The document is walked in order, with object keys sorted, so the hook is always called in the same order
+func (v *Schema) callValidationHook(root interface{}, rootContext *JsonContext, result *Result) {
+	// A node fails if there are errors for it or any of its children
+	failed := make(map[string]bool)
+	for _, err := range result.Errors() {
+		for context := err.Context(); context != nil; context = context.tail {
+			failed[context.Pointer()] = true
+		}
+	}
+
+	var walk func(node interface{}, context *JsonContext)
+	walk = func(node interface{}, context *JsonContext) {
+		// Nodes are keyed by pointer, as "a.b" is the same string for a property "a.b" and a nested property "b"
+		if pointer := context.Pointer(); result.state.visited[pointer] {
+			v.validationHook(context.String(), node, !failed[pointer])
+		}
+		switch node := node.(type) {
+		case []interface{}:
+			for i, item := range node {
+				walk(item, newJsonContextIndex(i, context))
+			}
+		case map[string]interface{}:
+			for _, k := range sortedKeys(node) {
+				walk(node[k], NewJsonContext(k, context))
+			}
+		}
+	}
+	walk(root, rootContext)
+}
+
+func (v *subSchema) subValidateWithContext(document interface{}, context *JsonContext, state *validationState) *Result {
+	result := &Result{state: state}
 	v.validateRecursive(v, document, result, context)
 	return result
 }
@@ -75,6 +355,33 @@ func (v *subSchema) validateRecursive(currentSubSchema *subSchema, currentNode i
 		internalLog(" %v", currentNode)
 	}
 
+	if result.state.aborted() {
+		return
+	}
+
+	// Further keywords can only add errors to a result that is already invalid
+	if result.state.failFast && len(result.errors) > 0 {
+		return
+	}
+
+	if maxDepth := result.state.schema.maxDepth; maxDepth > 0 && context.depth > maxDepth {
+		context.retain()
+		result.state.tooDeep = context
+		return
+	}
+
+	if result.state.visited != nil {
+		result.state.visited[context.Pointer()] = true
+	}
+	if result.state.evaluatedKeywords != nil {
+		result.state.traceKeywords(currentSubSchema, currentNode, context)
+	}
+
+	if !result.state.failFast {
+		result.state.evaluationPath = append(result.state.evaluationPath, currentSubSchema)
+		defer result.state.popEvaluationPath()
+	}
+
 	// Handle true/false schema as early as possible as all other fields will be nil
 	if currentSubSchema.pass != nil {
 		if !*currentSubSchema.pass {
@@ -89,12 +396,28 @@ func (v *subSchema) validateRecursive(currentSubSchema *subSchema, currentNode i
 	}
 
 	// Handle referenced schemas, returns directly when a $ref is found
+	if currentSubSchema.unresolvedRef {
+		result.addInternalError(
+			new(InternalError),
+			context,
+			currentNode,
+			ErrorDetails{"error": formatErrorDescription(
+				result.state.locale().UnresolvedReference(),
+				ErrorDetails{"reference": currentSubSchema.ref.String()},
+			)},
+		)
+		return
+	}
 	if currentSubSchema.refSchema != nil {
+		nbErrors := len(result.errors)
 		v.validateRecursive(currentSubSchema.refSchema, currentNode, result, context)
+		result.setSchemaURI(nbErrors, currentSubSchema.ref.String())
 		return
 	}
 
 	// Check for null value
+	// A null only ever fails on its type. Object and array keywords like "required" or
+	// "properties" are never applied to it, so a null subtree results in at most one error
 	if currentNode == nil {
 		if currentSubSchema.types.IsTyped() && !currentSubSchema.types.Contains(TYPE_NULL) {
 			result.addInternalError(
@@ -119,6 +442,9 @@ func (v *subSchema) validateRecursive(currentSubSchema *subSchema, currentNode i
 			value := currentNode.(json.Number)
 
 			isInt := checkJSONInteger(value)
+			if isInt && result.state.schema.strictIntegers {
+				isInt = isIntegerLiteral(value)
+			}
 
 			validType := currentSubSchema.types.Contains(TYPE_NUMBER) || (isInt && currentSubSchema.types.Contains(TYPE_INTEGER))
 
@@ -206,8 +532,9 @@ func (v *subSchema) validateRecursive(currentSubSchema *subSchema, currentNode i
 				for _, pSchema := range currentSubSchema.propertiesChildren {
 					nextNode, ok := castCurrentNode[pSchema.property]
 					if ok {
-						subContext := NewJsonContext(pSchema.property, context)
+						subContext := acquireJsonContext(pSchema.property, context)
 						v.validateRecursive(pSchema, nextNode, result, subContext)
+						releaseJsonContext(subContext)
 					}
 				}
 
@@ -274,16 +601,33 @@ func (v *subSchema) validateSchema(currentSubSchema *subSchema, currentNode inte
 		internalLog(" %v", currentNode)
 	}
 
-	if len(currentSubSchema.anyOf) > 0 {
+	if result.state.aborted() {
+		return
+	}
+
+	// discriminator:
+	// Objects are only validated against the branch of oneOf or anyOf selected by the discriminator property
+	discriminated := false
+	if object, ok := currentNode.(map[string]interface{}); ok && currentSubSchema.discriminator != nil {
+		v.validateDiscriminator(currentSubSchema.discriminator, object, result, context)
+		discriminated = true
+	}
+
+	if len(currentSubSchema.anyOf) > 0 && !discriminated {
 
 		validatedAnyOf := false
 		var bestValidationResult *Result
 
-		for _, anyOfSchema := range currentSubSchema.anyOf {
+		for i, anyOfSchema := range currentSubSchema.anyOf {
 			if !validatedAnyOf {
-				validationResult := anyOfSchema.subValidateWithContext(currentNode, context)
+				validationResult := anyOfSchema.subValidateWithContext(currentNode, context, result.state)
 				validatedAnyOf = validationResult.Valid()
 
+				if validatedAnyOf {
+					result.mergeAnnotations(validationResult)
+					result.addAnnotation(context, KEY_ANY_OF, i)
+				}
+
 				if !validatedAnyOf && (bestValidationResult == nil || validationResult.score > bestValidationResult.score) {
 					bestValidationResult = validationResult
 				}
@@ -301,15 +645,19 @@ func (v *subSchema) validateSchema(currentSubSchema *subSchema, currentNode inte
 		}
 	}
 
-	if len(currentSubSchema.oneOf) > 0 {
+	if len(currentSubSchema.oneOf) > 0 && !discriminated {
 
 		nbValidated := 0
 		var bestValidationResult *Result
+		var validResult *Result
+		validIndex := 0
 
-		for _, oneOfSchema := range currentSubSchema.oneOf {
-			validationResult := oneOfSchema.subValidateWithContext(currentNode, context)
+		for i, oneOfSchema := range currentSubSchema.oneOf {
+			validationResult := oneOfSchema.subValidateWithContext(currentNode, context, result.state)
 			if validationResult.Valid() {
 				nbValidated++
+				validResult = validationResult
+				validIndex = i
 			} else if nbValidated == 0 && (bestValidationResult == nil || validationResult.score > bestValidationResult.score) {
 				bestValidationResult = validationResult
 			}
@@ -324,30 +672,42 @@ func (v *subSchema) validateSchema(currentSubSchema *subSchema, currentNode inte
 				// that's probably the one the user was trying to match
 				result.mergeErrors(bestValidationResult)
 			}
+		} else {
+			result.mergeAnnotations(validResult)
+			result.addAnnotation(context, KEY_ONE_OF, validIndex)
 		}
 
 	}
 
 	if len(currentSubSchema.allOf) > 0 {
-		nbValidated := 0
+		failedIndex := -1
 
-		for _, allOfSchema := range currentSubSchema.allOf {
-			validationResult := allOfSchema.subValidateWithContext(currentNode, context)
-			if validationResult.Valid() {
-				nbValidated++
-			}
+		for i, allOfSchema := range currentSubSchema.allOf {
+			validationResult := allOfSchema.subValidateWithContext(currentNode, context, result.state)
 			result.mergeErrors(validationResult)
+			if !validationResult.Valid() && failedIndex < 0 {
+				failedIndex = i
+				// The remaining subschemas can't make the value valid again
+				if result.state.schema.shortCircuitAllOf {
+					break
+				}
+			}
 		}
 
-		if nbValidated != len(currentSubSchema.allOf) {
-			result.addInternalError(new(NumberAllOfError), context, currentNode, ErrorDetails{})
+		if failedIndex >= 0 {
+			result.addInternalError(new(NumberAllOfError), context, currentNode, ErrorDetails{"index": failedIndex})
 		}
 	}
 
 	if currentSubSchema.not != nil {
-		validationResult := currentSubSchema.not.subValidateWithContext(currentNode, context)
+		validationResult := currentSubSchema.not.subValidateWithContext(currentNode, context, result.state)
 		if validationResult.Valid() {
-			result.addInternalError(new(NumberNotError), context, currentNode, ErrorDetails{})
+			result.addInternalError(
+				new(NumberNotError),
+				context,
+				currentNode,
+				ErrorDetails{"schema": currentSubSchema.not.describe()},
+			)
 		}
 	}
 
@@ -378,17 +738,24 @@ func (v *subSchema) validateSchema(currentSubSchema *subSchema, currentNode inte
 	}
 
 	if currentSubSchema._if != nil {
-		validationResultIf := currentSubSchema._if.subValidateWithContext(currentNode, context)
+		validationResultIf := currentSubSchema._if.subValidateWithContext(currentNode, context, result.state)
+		if validationResultIf.Valid() {
+			result.mergeAnnotations(validationResultIf)
+		}
 		if currentSubSchema._then != nil && validationResultIf.Valid() {
-			validationResultThen := currentSubSchema._then.subValidateWithContext(currentNode, context)
-			if !validationResultThen.Valid() {
+			validationResultThen := currentSubSchema._then.subValidateWithContext(currentNode, context, result.state)
+			if validationResultThen.Valid() {
+				result.mergeAnnotations(validationResultThen)
+			} else {
 				result.addInternalError(new(ConditionThenError), context, currentNode, ErrorDetails{})
 				result.mergeErrors(validationResultThen)
 			}
 		}
 		if currentSubSchema._else != nil && !validationResultIf.Valid() {
-			validationResultElse := currentSubSchema._else.subValidateWithContext(currentNode, context)
-			if !validationResultElse.Valid() {
+			validationResultElse := currentSubSchema._else.subValidateWithContext(currentNode, context, result.state)
+			if validationResultElse.Valid() {
+				result.mergeAnnotations(validationResultElse)
+			} else {
 				result.addInternalError(new(ConditionElseError), context, currentNode, ErrorDetails{})
 				result.mergeErrors(validationResultElse)
 			}
@@ -398,6 +765,48 @@ func (v *subSchema) validateSchema(currentSubSchema *subSchema, currentNode inte
 	result.incrementScore()
 }
 
+// validateDiscriminator validates an object against the branch selected by the value of its discriminator property
+func (v *subSchema) validateDiscriminator(discriminator *discriminator, object map[string]interface{}, result *Result, context *JsonContext) {
+	value, ok := object[discriminator.property]
+	if !ok {
+		// A partial document only holds the values that are changed, so the branch can't be selected
+		if !result.state.partial {
+			result.addInternalError(
+				new(RequiredError),
+				context,
+				object,
+				ErrorDetails{"property": discriminator.property},
+			)
+		}
+		return
+	}
+
+	name, _ := value.(string)
+	branch, ok := discriminator.branches[name]
+	if !ok {
+		allowed := make([]string, 0, len(discriminator.branches))
+		for name := range discriminator.branches {
+			allowed = append(allowed, name)
+		}
+		sort.Strings(allowed)
+		result.addInternalError(
+			new(DiscriminatorError),
+			NewJsonContext(discriminator.property, context),
+			value,
+			ErrorDetails{"property": discriminator.property, "value": value, "allowed": strings.Join(allowed, ", ")},
+		)
+		return
+	}
+
+	validationResult := branch.subValidateWithContext(object, context, result.state)
+	if validationResult.Valid() {
+		result.mergeAnnotations(validationResult)
+		result.incrementScore()
+		return
+	}
+	result.mergeErrors(validationResult)
+}
+
 func (v *subSchema) validateCommon(currentSubSchema *subSchema, value interface{}, result *Result, context *JsonContext) {
 
 	if internalLogEnabled {
@@ -428,18 +837,30 @@ func (v *subSchema) validateCommon(currentSubSchema *subSchema, value interface{
 		if err != nil {
 			result.addInternalError(new(InternalError), context, value, ErrorDetails{"error": err})
 		}
-		if !isStringInSlice(currentSubSchema.enum, *vString) {
+		if !currentSubSchema.enumSet[*vString] {
+			details := ErrorDetails{
+				"allowed": strings.Join(currentSubSchema.enum, ", "),
+				"values":  currentSubSchema.enumValues,
+			}
+			if s, ok := value.(string); ok {
+				if suggestion, ok := closestString(s, currentSubSchema.enumValues); ok {
+					details["suggestion"] = suggestion
+				}
+			}
 			result.addInternalError(
 				new(EnumError),
 				context,
 				value,
-				ErrorDetails{
-					"allowed": strings.Join(currentSubSchema.enum, ", "),
-				},
+				details,
 			)
 		}
 	}
 
+	// custom keywords:
+	if len(currentSubSchema.customKeywords) > 0 {
+		v.validateCustomKeywords(currentSubSchema, value, result, context)
+	}
+
 	result.incrementScore()
 }
 
@@ -455,9 +876,11 @@ func (v *subSchema) validateArray(currentSubSchema *subSchema, value []interface
 	// TODO explain
 	if currentSubSchema.itemsChildrenIsSingleSchema {
 		for i := range value {
-			subContext := NewJsonContext(strconv.Itoa(i), context)
-			validationResult := currentSubSchema.itemsChildren[0].subValidateWithContext(value[i], subContext)
+			subContext := acquireJsonContextIndex(i, context)
+			validationResult := currentSubSchema.itemsChildren[0].subValidateWithContext(value[i], subContext, result.state)
+			releaseJsonContext(subContext)
 			result.mergeErrors(validationResult)
+			result.markEvaluatedItem(context, nbValues, i)
 		}
 	} else {
 		if currentSubSchema.itemsChildren != nil && len(currentSubSchema.itemsChildren) > 0 {
@@ -466,9 +889,11 @@ func (v *subSchema) validateArray(currentSubSchema *subSchema, value []interface
 
 			// while we have both schemas and values, check them against each other
 			for i := 0; i != nbItems && i != nbValues; i++ {
-				subContext := NewJsonContext(strconv.Itoa(i), context)
-				validationResult := currentSubSchema.itemsChildren[i].subValidateWithContext(value[i], subContext)
+				subContext := acquireJsonContextIndex(i, context)
+				validationResult := currentSubSchema.itemsChildren[i].subValidateWithContext(value[i], subContext, result.state)
+				releaseJsonContext(subContext)
 				result.mergeErrors(validationResult)
+				result.markEvaluatedItem(context, nbValues, i)
 			}
 
 			if nbItems < nbValues {
@@ -479,13 +904,19 @@ func (v *subSchema) validateArray(currentSubSchema *subSchema, value []interface
 				case bool:
 					if !currentSubSchema.additionalItems.(bool) {
 						result.addInternalError(new(ArrayNoAdditionalItemsError), context, value, ErrorDetails{})
+					} else {
+						for i := nbItems; i != nbValues; i++ {
+							result.markEvaluatedItem(context, nbValues, i)
+						}
 					}
 				case *subSchema:
 					additionalItemSchema := currentSubSchema.additionalItems.(*subSchema)
 					for i := nbItems; i != nbValues; i++ {
-						subContext := NewJsonContext(strconv.Itoa(i), context)
-						validationResult := additionalItemSchema.subValidateWithContext(value[i], subContext)
+						subContext := acquireJsonContextIndex(i, context)
+						validationResult := additionalItemSchema.subValidateWithContext(value[i], subContext, result.state)
+						releaseJsonContext(subContext)
 						result.mergeErrors(validationResult)
+						result.markEvaluatedItem(context, nbValues, i)
 					}
 				}
 			}
@@ -509,28 +940,46 @@ func (v *subSchema) validateArray(currentSubSchema *subSchema, value []interface
 				new(ArrayMaxItemsError),
 				context,
 				value,
-				ErrorDetails{"max": *currentSubSchema.maxItems},
+				ErrorDetails{"max": *currentSubSchema.maxItems, "from": *currentSubSchema.maxItems, "to": nbValues - 1},
 			)
 		}
 	}
 
 	// uniqueItems:
+	// Items are equal if their canonical forms are, which only need to be compared for items with the same hash
 	if currentSubSchema.uniqueItems {
-		var stringifiedItems = make(map[string]int)
+		// The indexes of the items by the hash of their canonical form, the last one of equal items
+		hashedItems := make(map[uint64][]int, len(value))
+		var canonical, other bytes.Buffer
 		for j, v := range value {
-			vString, err := marshalWithoutNumber(v)
-			if err != nil {
+			canonical.Reset()
+			if err := writeComparable(&canonical, v); err != nil {
 				result.addInternalError(new(InternalError), context, value, ErrorDetails{"err": err})
+				continue
 			}
-			if i, ok := stringifiedItems[*vString]; ok {
-				result.addInternalError(
-					new(ItemsMustBeUniqueError),
-					context,
-					value,
-					ErrorDetails{"type": TYPE_ARRAY, "i": i, "j": j},
-				)
+			h := fnv.New64a()
+			h.Write(canonical.Bytes())
+			hash := h.Sum64()
+
+			duplicate := false
+			for n, i := range hashedItems[hash] {
+				// Unless the hashes collide the items are equal, so this is only done again for duplicates
+				other.Reset()
+				if writeComparable(&other, value[i]) == nil && bytes.Equal(canonical.Bytes(), other.Bytes()) {
+					result.addInternalError(
+						new(ItemsMustBeUniqueError),
+						context,
+						value,
+						ErrorDetails{"type": TYPE_ARRAY, "i": i, "j": j},
+					)
+					hashedItems[hash][n] = j
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				hashedItems[hash] = append(hashedItems[hash], j)
 			}
-			stringifiedItems[*vString] = j
 		}
 	}
 
@@ -541,13 +990,18 @@ func (v *subSchema) validateArray(currentSubSchema *subSchema, value []interface
 		var bestValidationResult *Result
 
 		for i, v := range value {
-			subContext := NewJsonContext(strconv.Itoa(i), context)
-
-			validationResult := currentSubSchema.contains.subValidateWithContext(v, subContext)
+			subContext := acquireJsonContextIndex(i, context)
+			validationResult := currentSubSchema.contains.subValidateWithContext(v, subContext, result.state)
+			releaseJsonContext(subContext)
 			if validationResult.Valid() {
 				validatedOne = true
-				break
-			} else {
+				result.mergeAnnotations(validationResult)
+				result.markEvaluatedItem(context, nbValues, i)
+				// Every item that matches counts as evaluated for unevaluatedItems, otherwise the first one is enough
+				if !result.state.schema.tracksEvaluatedItems {
+					break
+				}
+			} else if !validatedOne {
 				if bestValidationResult == nil || validationResult.score > bestValidationResult.score {
 					bestValidationResult = validationResult
 				}
@@ -566,6 +1020,23 @@ func (v *subSchema) validateArray(currentSubSchema *subSchema, value []interface
 		}
 	}
 
+	// unevaluatedItems:
+	// applies to the items that no other keyword, including those of the subschemas applied to the array itself, evaluated
+
+	if currentSubSchema.unevaluatedItems != nil {
+		evaluated := result.evaluatedItems[context]
+		for i := range value {
+			if evaluated != nil && evaluated[i] {
+				continue
+			}
+			subContext := acquireJsonContextIndex(i, context)
+			validationResult := currentSubSchema.unevaluatedItems.subValidateWithContext(value[i], subContext, result.state)
+			releaseJsonContext(subContext)
+			result.mergeErrors(validationResult)
+			result.markEvaluatedItem(context, nbValues, i)
+		}
+	}
+
 	result.incrementScore()
 }
 
@@ -583,7 +1054,7 @@ func (v *subSchema) validateObject(currentSubSchema *subSchema, value map[string
 				new(ArrayMinPropertiesError),
 				context,
 				value,
-				ErrorDetails{"min": *currentSubSchema.minProperties},
+				ErrorDetails{"min": *currentSubSchema.minProperties, "count": len(value)},
 			)
 		}
 	}
@@ -593,23 +1064,52 @@ func (v *subSchema) validateObject(currentSubSchema *subSchema, value map[string
 				new(ArrayMaxPropertiesError),
 				context,
 				value,
-				ErrorDetails{"max": *currentSubSchema.maxProperties},
+				ErrorDetails{"max": *currentSubSchema.maxProperties, "count": len(value)},
 			)
 		}
 	}
 
 	// required:
-	for _, requiredProperty := range currentSubSchema.required {
-		_, ok := value[requiredProperty]
-		if ok {
-			result.incrementScore()
-		} else {
-			result.addInternalError(
-				new(RequiredError),
-				context,
-				value,
-				ErrorDetails{"property": requiredProperty},
-			)
+	// A partial document only holds the values that are changed, so nothing can be missing
+	if !result.state.partial {
+		for _, requiredProperty := range currentSubSchema.required {
+			_, ok := value[requiredProperty]
+			if ok {
+				result.incrementScore()
+			} else {
+				result.addInternalError(
+					new(RequiredError),
+					context,
+					value,
+					ErrorDetails{"property": requiredProperty},
+				)
+			}
+		}
+	}
+
+	// propertyOrder:
+	// The order of the keys is only known for objects decoded from the text of the document
+	if currentSubSchema.propertyOrder != nil && result.state.documentSource.hasOrder(value) {
+		rank := make(map[string]int, len(currentSubSchema.propertyOrder))
+		for i, property := range currentSubSchema.propertyOrder {
+			rank[property] = i
+		}
+		last := -1
+		for _, pk := range result.state.documentSource.keys(value) {
+			r, ok := rank[pk]
+			if !ok {
+				continue
+			}
+			if r < last {
+				result.addInternalError(
+					new(PropertyOrderError),
+					context,
+					value,
+					ErrorDetails{"property": pk, "before": currentSubSchema.propertyOrder[last]},
+				)
+				continue
+			}
+			last = r
 		}
 	}
 
@@ -621,6 +1121,7 @@ func (v *subSchema) validateObject(currentSubSchema *subSchema, value map[string
 		for _, spValue := range currentSubSchema.propertiesChildren {
 			if pk == spValue.property {
 				found = true
+				break
 			}
 		}
 
@@ -642,7 +1143,9 @@ func (v *subSchema) validateObject(currentSubSchema *subSchema, value map[string
 
 				}
 			case *subSchema:
-				validationResult := ap.subValidateWithContext(value[pk], NewJsonContext(pk, context))
+				subContext := acquireJsonContext(pk, context)
+				validationResult := ap.subValidateWithContext(value[pk], subContext, result.state)
+				releaseJsonContext(subContext)
 				result.mergeErrors(validationResult)
 			}
 		}
@@ -651,7 +1154,7 @@ func (v *subSchema) validateObject(currentSubSchema *subSchema, value map[string
 	// propertyNames:
 	if currentSubSchema.propertyNames != nil {
 		for pk := range value {
-			validationResult := currentSubSchema.propertyNames.subValidateWithContext(pk, context)
+			validationResult := currentSubSchema.propertyNames.subValidateWithContext(pk, context, result.state)
 			if !validationResult.Valid() {
 				result.addInternalError(new(InvalidPropertyNameError),
 					context,
@@ -673,21 +1176,27 @@ func (v *subSchema) validatePatternProperty(currentSubSchema *subSchema, key str
 		internalLog(" %s %v", key, value)
 	}
 
-	validated := false
+	var matched []string
+	subContext := acquireJsonContext(key, context)
+	defer releaseJsonContext(subContext)
 
 	for pk, pv := range currentSubSchema.patternProperties {
-		if matches, _ := regexp.MatchString(pk, key); matches {
-			validated = true
-			subContext := NewJsonContext(key, context)
-			validationResult := pv.subValidateWithContext(value, subContext)
+		if currentSubSchema.patternPropertiesRegexps[pk].MatchString(key) {
+			matched = append(matched, pk)
+			validationResult := pv.subValidateWithContext(value, subContext, result.state)
 			result.mergeErrors(validationResult)
 		}
 	}
 
-	if !validated {
+	if len(matched) == 0 {
 		return false
 	}
 
+	if len(matched) > 1 && result.state.schema.reportOverlappingPatterns {
+		sort.Strings(matched)
+		result.addAnnotation(subContext, KEY_PATTERN_PROPERTIES, matched)
+	}
+
 	result.incrementScore()
 	return true
 }
@@ -712,8 +1221,9 @@ func (v *subSchema) validateString(currentSubSchema *subSchema, value interface{
 	stringValue := value.(string)
 
 	// minLength & maxLength:
+	// The length of a string is the number of characters, that is Unicode code points, not bytes
 	if currentSubSchema.minLength != nil {
-		if utf8.RuneCount([]byte(stringValue)) < int(*currentSubSchema.minLength) {
+		if utf8.RuneCountInString(stringValue) < int(*currentSubSchema.minLength) {
 			result.addInternalError(
 				new(StringLengthGTEError),
 				context,
@@ -723,7 +1233,7 @@ func (v *subSchema) validateString(currentSubSchema *subSchema, value interface{
 		}
 	}
 	if currentSubSchema.maxLength != nil {
-		if utf8.RuneCount([]byte(stringValue)) > int(*currentSubSchema.maxLength) {
+		if utf8.RuneCountInString(stringValue) > int(*currentSubSchema.maxLength) {
 			result.addInternalError(
 				new(StringLengthLTEError),
 				context,
@@ -740,7 +1250,7 @@ func (v *subSchema) validateString(currentSubSchema *subSchema, value interface{
 				new(DoesNotMatchPatternError),
 				context,
 				value,
-				ErrorDetails{"pattern": currentSubSchema.pattern},
+				ErrorDetails{"pattern": currentSubSchema.patternString},
 			)
 
 		}
@@ -748,19 +1258,70 @@ func (v *subSchema) validateString(currentSubSchema *subSchema, value interface{
 
 	// format
 	if currentSubSchema.format != "" {
-		if !FormatCheckers.IsFormat(currentSubSchema.format, stringValue) {
-			result.addInternalError(
-				new(DoesNotMatchFormatError),
-				context,
-				value,
-				ErrorDetails{"format": currentSubSchema.format},
-			)
-		}
+		v.validateFormat(currentSubSchema, value, stringValue, result, context)
 	}
 
 	result.incrementScore()
 }
 
+// validateFormat checks input, the string or float64 form of value, against the format of the subSchema
+func (v *subSchema) validateFormat(currentSubSchema *subSchema, value interface{}, input interface{}, result *Result, context *JsonContext) {
+	format := currentSubSchema.format
+	asserted := result.state.schema.assertsFormat(format)
+	reportResult := result.state.schema.reportFormatResults && FormatCheckers.Has(format)
+	if !asserted {
+		result.addAnnotation(context, KEY_FORMAT, format)
+		// A format that is only an annotation is just checked to report the outcome
+		if reportResult {
+			if valid, ok := checkAnnotationFormat(format, input, result.state.now); ok {
+				result.addAnnotation(context, FormatResultKeyword, FormatResult{Format: format, Valid: valid})
+			}
+		}
+		return
+	}
+
+	defer recoverExtension(result, context, value, "format "+format)
+	valid := FormatCheckers.isFormatAt(format, input, result.state.now)
+	if reportResult {
+		result.addAnnotation(context, FormatResultKeyword, FormatResult{Format: format, Valid: valid})
+	}
+
+	if !valid {
+		result.addInternalError(
+			new(DoesNotMatchFormatError),
+			context,
+			value,
+			ErrorDetails{"format": format},
+		)
+	} else if result.state.schema.normalizeFormats {
+		result.addNormalizedValue(context, format, input)
+	}
+}
+
+// checkAnnotationFormat checks a format that is only an annotation. The outcome of such a format doesn't affect
+// the validity of the document, so a checker that panics isn't reported as an error either, ok is false then
+func checkAnnotationFormat(format string, input interface{}, now func() time.Time) (valid bool, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return FormatCheckers.isFormatAt(format, input, now), true
+}
+
+// recoverExtension turns a panic of a custom format checker or keyword into an ExtensionPanicError,
+// so a bug in an extension doesn't take down the caller. It must be deferred around the call to the extension
+func recoverExtension(result *Result, context *JsonContext, value interface{}, extension string) {
+	if r := recover(); r != nil {
+		result.addInternalError(
+			new(ExtensionPanicError),
+			context,
+			value,
+			ErrorDetails{"extension": extension, "panic": r},
+		)
+	}
+}
+
 func (v *subSchema) validateNumber(currentSubSchema *subSchema, value interface{}, result *Result, context *JsonContext) {
 
 	// Ignore non numbers
@@ -844,14 +1405,7 @@ func (v *subSchema) validateNumber(currentSubSchema *subSchema, value interface{
 
 	// format
 	if currentSubSchema.format != "" {
-		if !FormatCheckers.IsFormat(currentSubSchema.format, float64Value) {
-			result.addInternalError(
-				new(DoesNotMatchFormatError),
-				context,
-				value,
-				ErrorDetails{"format": currentSubSchema.format},
-			)
-		}
+		v.validateFormat(currentSubSchema, value, float64Value, result, context)
 	}
 
 	result.incrementScore()
@@ -0,0 +1,1568 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumericBoundsBeyondFloat64(t *testing.T) {
+	// 2^53 + 1 = 9007199254740993 is the first integer that float64 can't represent, it rounds to 2^53
+	for _, testCase := range []struct {
+		schema   string
+		document string
+		valid    bool
+	}{
+		{`{"maximum" : 9007199254740992}`, `9007199254740993`, false},
+		{`{"maximum" : 9007199254740993}`, `9007199254740993`, true},
+		{`{"maximum" : 9007199254740993}`, `9007199254740994`, false},
+		{`{"minimum" : 9007199254740993}`, `9007199254740992`, false},
+		{`{"minimum" : 9007199254740993}`, `9007199254740993.0`, true},
+		{`{"exclusiveMaximum" : 9007199254740993}`, `9007199254740993`, false},
+		{`{"exclusiveMaximum" : 9007199254740993}`, `9007199254740992`, true},
+		{`{"exclusiveMinimum" : 9007199254740992}`, `9007199254740993`, true},
+		{`{"maximum" : 1000000000000000000000000000001}`, `1000000000000000000000000000002`, false},
+		{`{"maximum" : 1000000000000000000000000000001}`, `1e30`, true},
+		{`{"minimum" : -9007199254740993}`, `-9007199254740994`, false},
+		{`{"maximum" : 0.30000000000000001}`, `0.30000000000000002`, false},
+	} {
+		result, err := Validate(NewStringLoader(testCase.schema), NewStringLoader(testCase.document))
+		require.Nil(t, err)
+		assert.Equal(t, testCase.valid, result.Valid(), "%s %s", testCase.schema, testCase.document)
+	}
+}
+
+func TestStringLengthCountsCodePoints(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{"minLength" : 3, "maxLength" : 5}`))
+	require.Nil(t, err)
+
+	for document, valid := range map[string]bool{
+		`"héllo"`:                                true,  // 6 bytes
+		`"日本語"`:                                  true,  // 9 bytes
+		`"😀😀😀"`:                                  true,  // 12 bytes
+		`"\ud83d\ude00\ud83d\ude00\ud83d\ude00"`: true,  // surrogate pairs are a single code point each
+		`"日本語です!"`:                               false, // 6 code points
+		`"😀😀"`:                                   false, // 8 bytes, but 2 code points
+		`"\ud83d\ude00\ud83d\ude00"`:             false,
+	} {
+		result, err := schema.Validate(NewStringLoader(document))
+		require.Nil(t, err)
+		assert.Equal(t, valid, result.Valid(), document)
+	}
+}
+
+func TestNullIsNotAbsent(t *testing.T) {
+	for _, keyword := range []string{`"const" : null`, `"enum" : [null]`, `"type" : "null"`} {
+		schema, err := NewSchema(NewStringLoader(`{
+			"properties" : {
+				"a" : {` + keyword + `}
+			}
+		}`))
+		require.Nil(t, err)
+
+		// An explicit null matches
+		result, err := schema.Validate(NewStringLoader(`{"a" : null}`))
+		require.Nil(t, err)
+		assert.True(t, result.Valid(), keyword)
+
+		// An absent property is not constrained at all
+		result, err = schema.Validate(NewStringLoader(`{}`))
+		require.Nil(t, err)
+		assert.True(t, result.Valid(), keyword)
+
+		// Any other value fails
+		result, err = schema.Validate(NewStringLoader(`{"a" : 0}`))
+		require.Nil(t, err)
+		if assert.Len(t, result.Errors(), 1, keyword) {
+			assert.Equal(t, "a", result.Errors()[0].Field())
+		}
+	}
+}
+
+func TestNullConstWithRequired(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"a" : {"const" : null}
+		},
+		"required" : ["a"]
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"a" : null}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	// Absence is governed by "required" alone
+	result, err = schema.Validate(NewStringLoader(`{}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "required", result.Errors()[0].Type())
+	}
+}
+
+func TestNullNestedObject(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"type" : "object",
+		"properties" : {
+			"address" : {
+				"type" : "object",
+				"properties" : {
+					"street" : {"type" : "string"},
+					"zip" : {"type" : "string"}
+				},
+				"required" : ["street", "zip"]
+			}
+		}
+	}`))
+	require.Nil(t, err)
+
+	// An absent parent does not trigger errors for its children
+	result, err := schema.Validate(NewStringLoader(`{}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	// A null parent yields a single type error and nothing for its subtree
+	result, err = schema.Validate(NewStringLoader(`{"address" : null}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "invalid_type", result.Errors()[0].Type())
+		assert.Equal(t, "address", result.Errors()[0].Field())
+		assert.Equal(t, "address: Invalid type. Expected: object, given: null", result.Errors()[0].String())
+	}
+}
+
+type slowFormatChecker struct{}
+
+func (f slowFormatChecker) IsFormat(input interface{}) bool {
+	time.Sleep(20 * time.Millisecond)
+	return true
+}
+
+func TestValidateWithTimeout(t *testing.T) {
+	FormatCheckers.Add("slow", slowFormatChecker{})
+	defer FormatCheckers.Remove("slow")
+
+	schema, err := NewSchema(NewStringLoader(`{
+		"type" : "array",
+		"items" : {"type" : "string", "format" : "slow"}
+	}`))
+	require.Nil(t, err)
+
+	document := NewStringLoader(`["a", "b", "c", "d", "e", "f", "g", "h", "i", "j"]`)
+
+	result, err := schema.ValidateWithTimeout(document, 30*time.Millisecond)
+	assert.Nil(t, result)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Validation did not finish within 30ms", err.Error())
+	}
+
+	result, err = schema.ValidateWithTimeout(document, time.Minute)
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+}
+
+func TestErrorTypeConstants(t *testing.T) {
+	result, err := Validate(NewStringLoader(simpleSchema), NewStringLoader(`{"firstName" : 1, "age" : -1}`))
+	require.Nil(t, err)
+
+	var types []string
+	for _, resultError := range result.Errors() {
+		types = append(types, resultError.Type())
+	}
+	assert.ElementsMatch(t, []string{ErrorTypeRequired, ErrorTypeInvalidType, ErrorTypeNumberGTE}, types)
+}
+
+func TestNotErrorDescription(t *testing.T) {
+	testCases := []struct {
+		schema   string
+		expected string
+	}{
+		{
+			`{"not" : {"type" : "string", "maxLength" : 3}}`,
+			`Must not validate the schema (not): {"maxLength":3,"type":"string"}`,
+		},
+		{
+			`{"not" : {"title" : "Short string", "type" : "string", "maxLength" : 3}}`,
+			`Must not validate the schema (not): Short string`,
+		},
+		{
+			`{"not" : {"$ref" : "#/definitions/short"}, "definitions" : {"short" : {"title" : "Short string", "maxLength" : 3}}}`,
+			`Must not validate the schema (not): Short string`,
+		},
+		{
+			`{"not" : {"$ref" : "#/definitions/short"}, "definitions" : {"short" : {"maxLength" : 3}}}`,
+			`Must not validate the schema (not): #/definitions/short`,
+		},
+		{
+			`{"not" : {"$ref" : "#/definitions/short"}, "definitions" : {"short" : {"$id" : "http://example.com/short.json", "maxLength" : 3}}}`,
+			`Must not validate the schema (not): http://example.com/short.json`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		result, err := Validate(NewStringLoader(testCase.schema), NewStringLoader(`"abc"`))
+		require.Nil(t, err)
+		if assert.Len(t, result.Errors(), 1) {
+			assert.Equal(t, ErrorTypeNumberNot, result.Errors()[0].Type())
+			assert.Equal(t, testCase.expected, result.Errors()[0].Description())
+		}
+	}
+}
+
+func TestMissingRequired(t *testing.T) {
+	schema := NewStringLoader(`{
+		"required" : ["name", "email", "address"],
+		"properties" : {
+			"address" : {"required" : ["city"]}
+		}
+	}`)
+
+	result, err := Validate(schema, NewStringLoader(`{"address" : {}}`))
+	require.Nil(t, err)
+	assert.Equal(t, map[string][]string{
+		"(root)":         {"name", "email"},
+		"(root).address": {"city"},
+	}, result.MissingRequired())
+
+	result, err = Validate(schema, NewStringLoader(`{"name" : "John", "email" : "john@example.com", "address" : {"city" : "Amsterdam"}}`))
+	require.Nil(t, err)
+	assert.Empty(t, result.MissingRequired())
+}
+
+func TestResultErr(t *testing.T) {
+	schema := NewStringLoader(`{
+		"properties" : {"age" : {"minimum" : 0}},
+		"required" : ["name"]
+	}`)
+
+	result, err := Validate(schema, NewStringLoader(`{"name" : "John", "age" : 30}`))
+	require.Nil(t, err)
+	assert.Nil(t, result.Err())
+
+	result, err = Validate(schema, NewStringLoader(`{"age" : 30}`))
+	require.Nil(t, err)
+	validationErr := result.Err()
+	require.NotNil(t, validationErr)
+	assert.Equal(t, "(root): name is required", validationErr.Error())
+
+	var requiredErr *RequiredError
+	if assert.True(t, errors.As(validationErr, &requiredErr)) {
+		assert.Equal(t, "name", requiredErr.Details()["property"])
+	}
+	var target *ValidationError
+	if assert.True(t, errors.As(validationErr, &target)) {
+		assert.Len(t, target.Errors, 1)
+	}
+
+	result, err = Validate(schema, NewStringLoader(`{"age" : -1}`))
+	require.Nil(t, err)
+	assert.Contains(t, result.Err().Error(), "(and 1 more errors)")
+	var numberErr *NumberGTEError
+	assert.True(t, errors.As(result.Err(), &numberErr))
+}
+
+func TestResultFilterByType(t *testing.T) {
+	schema := NewStringLoader(`{
+		"items" : {"type" : "integer", "minimum" : 0},
+		"maxItems" : 3,
+		"uniqueItems" : true
+	}`)
+
+	result, err := Validate(schema, NewStringLoader(`[1, "a", -1, 2.5, 1]`))
+	require.Nil(t, err)
+	require.True(t, len(result.Errors()) > 3)
+
+	filtered := result.FilterByType("invalid_type")
+	if assert.Len(t, filtered, 2) {
+		assert.Equal(t, "(root).1", filtered[0].Context().String())
+		assert.Equal(t, "(root).3", filtered[1].Context().String())
+	}
+	assert.Len(t, result.FilterByType("invalid_type", "number_gte", "array_max_items"), 4)
+	assert.Empty(t, result.FilterByType("required"))
+}
+
+func TestResultRejections(t *testing.T) {
+	schema := NewStringLoader(`{
+		"properties" : {
+			"name" : {"type" : "string"},
+			"age" : {"minimum" : 18}
+		}
+	}`)
+
+	result, err := Validate(schema, NewStringLoader(`{"name" : 7, "age" : 12}`))
+	require.Nil(t, err)
+
+	rejections := map[string]Rejection{}
+	for _, rejection := range result.Rejections() {
+		rejections[rejection.Path] = rejection
+	}
+	assert.Equal(t, map[string]Rejection{
+		"name": {Path: "name", Value: json.Number("7"), Type: ErrorTypeInvalidType},
+		"age":  {Path: "age", Value: json.Number("12"), Type: ErrorTypeNumberGTE},
+	}, rejections)
+
+	result, err = Validate(schema, NewStringLoader(`{"name" : "gopher"}`))
+	require.Nil(t, err)
+	assert.Empty(t, result.Rejections())
+}
+
+func TestMinMaxProperties(t *testing.T) {
+	drafts := []string{
+		"http://json-schema.org/draft-04/schema#",
+		"http://json-schema.org/draft-06/schema#",
+		"http://json-schema.org/draft-07/schema#",
+	}
+	for _, draft := range drafts {
+		schema, err := NewSchema(NewStringLoader(`{"$schema" : "` + draft + `", "minProperties" : 2, "maxProperties" : 3}`))
+		require.Nil(t, err, draft)
+
+		result, err := schema.Validate(NewStringLoader(`{"a" : 1}`))
+		require.Nil(t, err)
+		if assert.Len(t, result.Errors(), 1, draft) {
+			assert.Equal(t, ErrorTypeArrayMinProperties, result.Errors()[0].Type())
+			assert.Equal(t, 2, result.Errors()[0].Details()["min"])
+			assert.Equal(t, 1, result.Errors()[0].Details()["count"])
+			assert.Equal(t, "Must have at least 2 properties", result.Errors()[0].Description())
+		}
+
+		result, err = schema.Validate(NewStringLoader(`{"a" : 1, "b" : 2, "c" : 3, "d" : 4}`))
+		require.Nil(t, err)
+		if assert.Len(t, result.Errors(), 1, draft) {
+			assert.Equal(t, ErrorTypeArrayMaxProperties, result.Errors()[0].Type())
+			assert.Equal(t, 3, result.Errors()[0].Details()["max"])
+			assert.Equal(t, 4, result.Errors()[0].Details()["count"])
+		}
+
+		result, err = schema.Validate(NewStringLoader(`{"a" : 1, "b" : 2}`))
+		require.Nil(t, err)
+		assert.True(t, result.Valid(), draft)
+
+		// Only objects are constrained
+		result, err = schema.Validate(NewStringLoader(`[1]`))
+		require.Nil(t, err)
+		assert.True(t, result.Valid(), draft)
+	}
+}
+
+func TestMaxItemsExcess(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{"maxItems" : 2}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`["a", "b", "c", "d", "e"]`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeArrayMaxItems, result.Errors()[0].Type())
+		assert.Equal(t, 2, result.Errors()[0].Details()["max"])
+		assert.Equal(t, 2, result.Errors()[0].Details()["from"])
+		assert.Equal(t, 4, result.Errors()[0].Details()["to"])
+	}
+
+	result, err = schema.Validate(NewStringLoader(`["a", "b"]`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+}
+
+func TestShortCircuitAllOf(t *testing.T) {
+	schemaJSON := `{
+		"allOf" : [
+			{"type" : "string"},
+			{"minLength" : 5},
+			{"pattern" : "^[0-9]+$"}
+		]
+	}`
+	document := NewStringLoader(`"abc"`)
+
+	schema, err := NewSchema(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	result, err := schema.Validate(document)
+	require.Nil(t, err)
+	types := []string{}
+	for _, resultErr := range result.Errors() {
+		types = append(types, resultErr.Type())
+	}
+	assert.Equal(t, []string{ErrorTypeStringGTE, ErrorTypePattern, ErrorTypeNumberAllOf}, types)
+	assert.Equal(t, 1, result.Errors()[2].Details()["index"])
+
+	sl := NewSchemaLoader()
+	sl.ShortCircuitAllOf = true
+	schema, err = sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	result, err = schema.Validate(document)
+	require.Nil(t, err)
+	types = []string{}
+	for _, resultErr := range result.Errors() {
+		types = append(types, resultErr.Type())
+	}
+	assert.Equal(t, []string{ErrorTypeStringGTE, ErrorTypeNumberAllOf}, types, "only the first failing subschema is reported")
+	assert.Equal(t, 1, result.Errors()[1].Details()["index"])
+
+	result, err = schema.Validate(NewStringLoader(`"12345"`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+}
+
+func TestOverlappingPatternsAnnotation(t *testing.T) {
+	schemaJSON := `{
+		"patternProperties" : {
+			"^x-" : {"type" : "string"},
+			"-id$" : {"minLength" : 3},
+			"^y-" : {}
+		}
+	}`
+	document := NewStringLoader(`{"x-id" : "abc", "x-name" : "a", "y-id" : "def"}`)
+
+	sl := NewSchemaLoader()
+	sl.ReportOverlappingPatterns = true
+	schema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(document)
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	annotations := map[string]interface{}{}
+	for _, annotation := range result.Annotations() {
+		assert.Equal(t, KEY_PATTERN_PROPERTIES, annotation.Keyword)
+		annotations[annotation.Context.String()] = annotation.Value
+	}
+	assert.Equal(t, map[string]interface{}{
+		"(root).x-id": []string{"-id$", "^x-"},
+		"(root).y-id": []string{"-id$", "^y-"},
+	}, annotations)
+
+	// The diagnostic is off by default
+	result, err = Validate(NewStringLoader(schemaJSON), document)
+	require.Nil(t, err)
+	assert.Empty(t, result.Annotations())
+}
+
+func TestEnumValues(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{"enum" : ["a", 1, 2.5, true, null, {"b" : [1, 2]}, [3, {"c" : null}]]}`))
+	require.Nil(t, err)
+
+	for _, document := range []string{`"a"`, `1`, `1.0`, `2.50`, `true`, `null`, `{"b" : [1, 2.0]}`, `[3, {"c" : null}]`} {
+		result, err := schema.Validate(NewStringLoader(document))
+		require.Nil(t, err)
+		assert.True(t, result.Valid(), document)
+	}
+
+	for _, document := range []string{`"b"`, `"1"`, `2`, `false`, `{"b" : [2, 1]}`, `[3]`, `{}`} {
+		result, err := schema.Validate(NewStringLoader(document))
+		require.Nil(t, err)
+		assert.False(t, result.Valid(), document)
+	}
+}
+
+func BenchmarkLargeEnum(b *testing.B) {
+	enum := make([]interface{}, 10000)
+	for i := range enum {
+		enum[i] = fmt.Sprintf("value-%d", i)
+	}
+	schema, err := NewSchema(NewGoLoader(map[string]interface{}{"enum": enum}))
+	require.Nil(b, err)
+	document := NewStringLoader(`"value-9999"`)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := schema.Validate(document)
+		if err != nil || !result.Valid() {
+			b.Fatal("value-9999 should be valid")
+		}
+	}
+}
+
+func TestUniqueItems(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{"uniqueItems" : true}`))
+	require.Nil(t, err)
+
+	for _, document := range []string{`[]`, `[1, "1", true, null, {}, []]`, `[{"a" : 1, "b" : 2}, {"a" : 2, "b" : 1}]`, `[[1, 2], [2, 1]]`, `[1.5, 2.5]`} {
+		result, err := schema.Validate(NewStringLoader(document))
+		require.Nil(t, err)
+		assert.True(t, result.Valid(), document)
+	}
+
+	for _, document := range []string{`[1, 1.0]`, `[2.50, 2.5]`, `[{"a" : 1, "b" : 2}, {"b" : 2.0, "a" : 1}]`, `[[1, {"c" : null}], [1, {"c" : null}]]`, `["a", "b", "a"]`} {
+		result, err := schema.Validate(NewStringLoader(document))
+		require.Nil(t, err)
+		assert.False(t, result.Valid(), document)
+	}
+
+	result, err := schema.Validate(NewStringLoader(`["a", "b", "a", "a"]`))
+	require.Nil(t, err)
+	details := []ErrorDetails{}
+	for _, resultErr := range result.Errors() {
+		details = append(details, ErrorDetails{"i": resultErr.Details()["i"], "j": resultErr.Details()["j"]})
+	}
+	assert.Equal(t, []ErrorDetails{{"i": 0, "j": 2}, {"i": 2, "j": 3}}, details)
+}
+
+func BenchmarkUniqueItems(b *testing.B) {
+	items := make([]interface{}, 100000)
+	for i := range items {
+		items[i] = map[string]interface{}{"id": json.Number(fmt.Sprint(i)), "tags": []interface{}{"a", "b"}}
+	}
+	schema, err := NewSchema(NewStringLoader(`{"uniqueItems" : true}`))
+	require.Nil(b, err)
+	document := NewRawLoader(items)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := schema.Validate(document)
+		if err != nil || !result.Valid() {
+			b.Fatal("the items should be unique")
+		}
+	}
+}
+
+// deepDocument returns an object nested depth levels deep through "child", every level holding an array of integers
+func deepDocument(depth int, invalidAt int) interface{} {
+	var document interface{}
+	for level := depth - 1; level >= 0; level-- {
+		items := make([]interface{}, 10)
+		for i := range items {
+			items[i] = json.Number(fmt.Sprint(i))
+		}
+		if level == invalidAt {
+			items[3] = "three"
+		}
+		node := map[string]interface{}{"items": items}
+		if document != nil {
+			node["child"] = document
+		}
+		document = node
+	}
+	return document
+}
+
+const deepSchema = `{
+	"$ref" : "#/definitions/node",
+	"definitions" : {
+		"node" : {
+			"type" : "object",
+			"properties" : {
+				"child" : {"$ref" : "#/definitions/node"},
+				"items" : {"type" : "array", "items" : {"type" : "integer"}}
+			},
+			"patternProperties" : {"^i" : {"type" : "array"}}
+		}
+	}
+}`
+
+func TestValidateDeepDocumentContexts(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(deepSchema))
+	require.Nil(t, err)
+
+	// Validating a valid document first puts contexts in the pool, which must not end up in the paths of errors
+	result, err := schema.validateDocument(deepDocument(50, -1))
+	require.Nil(t, err)
+	require.True(t, result.Valid())
+
+	result, err = schema.validateDocument(deepDocument(50, 2))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "(root).child.child.items.3", result.Errors()[0].Context().String())
+	}
+	result, err = schema.validateDocument(deepDocument(50, 1))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "(root).child.items.3", result.Errors()[0].Context().String())
+	}
+}
+
+func BenchmarkValidateDeepDocument(b *testing.B) {
+	schema, err := NewSchema(NewStringLoader(deepSchema))
+	require.Nil(b, err)
+	document := deepDocument(1000, -1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := schema.validateDocument(document)
+		if err != nil || !result.Valid() {
+			b.Fatal("the document should be valid")
+		}
+	}
+}
+
+func TestValidationHook(t *testing.T) {
+	type visit struct {
+		path string
+		ok   bool
+	}
+	var visits []visit
+	hook := func(path string, value interface{}, ok bool) {
+		visits = append(visits, visit{path, ok})
+	}
+
+	sl := NewSchemaLoader()
+	sl.SetValidationHook(hook)
+	schema, err := sl.Compile(NewStringLoader(`{
+		"properties" : {
+			"name" : {"type" : "string"},
+			"tags" : {"items" : {"type" : "string"}},
+			"address" : {
+				"properties" : {
+					"zip" : {"type" : "string"},
+					"city" : {"type" : "string"}
+				}
+			}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{
+		"name" : "John",
+		"tags" : ["a", 1],
+		"address" : {"zip" : 1234, "city" : "Amsterdam"},
+		"unknown" : true
+	}`))
+	require.Nil(t, err)
+	assert.Len(t, result.Errors(), 2)
+
+	assert.Equal(t, []visit{
+		{"(root)", false},
+		{"(root).address", false},
+		{"(root).address.city", true},
+		{"(root).address.zip", false},
+		{"(root).name", true},
+		{"(root).tags", false},
+		{"(root).tags.0", true},
+		{"(root).tags.1", false},
+	}, visits)
+
+	// A property "a.b" is not the same node as the property "b" of "a"
+	visits = nil
+	sl = NewSchemaLoader()
+	sl.SetValidationHook(hook)
+	schema, err = sl.Compile(NewStringLoader(`{
+		"properties" : {
+			"a" : {"properties" : {"b" : {"type" : "string"}}},
+			"a.b" : {"type" : "integer"}
+		}
+	}`))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`{"a" : {"b" : "x"}, "a.b" : "y"}`))
+	require.Nil(t, err)
+	assert.Len(t, result.Errors(), 1)
+	assert.Equal(t, []visit{
+		{"(root)", false},
+		{"(root).a", true},
+		{"(root).a.b", true},
+		{"(root).a.b", false},
+	}, visits)
+}
+
+func TestValidatePartial(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"required" : ["name", "age"],
+		"properties" : {
+			"name" : {"type" : "string"},
+			"age" : {"type" : "integer"},
+			"address" : {
+				"required" : ["city"],
+				"properties" : {"zip" : {"type" : "string"}}
+			}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.ValidatePartial(NewStringLoader(`{"name" : "John", "address" : {"zip" : "1234AB"}}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.ValidatePartial(NewStringLoader(`{"age" : "42"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeInvalidType, result.Errors()[0].Type())
+		assert.Equal(t, "age", result.Errors()[0].Field())
+	}
+
+	// The same document is incomplete when validated as a whole
+	result, err = schema.Validate(NewStringLoader(`{"name" : "John", "address" : {"zip" : "1234AB"}}`))
+	require.Nil(t, err)
+	assert.Len(t, result.Errors(), 2)
+}
+
+func TestMatchedBranchAnnotations(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"shape" : {
+				"oneOf" : [
+					{"properties" : {"kind" : {"const" : "circle"}}, "required" : ["radius"]},
+					{"properties" : {"kind" : {"const" : "square"}}, "required" : ["side"]}
+				]
+			},
+			"id" : {
+				"anyOf" : [{"type" : "integer"}, {"type" : "string"}, {"type" : "number"}]
+			}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"shape" : {"kind" : "square", "side" : 2}, "id" : 5}`))
+	require.Nil(t, err)
+	require.True(t, result.Valid())
+
+	annotations := map[string]interface{}{}
+	for _, annotation := range result.Annotations() {
+		annotations[annotation.Context.String()+" "+annotation.Keyword] = annotation.Value
+	}
+	assert.Equal(t, map[string]interface{}{
+		"(root).shape oneOf": 1,
+		"(root).id anyOf":    0,
+	}, annotations)
+
+	// Nothing is recorded for branches that don't match
+	result, err = schema.Validate(NewStringLoader(`{"shape" : {"kind" : "triangle"}, "id" : true}`))
+	require.Nil(t, err)
+	assert.Empty(t, result.Annotations())
+}
+
+func TestValidateStringAndBytes(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(simpleSchema))
+	require.Nil(t, err)
+
+	valid := `{"firstName" : "John", "lastName" : "Doe", "age" : 42}`
+	invalid := `{"firstName" : "John", "age" : -1}`
+
+	for _, document := range []string{valid, invalid} {
+		expected, err := schema.Validate(NewStringLoader(document))
+		require.Nil(t, err)
+
+		result, err := schema.ValidateString(document)
+		require.Nil(t, err)
+		assert.Equal(t, expected.Errors(), result.Errors())
+
+		result, err = schema.ValidateBytes([]byte(document))
+		require.Nil(t, err)
+		assert.Equal(t, expected.Errors(), result.Errors())
+	}
+
+	result, err := schema.ValidateString(invalid)
+	require.Nil(t, err)
+	assert.Len(t, result.Errors(), 2)
+
+	_, err = schema.ValidateString(`{`)
+	assert.NotNil(t, err)
+	_, err = schema.ValidateBytes([]byte(`{`))
+	assert.NotNil(t, err)
+}
+
+func TestRefErrorSchemaURI(t *testing.T) {
+	sl := NewSchemaLoader()
+	err := sl.AddSchema("http://example.com/base.json", NewStringLoader(`{
+		"type" : "object",
+		"required" : ["id"],
+		"properties" : {
+			"id" : {"type" : "integer"},
+			"name" : {"$ref" : "#/definitions/name"}
+		},
+		"definitions" : {
+			"name" : {"type" : "string"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	schema, err := sl.Compile(NewStringLoader(`{
+		"$id" : "http://example.com/person.json",
+		"allOf" : [
+			{"$ref" : "http://example.com/base.json"},
+			{"required" : ["email"]}
+		]
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"name" : 5}`))
+	require.Nil(t, err)
+
+	sources := map[string]string{}
+	for _, resultError := range result.Errors() {
+		source := resultError.Type() + " " + resultError.Field()
+		if property, ok := resultError.Details()["property"]; ok {
+			source += " " + property.(string)
+		}
+		sources[source] = resultError.SchemaURI()
+	}
+	assert.Equal(t, map[string]string{
+		"required (root) id":    "http://example.com/base.json",
+		"invalid_type name":     "http://example.com/base.json#/definitions/name",
+		"required (root) email": "http://example.com/person.json",
+		"number_all_of (root)":  "http://example.com/person.json",
+	}, sources)
+}
+
+func TestValidateCanonical(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(simpleSchema))
+	require.Nil(t, err)
+
+	result, first, err := schema.ValidateCanonical(NewStringLoader(`{
+		"lastName" : "<Doe>",
+		"firstName" : "John",
+		"age" : 42.0,
+		"scores" : [1.50, 2e2, {"b" : 1, "a" : 0.0}]
+	}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, second, err := schema.ValidateCanonical(NewStringLoader(`{"age":42,"firstName":"John","scores":[1.5,200,{"a":0,"b":1}],"lastName":"<Doe>"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	assert.Equal(t, `{"age":42,"firstName":"John","lastName":"<Doe>","scores":[1.5,200,{"a":0,"b":1}]}`, string(first))
+	assert.Equal(t, first, second)
+
+	result, canonical, err := schema.ValidateCanonical(NewStringLoader(`{"firstName" : "John", "age" : 1.25e1}`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+	assert.Equal(t, `{"age":12.5,"firstName":"John"}`, string(canonical))
+
+	// Numbers are not rounded to float64
+	_, canonical, err = schema.ValidateCanonical(NewStringLoader(`[0.10000000000000000001, 0.1e0, 1.25e-3, -5e-1]`))
+	require.Nil(t, err)
+	assert.Equal(t, `[0.10000000000000000001,0.1,0.00125,-0.5]`, string(canonical))
+	schema, err = NewSchema(NewStringLoader(`{"uniqueItems" : true}`))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`[0.1, 0.10000000000000000001]`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid(), "%v", result.Errors())
+}
+
+func TestAdditionalPropertiesSubschema(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {"name" : {"type" : "string"}},
+		"patternProperties" : {"^x-" : {"type" : "boolean"}},
+		"additionalProperties" : {"type" : "integer", "minimum" : 0}
+	}`))
+	require.Nil(t, err)
+
+	// Declared and pattern matched keys are not validated against additionalProperties
+	result, err := schema.Validate(NewStringLoader(`{"name" : "John", "x-flag" : true, "count" : 3}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`{"name" : "John", "x-flag" : false, "count" : "3", "other" : -1, "x-bad" : 1}`))
+	require.Nil(t, err)
+
+	errs := map[string]string{}
+	for _, resultErr := range result.Errors() {
+		errs[resultErr.Context().String()] = resultErr.Type()
+	}
+	assert.Equal(t, map[string]string{
+		"(root).count": "invalid_type",
+		"(root).other": "number_gte",
+		"(root).x-bad": "invalid_type",
+	}, errs)
+}
+
+func TestPatternIsUnanchored(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"code" : {"pattern" : "[0-9]{3}"},
+			"anchored" : {"pattern" : "^[0-9]{3}$"},
+			"expression" : {"format" : "regex"}
+		},
+		"patternProperties" : {"id" : {"type" : "integer"}},
+		"additionalProperties" : false
+	}`))
+	require.Nil(t, err)
+
+	// The patterns only have to match part of the string
+	result, err := schema.Validate(NewStringLoader(`{"code" : "abc123def", "anchored" : "123", "expression" : "[0-9]", "user_id_x" : 5}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`{"code" : "12", "anchored" : "abc123def", "user_id" : "x"}`))
+	require.Nil(t, err)
+	fields := []string{}
+	for _, resultErr := range result.Errors() {
+		fields = append(fields, resultErr.Field())
+	}
+	assert.ElementsMatch(t, []string{"code", "anchored", "user_id"}, fields)
+}
+
+func TestMaxDepth(t *testing.T) {
+	schemaJSON := `{"items" : {"$ref" : "#"}, "additionalProperties" : {"$ref" : "#"}}`
+	nested := func(depth int) interface{} {
+		var document interface{} = "leaf"
+		for i := 0; i < depth; i++ {
+			if i%2 == 0 {
+				document = []interface{}{document}
+			} else {
+				document = map[string]interface{}{"a": document}
+			}
+		}
+		return document
+	}
+
+	sl := NewSchemaLoader()
+	sl.SetMaxDepth(50)
+	schema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewRawLoader(nested(50)))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	_, err = schema.Validate(NewRawLoader(nested(51)))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "Document is nested deeper than the maximum depth of 50 at (root).0.a.0")
+	}
+
+	// The default limit is generous, but finite
+	schema, err = NewSchema(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	result, err = schema.Validate(NewRawLoader(nested(5000)))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	_, err = schema.Validate(NewRawLoader(nested(defaultMaxDepth + 1)))
+	assert.NotNil(t, err)
+}
+
+func TestEnumSuggestion(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{"enum" : ["red", "green", "blue", 5]}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`"gren"`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		resultErr := result.Errors()[0]
+		assert.Equal(t, []interface{}{"red", "green", "blue", json.Number("5")}, resultErr.Details()["values"])
+		assert.Equal(t, "green", resultErr.Details()["suggestion"])
+		assert.Equal(t, `(root) must be one of the following: "red", "green", "blue", 5, did you mean "green"?`, resultErr.Description())
+	}
+
+	// Values that aren't close to any allowed string get no suggestion
+	result, err = schema.Validate(NewStringLoader(`"yellow"`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		_, ok := result.Errors()[0].Details()["suggestion"]
+		assert.False(t, ok)
+		assert.Equal(t, `(root) must be one of the following: "red", "green", "blue", 5`, result.Errors()[0].Description())
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"name" : {"type" : "string", "minLength" : 2},
+			"kind" : {"oneOf" : [{"const" : "a"}, {"const" : "b"}]},
+			"tags" : {"items" : {"anyOf" : [{"type" : "string"}, {"type" : "integer"}]}},
+			"extra" : {"not" : {"type" : "null"}}
+		},
+		"required" : ["name"]
+	}`))
+	require.Nil(t, err)
+
+	for document, valid := range map[string]bool{
+		`{"name" : "John", "kind" : "a", "tags" : ["x", 1], "extra" : 5}`: true,
+		`{"name" : "John"}`:               true,
+		`{}`:                              false,
+		`{"name" : "J"}`:                  false,
+		`{"name" : "John", "kind" : "c"}`: false,
+		`{"name" : "John", "tags" : ["x", true]}`: false,
+		`{"name" : "John", "extra" : null}`:       false,
+	} {
+		isValid, err := schema.IsValid(NewStringLoader(document))
+		require.Nil(t, err)
+		assert.Equal(t, valid, isValid, document)
+
+		result, err := schema.Validate(NewStringLoader(document))
+		require.Nil(t, err)
+		assert.Equal(t, result.Valid(), isValid, document)
+	}
+
+	_, err = schema.IsValid(NewStringLoader(`{`))
+	assert.NotNil(t, err)
+}
+
+const benchmarkInvalidDocument = `{"name" : "J", "kind" : "c", "tags" : ["x", true, null, 1.5], "extra" : null}`
+
+func BenchmarkValidateInvalid(b *testing.B) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"name" : {"type" : "string", "minLength" : 2},
+			"kind" : {"oneOf" : [{"const" : "a"}, {"const" : "b"}]},
+			"tags" : {"items" : {"anyOf" : [{"type" : "string"}, {"type" : "integer"}]}},
+			"extra" : {"not" : {"type" : "null"}}
+		}
+	}`))
+	require.Nil(b, err)
+	document := NewStringLoader(benchmarkInvalidDocument)
+
+	b.Run("Validate", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			result, err := schema.Validate(document)
+			if err != nil || result.Valid() {
+				b.Fatal("the document should be invalid")
+			}
+		}
+	})
+
+	b.Run("IsValid", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			valid, err := schema.IsValid(document)
+			if err != nil || valid {
+				b.Fatal("the document should be invalid")
+			}
+		}
+	})
+}
+
+func TestErrorFieldPointer(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"a.b" : {
+				"properties" : {"c/d" : {"items" : {"type" : "integer"}}}
+			},
+			"e~f" : {"type" : "string"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"a.b" : {"c/d" : [1, "x"]}, "e~f" : 1}`))
+	require.Nil(t, err)
+
+	pointers := map[string]string{}
+	for _, resultErr := range result.Errors() {
+		pointers[resultErr.FieldPointer()] = resultErr.Field()
+	}
+	assert.Equal(t, map[string]string{
+		"/a.b/c~1d/1": "a.b.c/d.1",
+		"/e~0f":       "e~f",
+	}, pointers)
+
+	result, err = schema.Validate(NewStringLoader(`[]`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = Validate(NewStringLoader(`{"type" : "object"}`), NewStringLoader(`[]`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "", result.Errors()[0].FieldPointer())
+	}
+}
+
+func TestErrorPath(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"users" : {
+				"items" : {"properties" : {"name" : {"type" : "string"}}}
+			},
+			"0" : {"type" : "string"}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"users" : [{"name" : "a"}, {"name" : 1}], "0" : 1}`))
+	require.Nil(t, err)
+
+	paths := map[string][]interface{}{}
+	for _, resultErr := range result.Errors() {
+		paths[resultErr.Field()] = resultErr.Path()
+	}
+	assert.Equal(t, map[string][]interface{}{
+		"users.1.name": {"users", 1, "name"},
+		"0":            {"0"},
+	}, paths)
+
+	result, err = Validate(NewStringLoader(`{"type" : "object"}`), NewStringLoader(`[]`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, []interface{}{}, result.Errors()[0].Path())
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"required" : ["id"],
+		"properties" : {"id" : {"type" : "integer"}, "name" : {"type" : "string"}}
+	}`))
+	require.Nil(t, err)
+
+	summary, results := ValidateAll(schema, []JSONLoader{
+		NewStringLoader(`{"id" : 1}`),
+		NewStringLoader(`{"id" : "x", "name" : 2}`),
+		NewStringLoader(`{"name" : "a"}`),
+		NewStringLoader(`{"id" : 2, "name" : "b"}`),
+		NewStringLoader(`{"id" :`),
+	})
+
+	assert.Equal(t, Summary{
+		Total:   5,
+		Passed:  2,
+		Failed:  2,
+		Errored: 1,
+		ErrorTypes: map[string]int{
+			"invalid_type": 2,
+			"required":     1,
+		},
+	}, summary)
+	if assert.Len(t, results, 5) {
+		assert.True(t, results[0].Valid())
+		assert.False(t, results[1].Valid())
+		assert.Nil(t, results[4])
+	}
+}
+
+func TestUnevaluatedItemsContains(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"items" : [{"type" : "string"}],
+		"contains" : {"type" : "integer"},
+		"unevaluatedItems" : false
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`["a", 1, 2]`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`["a", 1, true, 2]`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "2", result.Errors()[0].Field())
+		assert.Equal(t, "false", result.Errors()[0].Type())
+	}
+
+	schema, err = NewSchema(NewStringLoader(`{
+		"allOf" : [{"contains" : {"const" : 1}}],
+		"unevaluatedItems" : {"type" : "string"}
+	}`))
+	require.Nil(t, err)
+
+	result, err = schema.Validate(NewStringLoader(`[1, "a", 1]`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.Validate(NewStringLoader(`[1, 2]`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "1", result.Errors()[0].Field())
+	}
+}
+
+func TestErrorSchemaSnippet(t *testing.T) {
+	compile := func(l JSONLoader) (*Schema, error) {
+		sl := NewSchemaLoader()
+		sl.SchemaSnippets = true
+		return sl.Compile(l)
+	}
+	schema, err := compile(NewStringLoader(`{
+		"properties" : {
+			"age" : {"type" : "integer", "minimum": 0},
+			"tags" : {"items" : {"$ref" : "#/definitions/tag"}}
+		},
+		"required" : ["age"],
+		"definitions" : {
+			"tag" : {"maxLength" :   3}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"age" : -1, "tags" : ["a", "long"]}`))
+	require.Nil(t, err)
+
+	snippets := map[string]string{}
+	for _, resultErr := range result.Errors() {
+		snippets[resultErr.Field()] = resultErr.SchemaSnippet()
+	}
+	assert.Equal(t, map[string]string{
+		"age":    `"minimum": 0`,
+		"tags.1": `"maxLength" :   3`,
+	}, snippets)
+
+	result, err = schema.Validate(NewStringLoader(`{}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, `"required" : ["age"]`, result.Errors()[0].SchemaSnippet())
+	}
+
+	// In draft 4 "exclusiveMinimum" makes "minimum" exclusive
+	schema, err = compile(NewStrictLoader(NewStringLoader(`{"$schema" : "http://json-schema.org/draft-04/schema#", "minimum" : 0, "exclusiveMinimum" : true}`)))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`0`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, `"minimum" : 0`, result.Errors()[0].SchemaSnippet())
+		assert.Equal(t, "minimum", result.Errors()[0].(*NumberGTError).pythonStyle().Validator)
+	}
+	schema, err = compile(NewStrictLoader(NewStringLoader(`{"properties" : {"b" : {"minimum" : 1}, "a" : {"maxLength" : 2}}}`)))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`{"a" : "abc", "b" : 0}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 2) {
+		assert.Equal(t, "b", result.Errors()[0].Field(), "strict loaded schemas keep the declared order")
+		assert.Equal(t, `"minimum" : 1`, result.Errors()[0].SchemaSnippet())
+		assert.Equal(t, `"maxLength" : 2`, result.Errors()[1].SchemaSnippet())
+	}
+	_, err = compile(NewStrictLoader(NewStringLoader(`{"minimum" : 0, "minimum" : 1}`)))
+	assert.EqualError(t, err, `Duplicate key "minimum" in (root)`)
+	schema, err = compile(NewStringLoader(`{"exclusiveMinimum" : 0}`))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`0`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, `"exclusiveMinimum" : 0`, result.Errors()[0].SchemaSnippet())
+	}
+
+	schema, err = compile(NewGoLoader(map[string]interface{}{"minimum": 0}))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`-1`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "", result.Errors()[0].SchemaSnippet())
+	}
+
+	// The text of schemas is only kept when asked for
+	schema, err = NewSchema(NewStringLoader(`{"minimum" : 0}`))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`-1`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "", result.Errors()[0].SchemaSnippet())
+	}
+	_, source, err := loadJSONWithSource(NewStringLoader(`{"minimum" : 0}`), false)
+	require.Nil(t, err)
+	assert.Nil(t, source.text, "only the order of the keys is recorded")
+	assert.Nil(t, source.spans)
+}
+
+func TestNullableTypeUnion(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"nickname" : {"type" : ["string", "null"], "minLength" : 2}
+		}
+	}`))
+	require.Nil(t, err)
+
+	for _, document := range []string{`{"nickname" : "Jo"}`, `{"nickname" : null}`, `{}`} {
+		result, err := schema.Validate(NewStringLoader(document))
+		require.Nil(t, err)
+		assert.True(t, result.Valid(), document)
+	}
+
+	result, err := schema.Validate(NewStringLoader(`{"nickname" : "J"}`))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeStringGTE, result.Errors()[0].Type())
+	}
+
+	for _, document := range []string{`{"nickname" : 5}`, `{"nickname" : ["Jo"]}`, `{"nickname" : {}}`, `{"nickname" : false}`} {
+		result, err := schema.Validate(NewStringLoader(document))
+		require.Nil(t, err)
+		if assert.Len(t, result.Errors(), 1, document) {
+			assert.Equal(t, ErrorTypeInvalidType, result.Errors()[0].Type())
+			assert.Equal(t, "[string,null]", result.Errors()[0].Details()["expected"])
+		}
+	}
+}
+
+func TestPythonStyleErrors(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"age" : {"type" : "integer", "minimum" : 0},
+			"tags" : {"items" : {"$ref" : "#/definitions/tag"}}
+		},
+		"definitions" : {
+			"tag" : {"maxLength" : 3}
+		}
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"age" : -1}`))
+	require.Nil(t, err)
+	output, err := json.Marshal(result.PythonStyleErrors())
+	require.Nil(t, err)
+	assert.JSONEq(t, `[{
+		"message" : "Must be greater than or equal to 0",
+		"path" : ["age"],
+		"schema_path" : ["properties", "age", "minimum"],
+		"validator" : "minimum",
+		"validator_value" : 0
+	}]`, string(output))
+
+	result, err = schema.Validate(NewStringLoader(`{"tags" : ["a", "long"]}`))
+	require.Nil(t, err)
+	errs := result.PythonStyleErrors()
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, []interface{}{"tags", 1}, errs[0].Path)
+		assert.Equal(t, []interface{}{"properties", "tags", "items", "$ref", "maxLength"}, errs[0].SchemaPath)
+	}
+}
+
+func TestResultReport(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"properties" : {
+			"name" : {"type" : "string", "minLength" : 3, "pattern" : "^[A-Z]"},
+			"age" : {"type" : "integer", "minimum" : 0}
+		},
+		"required" : ["name", "age"]
+	}`))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"name" : "jo", "age" : -1}`))
+	require.Nil(t, err)
+	assert.Equal(t, `The document is not valid, 3 errors:
+  age
+    - Must be greater than or equal to 0
+  name
+    - Does not match pattern '^[A-Z]'
+    - String length must be greater than or equal to 3
+`, result.Report())
+
+	result, err = schema.Validate(NewStringLoader(`{"name" : "John", "age" : 30}`))
+	require.Nil(t, err)
+	assert.Equal(t, "The document is valid\n", result.Report())
+}
+
+func TestValidateObjectStream(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"type" : "object",
+		"properties" : {
+			"id" : {"type" : "string"},
+			"name" : {"type" : "string"},
+			"version" : {"type" : "integer"}
+		},
+		"patternProperties" : {
+			"^item" : {"type" : "integer", "minimum" : 0}
+		},
+		"additionalProperties" : false,
+		"required" : ["id", "name"],
+		"dependencies" : {"id" : ["version"]}
+	}`))
+	require.Nil(t, err)
+
+	var document strings.Builder
+	document.WriteString(`{"id" : 1`)
+	for i := 0; i < 10000; i++ {
+		value := i
+		if i == 5000 {
+			value = -1
+		}
+		fmt.Fprintf(&document, `, "item%d" : %d`, i, value)
+	}
+	document.WriteString(`, "other" : true}`)
+
+	result, err := schema.ValidateObjectStream(strings.NewReader(document.String()))
+	require.Nil(t, err)
+	errs := map[string]string{}
+	for _, e := range result.Errors() {
+		errs[e.Field()+" "+e.Type()] = e.Description()
+	}
+	assert.Equal(t, map[string]string{
+		"id invalid_type":                        "Invalid type. Expected: string, given: integer",
+		"item5000 number_gte":                    "Must be greater than or equal to 0",
+		"(root) additional_property_not_allowed": "Additional property other is not allowed",
+		"(root) required":                        "name is required",
+		"(root) missing_dependency":              "Has a dependency on version",
+	}, errs)
+
+	result, err = schema.ValidateObjectStream(strings.NewReader(`{"id" : "a", "name" : "b", "version" : 1, "item0" : 1}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	result, err = schema.ValidateObjectStream(strings.NewReader(`[1, 2]`))
+	require.Nil(t, err)
+	assert.False(t, result.Valid())
+
+	schema, err = NewSchema(NewStringLoader(`{"anyOf" : [{"required" : ["a"]}, {"required" : ["b"]}]}`))
+	require.Nil(t, err)
+	_, err = schema.ValidateObjectStream(strings.NewReader(`{}`))
+	assert.EqualError(t, err, "Keyword anyOf of the root schema can't be validated while streaming an object")
+}
+
+func TestEvaluatedKeywords(t *testing.T) {
+	schemaJSON := `{
+		"type" : "object",
+		"properties" : {
+			"age" : {"type" : "integer", "minimum" : 18, "maxLength" : 3, "description" : "in years"},
+			"name" : {"$ref" : "#/definitions/name"}
+		},
+		"definitions" : {"name" : {"type" : "string"}}
+	}`
+
+	sl := NewSchemaLoader()
+	sl.TraceEvaluatedKeywords = true
+	schema, err := sl.Compile(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+
+	result, err := schema.Validate(NewStringLoader(`{"age" : 21, "name" : "gopher"}`))
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+	assert.Equal(t, []string{"/properties/age/minimum", "/properties/age/type"}, result.EvaluatedKeywords("/age"))
+	assert.Equal(t, []string{"/properties", "/type"}, result.EvaluatedKeywords(""))
+	assert.Equal(t, []string{"/properties/name/$ref", "/properties/name/$ref/type"}, result.EvaluatedKeywords("/name"))
+
+	// A value of the wrong type is only checked against "type"
+	result, err = schema.Validate(NewStringLoader(`{"age" : "21"}`))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"/properties/age/type"}, result.EvaluatedKeywords("/age"))
+	assert.Nil(t, result.EvaluatedKeywords("/name"))
+
+	// Nothing is recorded without TraceEvaluatedKeywords
+	schema, err = NewSchema(NewStringLoader(schemaJSON))
+	require.Nil(t, err)
+	result, err = schema.Validate(NewStringLoader(`{"age" : 21}`))
+	require.Nil(t, err)
+	assert.Nil(t, result.EvaluatedKeywords("/age"))
+}
+
+func TestArrayValidator(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"type" : "array",
+		"items" : {"type" : "integer"},
+		"uniqueItems" : true,
+		"maxItems" : 4,
+		"minItems" : 2,
+		"contains" : {"minimum" : 10}
+	}`))
+	require.Nil(t, err)
+
+	v := schema.NewArrayValidator()
+	errorTypes := func(item interface{}) []string {
+		result, err := v.Append(item)
+		require.Nil(t, err)
+		types := []string{}
+		for _, resultErr := range result.Errors() {
+			types = append(types, resultErr.Type())
+		}
+		return types
+	}
+
+	assert.Empty(t, errorTypes(json.Number("1")))
+	assert.Empty(t, errorTypes(json.Number("2")))
+	assert.Equal(t, []string{ErrorTypeInvalidType}, errorTypes("3"))
+
+	// The duplicate is only caught when it is appended
+	result, err := v.Append(json.Number("1.0"))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, ErrorTypeUnique, result.Errors()[0].Type())
+		assert.Equal(t, 0, result.Errors()[0].Details()["i"])
+		assert.Equal(t, 3, result.Errors()[0].Details()["j"])
+	}
+
+	assert.Equal(t, []string{ErrorTypeArrayMaxItems}, errorTypes(json.Number("10")))
+	result, err = v.Append(json.Number("11"))
+	require.Nil(t, err)
+	if assert.Len(t, result.Errors(), 1, "every item beyond maxItems is reported") {
+		assert.Equal(t, ErrorTypeArrayMaxItems, result.Errors()[0].Type())
+		assert.Equal(t, 5, result.Errors()[0].Details()["from"])
+		assert.Equal(t, 5, result.Errors()[0].Details()["to"])
+	}
+
+	result, err = v.Finish()
+	require.Nil(t, err)
+	assert.True(t, result.Valid())
+
+	// minItems and contains are checked by Finish
+	v = schema.NewArrayValidator()
+	assert.Empty(t, errorTypes(json.Number("1")))
+	result, err = v.Finish()
+	require.Nil(t, err)
+	types := map[string]bool{}
+	for _, resultErr := range result.Errors() {
+		types[resultErr.Type()] = true
+	}
+	assert.Equal(t, map[string]bool{ErrorTypeArrayMinItems: true, ErrorTypeContains: true, ErrorTypeNumberGTE: true}, types)
+
+	// Go values are normalized like those of NewGoLoader
+	v = schema.NewArrayValidator()
+	assert.Equal(t, []string{ErrorTypeInvalidType}, errorTypes(3.5))
+	assert.Empty(t, errorTypes(float64(12)))
+	assert.Equal(t, []string{ErrorTypeUnique}, errorTypes(12))
+
+	schema, err = NewSchema(NewStringLoader(`{"items" : {"type" : "integer"}, "anyOf" : [{"maxItems" : 1}, {"minItems" : 3}]}`))
+	require.Nil(t, err)
+	_, err = schema.NewArrayValidator().Append(json.Number("1"))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "Keyword anyOf of the root schema can't be validated one item at a time", err.Error())
+	}
+
+	schema, err = NewSchema(NewStringLoader(`{"type" : "object"}`))
+	require.Nil(t, err)
+	v = schema.NewArrayValidator()
+	assert.Equal(t, []string{ErrorTypeInvalidType}, errorTypes(json.Number("1")))
+	assert.Empty(t, errorTypes(json.Number("2")), "the type is only reported once")
+}
+
+func TestSanitize(t *testing.T) {
+	schema, err := NewSchema(NewStringLoader(`{
+		"type" : "object",
+		"properties" : {
+			"name" : {"type" : "string"},
+			"address" : {
+				"type" : "object",
+				"properties" : {
+					"street" : {"type" : "string"}
+				},
+				"patternProperties" : {
+					"^x-" : {}
+				},
+				"additionalProperties" : false
+			},
+			"tags" : {
+				"type" : "array",
+				"items" : {"$ref" : "#/definitions/tag"}
+			}
+		},
+		"required" : ["address"],
+		"definitions" : {
+			"tag" : {
+				"properties" : {"label" : {}},
+				"additionalProperties" : false
+			}
+		}
+	}`))
+	require.Nil(t, err)
+
+	document := `{
+		"name" : "John",
+		"extra" : 1,
+		"address" : {"street" : "Main Street", "x-note" : "back door", "city" : "Springfield"},
+		"tags" : [{"label" : "a", "color" : "red"}]
+	}`
+	sanitized, result, err := schema.Sanitize(NewStringLoader(document))
+	require.Nil(t, err)
+	assert.True(t, result.Valid(), "%v", result.Errors())
+
+	actual, err := json.Marshal(sanitized)
+	require.Nil(t, err)
+	assert.JSONEq(t, `{
+		"name" : "John",
+		"extra" : 1,
+		"address" : {"street" : "Main Street", "x-note" : "back door"},
+		"tags" : [{"label" : "a"}]
+	}`, string(actual))
+
+	// Properties that are allowed, but invalid, are kept and reported
+	sanitized, result, err = schema.Sanitize(NewStringLoader(`{"address" : {"street" : 1, "zip" : "1234"}}`))
+	require.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"street": json.Number("1")}, sanitized.(map[string]interface{})["address"])
+	if assert.Len(t, result.Errors(), 1) {
+		assert.Equal(t, "address.street", result.Errors()[0].Field())
+	}
+}
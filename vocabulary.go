@@ -0,0 +1,107 @@
+// Copyright 2018 johandorland ( https://github.com/johandorland )
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gojsonschema
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/xeipuuv/gojsonreference"
+)
+
+// Vocabularies of the 2019-09 and 2020-12 drafts that are understood by this package
+const (
+	VOCABULARY_2019_09_FORMAT           = "https://json-schema.org/draft/2019-09/vocab/format"
+	VOCABULARY_2020_12_FORMAT_ASSERTION = "https://json-schema.org/draft/2020-12/vocab/format-assertion"
+)
+
+// knownVocabularies holds the vocabularies of which all keywords are implemented. The applicator, unevaluated,
+// validation and content vocabularies are not, as some of their keywords such as dependentSchemas, unevaluatedProperties
+// or contentSchema aren't applied. Meta-schemas requiring them fail to compile, as the drafts require
+var knownVocabularies = map[string]bool{
+	"https://json-schema.org/draft/2019-09/vocab/core":              true,
+	"https://json-schema.org/draft/2019-09/vocab/meta-data":         true,
+	VOCABULARY_2019_09_FORMAT:                                       true,
+	"https://json-schema.org/draft/2020-12/vocab/core":              true,
+	"https://json-schema.org/draft/2020-12/vocab/meta-data":         true,
+	"https://json-schema.org/draft/2020-12/vocab/format-annotation": true,
+	VOCABULARY_2020_12_FORMAT_ASSERTION:                             true,
+}
+
+// metaSchemaVocabularies returns the $vocabulary of the meta-schema referenced by $schema.
+// Only meta-schemas that were added to the schema pool are consulted, nil is returned otherwise.
+func (sl *SchemaLoader) metaSchemaVocabularies(doc interface{}) (map[string]bool, error) {
+	schemaURL, _, err := parseSchemaURL(doc)
+	if err != nil || schemaURL == "" {
+		return nil, err
+	}
+
+	ref, err := gojsonreference.NewJsonReference(schemaURL)
+	if err != nil {
+		return nil, err
+	}
+	ref = sl.pool.normalize(ref)
+	spd, ok := sl.pool.schemaPoolDocuments[ref.String()]
+	if !ok {
+		ref.GetUrl().Fragment = ""
+		if spd, ok = sl.pool.schemaPoolDocuments[ref.String()]; !ok {
+			return nil, nil
+		}
+	}
+
+	m, ok := spd.Document.(map[string]interface{})
+	if !ok || !existsMapKey(m, KEY_VOCABULARY) {
+		return nil, nil
+	}
+	declared, ok := m[KEY_VOCABULARY].(map[string]interface{})
+	if !ok {
+		return nil, errors.New(formatErrorDescription(
+			Locale.MustBeOfAn(),
+			ErrorDetails{"x": KEY_VOCABULARY, "y": TYPE_OBJECT},
+		))
+	}
+
+	vocabularies := make(map[string]bool, len(declared))
+	for _, uri := range sortedKeys(declared) {
+		if !isKind(declared[uri], reflect.Bool) {
+			return nil, errors.New(formatErrorDescription(
+				Locale.MustBeOfType(),
+				ErrorDetails{"key": uri, "type": TYPE_BOOLEAN},
+			))
+		}
+		required := declared[uri].(bool)
+		if !knownVocabularies[uri] {
+			if required {
+				return nil, errors.New(formatErrorDescription(
+					Locale.UnsupportedVocabulary(),
+					ErrorDetails{"vocabulary": uri},
+				))
+			}
+			// Optional vocabularies that aren't understood are ignored
+			continue
+		}
+		vocabularies[uri] = required
+	}
+	return vocabularies, nil
+}
+
+// assertsFormatVocabulary reports whether the vocabularies turn format into an assertion.
+// The 2020-12 format-assertion vocabulary always does, the 2019-09 format vocabulary only when it is required.
+func assertsFormatVocabulary(vocabularies map[string]bool) bool {
+	if _, ok := vocabularies[VOCABULARY_2020_12_FORMAT_ASSERTION]; ok {
+		return true
+	}
+	return vocabularies[VOCABULARY_2019_09_FORMAT]
+}